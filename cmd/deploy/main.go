@@ -44,6 +44,10 @@ func run() error {
 	// Welcome
 	log.Infof("NAIS deploy %s", version.Version())
 
+	if flag.NArg() > 0 {
+		return command(ctx, cfg, flag.Args())
+	}
+
 	err := cfg.Validate()
 	if err != nil {
 		if !errors.Is(err, deployclient.ErrInvalidTelemetryFormat) {
@@ -135,3 +139,52 @@ func run() error {
 
 	return d.Deploy(ctx, cfg, request)
 }
+
+// command runs one of the commands that act on an existing deployment, instead of making a new one.
+func command(ctx context.Context, cfg *deployclient.Config, args []string) error {
+	switch args[0] {
+	case "cancel":
+		if len(args) != 2 {
+			return deployclient.Errorf(deployclient.ExitInvocationFailure, "usage: deploy cancel <id>")
+		}
+	case "status":
+		if len(args) != 1 || len(cfg.ID) == 0 {
+			return deployclient.Errorf(deployclient.ExitInvocationFailure, "usage: deploy status --id <id>")
+		}
+	case "history":
+		if len(args) != 1 {
+			return deployclient.Errorf(deployclient.ExitInvocationFailure, "usage: deploy history [--repository <name>] [--state <state>] [--since <time>] [--until <time>]")
+		}
+	default:
+		return deployclient.Errorf(deployclient.ExitInvocationFailure, "unknown command %q", args[0])
+	}
+
+	err := cfg.ValidateCommand()
+	if err != nil {
+		return deployclient.ErrorWrap(deployclient.ExitInvocationFailure, err)
+	}
+
+	grpcConnection, err := deployclient.NewGrpcConnection(*cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := grpcConnection.Close()
+		if err != nil {
+			log.Error(err)
+		}
+	}()
+
+	d := deployclient.Deployer{
+		Client: pb.NewDeployClient(grpcConnection),
+	}
+
+	switch args[0] {
+	case "cancel":
+		return d.Cancel(ctx, cfg, args[1])
+	case "history":
+		return d.History(ctx, cfg, os.Stdout)
+	default:
+		return d.Status(ctx, cfg, cfg.ID)
+	}
+}
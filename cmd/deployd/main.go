@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,9 +24,13 @@ import (
 
 	"github.com/nais/deploy/pkg/deployd/config"
 	"github.com/nais/deploy/pkg/deployd/deployd"
+	"github.com/nais/deploy/pkg/deployd/fairqueue"
 	"github.com/nais/deploy/pkg/deployd/kubeclient"
 	"github.com/nais/deploy/pkg/deployd/metrics"
 	"github.com/nais/deploy/pkg/deployd/operation"
+	"github.com/nais/deploy/pkg/deployd/outbox"
+	"github.com/nais/deploy/pkg/deployd/strategy"
+	"github.com/nais/deploy/pkg/deployd/teamconfig"
 	presharedkey_interceptor "github.com/nais/deploy/pkg/grpc/interceptor/presharedkey"
 	"github.com/nais/deploy/pkg/logging"
 	"github.com/nais/deploy/pkg/pb"
@@ -85,10 +91,33 @@ func run() error {
 		return fmt.Errorf("authenticated gRPC calls enabled, but --hookd-key is not specified")
 	}
 
+	conditionKinds, err := strategy.ParseConditionKinds(cfg.ConditionWatch)
+	if err != nil {
+		return fmt.Errorf("parse --%s: %w", config.ConditionWatch, err)
+	}
+	strategy.SetConditionKinds(conditionKinds)
+	strategy.SetMaxRestarts(cfg.MaxRestarts)
+	strategy.SetLogTailLines(cfg.LogTailLines)
+
+	if cfg.MaxInFlight < 1 {
+		return fmt.Errorf("--%s must be at least 1", config.MaxInFlight)
+	}
+
+	if cfg.OutboxMaxEntries < 1 {
+		return fmt.Errorf("--%s must be at least 1", config.OutboxMaxEntries)
+	}
+
+	// Statuses left over from a previous run are reported once connected to hookd.
+	statusOutbox, err := outbox.Open(cfg.OutboxPath, cfg.OutboxMaxEntries)
+	if err != nil {
+		return err
+	}
+
 	kube, err := kubeclient.DefaultClient()
 	if err != nil {
 		return fmt.Errorf("cannot configure Kubernetes client: %s", err)
 	}
+	kube = kubeclient.NewPool(kube, cfg.ClientPoolSize, cfg.ClientPoolTTL)
 
 	metricsServer := http.NewServeMux()
 	metricsServer.Handle(cfg.MetricsPath, metrics.Handler())
@@ -132,49 +161,118 @@ func run() error {
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 
 	startupTime := time.Now()
+
+	// hookd tells instances apart by their host name, i.e. the pod name, when several of them serve the same cluster.
+	instance, err := os.Hostname()
+	if err != nil {
+		log.Warnf("Unable to identify deployd instance by host name: %s", err)
+	}
+
 	statusChan := make(chan *pb.DeploymentStatus, 1024)
-	requestChan := make(chan *pb.DeploymentRequest, 1024)
+	queue := fairqueue.New(statusChan)
+	defer queue.Close()
+	running := operation.NewRegistry()
+
+	// Queued deployments are cancelled right away, while running deployments report back once they have stopped.
+	cancelDeployment := func(req *pb.DeploymentRequest) {
+		logger := log.WithFields(req.LogFields())
+		if queued, ok := queue.Remove(req.GetID()); ok {
+			logger.Infof("Cancelled queued deployment")
+			statusChan <- pb.NewCancelledStatus(queued)
+			return
+		}
+		if running.Cancel(req.GetID()) {
+			logger.Infof("Cancelling running deployment")
+			return
+		}
+		logger.Warnf("Ignoring cancellation of deployment that is neither queued nor running")
+	}
+
+	// New deployment requests are refused once deployd starts draining, but cancellations still reach the
+	// deployments in progress. Refused requests are resumed by the instance that takes over when deployd disconnects.
+	receiveContext, stopReceiving := context.WithCancel(programContext)
+	defer stopReceiving()
+	var draining atomic.Bool
 
 	// Keep deployment requests coming in on the request channel.
 	go func() {
 		for {
 			time.Sleep(requestBackoff)
+			if receiveContext.Err() != nil || draining.Load() {
+				return
+			}
 
-			deploymentStream, err := grpcClient.Deployments(programContext, &pb.GetDeploymentOpts{
+			deploymentStream, err := grpcClient.Deployments(receiveContext, &pb.GetDeploymentOpts{
 				Cluster:     cfg.Cluster,
 				StartupTime: pb.TimeAsTimestamp(startupTime),
+				Resume:      true,
+				Instance:    instance,
 			})
 			if err != nil {
 				log.Errorf("Open hookd deployment stream: %s", err)
 				continue
 			}
 
-			log.Infof("Connected to hookd and receiving deployment requests")
+			log.Infof("Connected to hookd as deployd instance '%s'", instance)
 
 			for {
 				req, err := deploymentStream.Recv()
+				if receiveContext.Err() != nil {
+					return
+				}
 				if err != nil {
 					log.Errorf("Receive deployment request: %v", err)
 					break
 				}
-				requestChan <- req
+				if req.GetCancel() {
+					cancelDeployment(req)
+					continue
+				}
+				if draining.Load() {
+					log.WithFields(req.LogFields()).Warnf("Refusing deployment request while draining")
+					continue
+				}
+				queue.Push(req)
 			}
 
 			log.Errorf("Disconnected from hookd")
 		}
 	}()
 
+	bootstrap := teamconfig.BootstrapOptions{
+		Namespace:      cfg.TeamNamespaces,
+		ServiceAccount: cfg.AutoCreateServiceAccount,
+		ClusterRole:    cfg.TeamClusterRole,
+	}
+
 	deploy := func(req *pb.DeploymentRequest) {
 		ctx, cancel := req.Context()
+		ctx, cancelCause := context.WithCancelCause(ctx)
+		running.Add(req.GetID(), cancelCause)
+		defer running.Remove(req.GetID())
 		ctx = telemetry.WithTraceParent(ctx, req.TraceParent)
 		ctx, span := telemetry.Tracer().Start(ctx, "Deploy to Kubernetes", otrace.WithSpanKind(otrace.SpanKindServer))
 
-		client, err := kube.Impersonate(req.GetTeam())
-		if err != nil {
+		fail := func(err error) {
 			span.SetStatus(ocodes.Error, err.Error())
 			span.End()
 			cancel()
 			statusChan <- pb.NewErrorStatus(req, err)
+		}
+
+		// Dry runs and diffs never change anything in the cluster, including the team's own setup.
+		dryRun := req.GetDryRun() || req.GetDiff()
+		if (bootstrap.Namespace || bootstrap.ServiceAccount) && !dryRun {
+			err := teamconfig.Bootstrap(ctx, kube.Kubernetes(), req.GetTeam(), bootstrap)
+			if err != nil {
+				fail(err)
+				return
+			}
+		}
+
+		client, err := kube.Impersonate(req.GetTeam())
+		if err != nil {
+			fail(err)
 			return
 		}
 
@@ -192,7 +290,22 @@ func run() error {
 		deployd.Run(op, client)
 	}
 
-	statusQueue := make([]*pb.DeploymentStatus, 0, 128)
+	// A fixed number of workers limits the load on the Kubernetes API server.
+	var workers sync.WaitGroup
+	workers.Add(cfg.MaxInFlight)
+	for i := 0; i < cfg.MaxInFlight; i++ {
+		go func() {
+			defer workers.Done()
+			for {
+				req, ok := queue.Pop()
+				if !ok {
+					return
+				}
+				deploy(req)
+				queue.Done(req)
+			}
+		}()
+	}
 
 	report := func(st *pb.DeploymentStatus) error {
 		logger := log.WithFields(st.LogFields())
@@ -209,45 +322,87 @@ func run() error {
 			logger.Infof(st.GetMessage())
 		}
 
-		_, err = grpcClient.ReportStatus(programContext, st)
+		_, err := grpcClient.ReportStatus(programContext, st)
 
+		switch status.Convert(err).Code() {
+		case codes.FailedPrecondition, codes.InvalidArgument, codes.AlreadyExists:
+			// drop message on terminal error conditions
+			logger.Error(err)
+			logger.Warnf("Dropping message because server did not accept it: %s", st.GetMessage())
+			return nil
+		}
+
+		// re-queue on all other error conditions
 		return err
 	}
 
-	reportAllInQueue := func() {
-		for i, st := range statusQueue {
-			err := report(st)
-			if err != nil {
-				logger := log.WithFields(st.LogFields())
-				logger.Error(err)
-				switch status.Convert(err).Code() {
-				case codes.FailedPrecondition, codes.InvalidArgument, codes.AlreadyExists:
-					// drop message on terminal error conditions
-					logger.Warnf("Dropping message because server did not accept it: %s", st.GetMessage())
-				default:
-					// re-queue on all other error conditions
-					statusQueue = statusQueue[i:]
-					return
+	addStatus := func(st *pb.DeploymentStatus) {
+		err := statusOutbox.Add(st)
+		if err != nil {
+			log.WithFields(st.LogFields()).Error(err)
+		}
+	}
+
+	// Report statuses that are still waiting on the status channel, along with any unreported statuses.
+	flushStatuses := func() {
+		for {
+			select {
+			case st := <-statusChan:
+				addStatus(st)
+			default:
+				statusOutbox.Flush(report)
+				if statusOutbox.Len() > 0 {
+					log.Warnf("Exiting with %d unreported deployment statuses", statusOutbox.Len())
 				}
+				return
 			}
 		}
-		statusQueue = statusQueue[:0]
 	}
 
+	// Both are nil until draining starts.
+	var drained chan struct{}
+	var drainTimeout <-chan time.Time
+
 	for {
 		select {
-		case req := <-requestChan:
-			go deploy(req)
-
 		case st := <-statusChan:
-			statusQueue = append(statusQueue, st)
-			reportAllInQueue()
+			addStatus(st)
+			statusOutbox.Flush(report)
 
 		case <-time.NewTimer(statusQueueReportInterval).C:
-			reportAllInQueue()
+			statusOutbox.Flush(report)
 
 		case sig := <-signals:
-			log.Infof("Received signal %s (%d), exiting...", sig, sig)
+			if drained != nil {
+				log.Warnf("Received signal %s (%d) while draining, exiting...", sig, sig)
+				flushStatuses()
+				return nil
+			}
+
+			// Deployments in progress are allowed to finish, while queued deployments are left for the next start to resume.
+			log.Infof("Received signal %s (%d), waiting up to %s for deployments in progress to finish...", sig, sig, cfg.DrainTimeout)
+			draining.Store(true)
+			queue.Close()
+
+			drained = make(chan struct{})
+			drainTimeout = time.After(cfg.DrainTimeout)
+			go func() {
+				workers.Wait()
+				close(drained)
+			}()
+
+		case <-drained:
+			log.Infof("All deployments in progress have finished, exiting...")
+			flushStatuses()
+			return nil
+
+		case <-drainTimeout:
+			active := queue.Active()
+			log.Warnf("%d deployments still in progress after %s, exiting...", len(active), cfg.DrainTimeout)
+			for _, req := range active {
+				addStatus(pb.NewInProgressStatus(req, "NAIS deploy is restarting; the deployment will be resumed once it is back"))
+			}
+			flushStatuses()
 			return nil
 		}
 	}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -17,6 +18,7 @@ import (
 	"github.com/nais/deploy/pkg/grpc/deployserver"
 	"github.com/nais/deploy/pkg/grpc/dispatchserver"
 	auth_interceptor "github.com/nais/deploy/pkg/grpc/interceptor/auth"
+	drain_interceptor "github.com/nais/deploy/pkg/grpc/interceptor/drain"
 	presharedkey_interceptor "github.com/nais/deploy/pkg/grpc/interceptor/presharedkey"
 	switch_interceptor "github.com/nais/deploy/pkg/grpc/interceptor/switch"
 	unauthenticated_interceptor "github.com/nais/deploy/pkg/grpc/interceptor/unauthenticated"
@@ -47,6 +49,7 @@ var maskedConfig = []string{
 
 const (
 	databaseConnectBackoffInterval = 3 * time.Second
+	payloadCleanupInterval         = time.Hour
 )
 
 func run() error {
@@ -127,8 +130,17 @@ func run() error {
 		return fmt.Errorf("migrating database: %s", err)
 	}
 
+	// The last successful deployment is always needed to roll back to.
+	if cfg.PayloadRetention < 1 {
+		return fmt.Errorf("--%s must be at least 1", config.PayloadRetention)
+	}
+	go cleanupPayloads(programContext, db, cfg.PayloadRetention)
+
+	// Statuses from deployd are still accepted while draining, so that deployments in progress are not left hanging.
+	drainInterceptor := drain_interceptor.New(pb.Dispatch_ReportStatus_FullMethodName)
+
 	// Set up gRPC server
-	grpcServer, dispatchServer, err := startGrpcServer(*cfg, db, db)
+	grpcServer, dispatchServer, err := startGrpcServer(*cfg, db, db, drainInterceptor)
 	if err != nil {
 		return err
 	}
@@ -136,6 +148,19 @@ func run() error {
 
 	log.Infof("gRPC server started")
 
+	if cfg.Replication {
+		replicaID, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("hostname is used as replica ID: %s", err)
+		}
+		go func() {
+			err := dispatchServer.Replicate(programContext, db, replicaID)
+			if err != nil {
+				log.Errorf("Replication between hookd replicas stopped: %s", err)
+			}
+		}()
+	}
+
 	projects, err := parseKeyVal(cfg.GoogleClusterProjects)
 	if err != nil {
 		return fmt.Errorf("unable to parse google cluster projects: %v", err)
@@ -152,9 +177,14 @@ func run() error {
 		LogLinkFormatter:      logproxy.ParseLogLinkFormatter(cfg.LogLinkFormatter),
 	})
 
+	httpServer := &http.Server{
+		Addr:    cfg.ListenAddress,
+		Handler: router,
+	}
+
 	go func() {
-		err := http.ListenAndServe(cfg.ListenAddress, router)
-		if err != nil {
+		err := httpServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error(err)
 		}
 	}()
@@ -165,11 +195,57 @@ func run() error {
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-signals
 
-	log.Infof("Received signal %s (%d), exiting...", sig, sig)
+	log.Infof("Received signal %s (%d), draining connections...", sig, sig)
+
+	// Clients and deployd are told to reconnect, while requests already in progress are allowed to finish.
+	drainInterceptor.Drain()
+
+	ctx, cancel = context.WithTimeout(programContext, cfg.DrainTimeout)
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	err = httpServer.Shutdown(ctx)
+	if err != nil {
+		log.Errorf("Shut down HTTP server: %s", err)
+	}
+
+	select {
+	case <-stopped:
+		log.Infof("All connections drained, exiting...")
+	case <-ctx.Done():
+		log.Warnf("Connections not drained within %s, exiting...", cfg.DrainTimeout)
+		grpcServer.Stop()
+	}
 
 	return nil
 }
 
+// cleanupPayloads periodically deletes the Kubernetes resources of deployments that can no longer be resumed or rolled back to.
+// With several replicas, every replica does this; deleting the same payloads twice is harmless.
+func cleanupPayloads(ctx context.Context, db database.DeploymentStore, keep int) {
+	ticker := time.NewTicker(payloadCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := db.DeleteDeploymentPayloads(ctx, keep)
+			if err != nil {
+				log.Errorf("Delete old deployment payloads: %s", err)
+			} else if deleted > 0 {
+				log.Infof("Deleted %d old deployment payload(s)", deleted)
+			}
+		}
+	}
+}
+
 func newApiClient(target string, insecureConnection bool) (*apiclient.APIClient, error) {
 	opts := []grpc.DialOption{}
 	if insecureConnection {
@@ -179,7 +255,7 @@ func newApiClient(target string, insecureConnection bool) (*apiclient.APIClient,
 	return apiclient.New(target, opts...)
 }
 
-func startGrpcServer(cfg config.Config, db database.DeploymentStore, apikeys database.ApiKeyStore) (*grpc.Server, dispatchserver.DispatchServer, error) {
+func startGrpcServer(cfg config.Config, db database.DeploymentStore, apikeys database.ApiKeyStore, drain *drain_interceptor.ServerInterceptor) (*grpc.Server, dispatchserver.DispatchServer, error) {
 	clusterRedirects, err := parseKeyVal(cfg.ClusterMigrationRedirect)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to parse cluster migration redirects: %v", err)
@@ -205,6 +281,9 @@ func startGrpcServer(cfg config.Config, db database.DeploymentStore, apikeys dat
 	unaryInterceptors = append(unaryInterceptors, serverMetrics.UnaryServerInterceptor())
 	streamInterceptors = append(streamInterceptors, serverMetrics.StreamServerInterceptor())
 
+	unaryInterceptors = append(unaryInterceptors, drain.UnaryServerInterceptor)
+	streamInterceptors = append(streamInterceptors, drain.StreamServerInterceptor)
+
 	if cfg.GRPC.CliAuthentication || cfg.GRPC.DeploydAuthentication {
 		interceptor := switch_interceptor.NewServerInterceptor()
 
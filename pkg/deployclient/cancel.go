@@ -0,0 +1,50 @@
+package deployclient
+
+import (
+	"context"
+
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+)
+
+// Cancel aborts a deployment that has not yet finished.
+// When waiting, blocks until the deployment has stopped, and fails if it finished before it could be cancelled.
+func (d *Deployer) Cancel(ctx context.Context, cfg *Config, id string) error {
+	request := &pb.DeploymentRequest{
+		ID:   id,
+		Team: cfg.Team,
+	}
+
+	var deployStatus *pb.DeploymentStatus
+	err := retryUnavailable(cfg.RetryInterval, cfg.Retry, func() error {
+		var err error
+		deployStatus, err = d.Client.Cancel(ctx, request)
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return Errorf(ExitTimeout, "cancellation timed out: %s", ctx.Err())
+		}
+		return Errorf(ExitNoDeployment, "cancel deployment: %s", formatGrpcError(err))
+	}
+
+	log.Infof("Cancellation of deployment %s requested", id)
+	logDeployStatus(deployStatus)
+
+	if !cfg.Wait {
+		return nil
+	}
+
+	log.Infof("Waiting for deployment to stop...")
+
+	deployStatus, err = d.waitFinished(ctx, cfg, request)
+	if err != nil {
+		return err
+	}
+
+	if deployStatus.GetState() != pb.DeploymentState_cancelled {
+		return Errorf(ExitNoDeployment, "deployment finished with state %s before it could be cancelled", deployStatus.GetState())
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package deployclient_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/deployclient"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCancel(t *testing.T) {
+	cfg := validConfig()
+	cfg.Wait = true
+	cfg.Retry = true
+	cfg.RetryInterval = time.Millisecond
+	request := &pb.DeploymentRequest{ID: "1", Team: cfg.Team}
+
+	client := &pb.MockDeployClient{}
+	client.On("Cancel", mock.Anything, request).Return(nil, status.Errorf(codes.Unavailable, "try again")).Once()
+	client.On("Cancel", mock.Anything, request).Return(&pb.DeploymentStatus{
+		Request: request,
+		State:   pb.DeploymentState_in_progress,
+	}, nil).Once()
+
+	statusClient := &pb.MockDeploy_StatusClient{}
+	client.On("Status", mock.Anything, request).Return(statusClient, nil).Once()
+	statusClient.On("Recv").Return(&pb.DeploymentStatus{
+		Request: request,
+		State:   pb.DeploymentState_cancelled,
+	}, nil).Once()
+
+	d := deployclient.Deployer{Client: client}
+	err := d.Cancel(context.Background(), cfg, "1")
+	assert.NoError(t, err)
+}
+
+func TestCancelTooLate(t *testing.T) {
+	cfg := validConfig()
+	cfg.Wait = true
+	request := &pb.DeploymentRequest{ID: "1", Team: cfg.Team}
+
+	client := &pb.MockDeployClient{}
+	client.On("Cancel", mock.Anything, request).Return(&pb.DeploymentStatus{
+		Request: request,
+		State:   pb.DeploymentState_in_progress,
+	}, nil).Once()
+
+	statusClient := &pb.MockDeploy_StatusClient{}
+	client.On("Status", mock.Anything, request).Return(statusClient, nil).Once()
+	statusClient.On("Recv").Return(&pb.DeploymentStatus{
+		Request: request,
+		State:   pb.DeploymentState_success,
+	}, nil).Once()
+
+	d := deployclient.Deployer{Client: client}
+	err := d.Cancel(context.Background(), cfg, "1")
+	assert.EqualError(t, err, "deployment finished with state success before it could be cancelled")
+}
+
+func TestCancelFinishedDeployment(t *testing.T) {
+	cfg := validConfig()
+	request := &pb.DeploymentRequest{ID: "1", Team: cfg.Team}
+
+	client := &pb.MockDeployClient{}
+	client.On("Cancel", mock.Anything, request).Return(nil, status.Errorf(codes.FailedPrecondition, "deployment has already finished with state success")).Once()
+
+	d := deployclient.Deployer{Client: client}
+	err := d.Cancel(context.Background(), cfg, "1")
+	assert.Error(t, err)
+	assert.Equal(t, deployclient.ExitNoDeployment, deployclient.ErrorExitCode(err))
+}
@@ -16,27 +16,39 @@ type Config struct {
 	APIKey                    string
 	Actions                   bool
 	Cluster                   string
+	Cursor                    string
 	DeployServerURL           string
+	Diff                      bool
 	DryRun                    bool
 	Environment               string
+	ForceOwnership            bool
 	GithubToken               string
 	GrpcAuthentication        bool
 	GrpcUseTLS                bool
+	ID                        string
+	Limit                     int
+	Output                    string
 	Owner                     string
 	PollInterval              time.Duration
 	PrintPayload              bool
+	Prune                     bool
 	Quiet                     bool
 	Ref                       string
 	Repository                string
 	Resource                  []string
 	Retry                     bool
 	RetryInterval             time.Duration
+	RollbackOnFailure         bool
+	ServerDryRun              bool
+	Since                     string
+	State                     []string
 	Team                      string
 	Traceparent               string
 	TelemetryInput            string
 	Telemetry                 *telemetry.PipelineTimings
 	Timeout                   time.Duration
 	TracingDashboardURL       string
+	Until                     string
 	OpenTelemetryCollectorURL string
 	Variables                 []string
 	VariablesFile             string
@@ -48,24 +60,36 @@ func InitConfig(cfg *Config) {
 	flag.StringVar(&cfg.GithubToken, "github-token", os.Getenv("GITHUB_TOKEN"), "Github JWT. (env GITHUB_TOKEN)")
 	flag.StringVar(&cfg.APIKey, "apikey", os.Getenv("APIKEY"), "NAIS Deploy API key. (env APIKEY)")
 	flag.StringVar(&cfg.Cluster, "cluster", os.Getenv("CLUSTER"), "NAIS cluster to deploy into. (env CLUSTER)")
+	flag.StringVar(&cfg.Cursor, "cursor", os.Getenv("CURSOR"), "Continue the history command from where a previous invocation stopped. (env CURSOR)")
 	flag.StringVar(&cfg.DeployServerURL, "deploy-server", getEnv("DEPLOY_SERVER", DefaultDeployServer), "URL to API server. (env DEPLOY_SERVER)")
+	flag.BoolVar(&cfg.Diff, "diff", getEnvBool("DIFF", false), "Show what would change in the cluster for each resource, without persisting any changes. Implies --server-dry-run and --wait. (env DIFF)")
 	flag.BoolVar(&cfg.DryRun, "dry-run", getEnvBool("DRY_RUN", false), "Run templating, but don't actually make any requests. (env DRY_RUN)")
 	flag.StringVar(&cfg.Environment, "environment", os.Getenv("ENVIRONMENT"), "Environment for GitHub deployment. Autodetected from nais.yaml if not specified. (env ENVIRONMENT)")
+	flag.BoolVar(&cfg.ForceOwnership, "force-ownership", getEnvBool("FORCE_OWNERSHIP", false), "Take ownership of fields managed by other controllers or clients when applying resources. (env FORCE_OWNERSHIP)")
 	flag.BoolVar(&cfg.GrpcAuthentication, "grpc-authentication", getEnvBool("GRPC_AUTHENTICATION", true), "Use team API key to authenticate requests. (env GRPC_AUTHENTICATION)")
 	flag.BoolVar(&cfg.GrpcUseTLS, "grpc-use-tls", getEnvBool("GRPC_USE_TLS", true), "Use encrypted connection for gRPC calls. (env GRPC_USE_TLS)")
+	flag.StringVar(&cfg.ID, "id", os.Getenv("DEPLOYMENT_ID"), "ID of an existing deployment to follow with the status command. (env DEPLOYMENT_ID)")
+	flag.IntVar(&cfg.Limit, "limit", getEnvInt("LIMIT", DefaultHistoryLimit), "Maximum number of deployments listed by the history command. (env LIMIT)")
+	flag.StringVar(&cfg.Output, "output", getEnv("OUTPUT", OutputTable), "Output format of the history command; either table or json. (env OUTPUT)")
 	flag.StringVar(&cfg.Owner, "owner", getEnv("OWNER", DefaultOwner), "Owner of GitHub repository. (env OWNER)")
 	flag.BoolVar(&cfg.PrintPayload, "print-payload", getEnvBool("PRINT_PAYLOAD", false), "Print templated resources to standard output. (env PRINT_PAYLOAD)")
+	flag.BoolVar(&cfg.Prune, "prune", getEnvBool("PRUNE", false), "Delete resources that were part of the last successful deployment from this repository, but are missing from this one. (env PRUNE)")
 	flag.BoolVar(&cfg.Quiet, "quiet", getEnvBool("QUIET", false), "Suppress printing of informational messages except errors. (env QUIET)")
 	flag.StringVar(&cfg.Ref, "ref", getEnv("REF", DefaultRef), "Git commit hash, tag, or branch of the code being deployed. (env REF)")
 	flag.StringVar(&cfg.Repository, "repository", os.Getenv("REPOSITORY"), "Name of GitHub repository. (env REPOSITORY)")
 	flag.StringSliceVar(&cfg.Resource, "resource", getEnvStringSlice("RESOURCE"), "File with Kubernetes resource. Can be specified multiple times. (env RESOURCE)")
 	flag.BoolVar(&cfg.Retry, "retry", getEnvBool("RETRY", true), "Retry deploy when encountering transient errors. (env RETRY)")
+	flag.BoolVar(&cfg.RollbackOnFailure, "rollback-on-failure", getEnvBool("ROLLBACK_ON_FAILURE", false), "Re-apply the last successful deployment from this repository if the rollout fails. The deployment is still reported as failed. (env ROLLBACK_ON_FAILURE)")
+	flag.BoolVar(&cfg.ServerDryRun, "server-dry-run", getEnvBool("SERVER_DRY_RUN", false), "Send resources to the cluster for validation and admission, but don't persist any changes. (env SERVER_DRY_RUN)")
+	flag.StringVar(&cfg.Since, "since", os.Getenv("SINCE"), "List deployments created after this time, given as RFC 3339 or as a duration ago, such as 24h. (env SINCE)")
+	flag.StringSliceVar(&cfg.State, "state", getEnvStringSlice("STATE"), "List deployments in this state. Can be specified multiple times. (env STATE)")
 	flag.StringVar(&cfg.Team, "team", os.Getenv("TEAM"), "Team making the deployment. Auto-detected from nais.yaml if possible. (env TEAM)")
 	flag.StringVar(&cfg.OpenTelemetryCollectorURL, "otel-collector-endpoint", getEnv("OTEL_COLLECTOR_ENDPOINT", DefaultOtelCollectorEndpoint), "OpenTelemetry collector endpoint. (env OTEL_COLLECTOR_ENDPOINT)")
 	flag.StringVar(&cfg.Traceparent, "traceparent", os.Getenv("TRACEPARENT"), "The W3C Trace Context traceparent value for the workflow run. (env TRACEPARENT)")
 	flag.StringVar(&cfg.TelemetryInput, "telemetry", os.Getenv("TELEMETRY"), "Telemetry data from CI pipeline. (env TELEMETRY)")
 	flag.DurationVar(&cfg.Timeout, "timeout", getEnvDuration("TIMEOUT", DefaultDeployTimeout), "Time to wait for successful deployment. (env TIMEOUT)")
 	flag.StringVar(&cfg.TracingDashboardURL, "tracing-dashboard-url", getEnv("TRACING_DASHBOARD_URL", DefaultTracingDashboardURL), "Base URL to Grafana tracing dashboard onto which the trace ID can be appended (env TRACING_DASHBOARD_URL)")
+	flag.StringVar(&cfg.Until, "until", os.Getenv("UNTIL"), "List deployments created before this time, given as RFC 3339 or as a duration ago, such as 24h. (env UNTIL)")
 	flag.StringSliceVar(&cfg.Variables, "var", getEnvStringSlice("VAR"), "Template variable in the form KEY=VALUE. Can be specified multiple times. (env VAR)")
 	flag.StringVar(&cfg.VariablesFile, "vars", os.Getenv("VARS"), "File containing template variables. (env VARS)")
 	flag.BoolVar(&cfg.Wait, "wait", getEnvBool("WAIT", false), "Block until deployment reaches final state (success, failure, error). (env WAIT)")
@@ -105,6 +129,16 @@ func getEnvDuration(key string, fallback time.Duration) time.Duration {
 	return fallback
 }
 
+func getEnvInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		i, err := strconv.Atoi(value)
+		if err == nil {
+			return i
+		}
+	}
+	return fallback
+}
+
 func getEnvStringSlice(key string) []string {
 	if value, ok := os.LookupEnv(key); ok {
 		return strings.Split(value, ",")
@@ -131,6 +165,29 @@ func (cfg *Config) Validate() error {
 		return ErrClusterRequired
 	}
 
+	err := cfg.validateAuth()
+	if err != nil {
+		return err
+	}
+
+	cfg.Telemetry, err = telemetry.ParsePipelineTelemetry(cfg.TelemetryInput)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTelemetryFormat, err)
+	}
+
+	return nil
+}
+
+// ValidateCommand validates the configuration for commands that act on an existing deployment, such as `cancel`.
+func (cfg *Config) ValidateCommand() error {
+	if len(cfg.Team) == 0 {
+		return ErrTeamRequired
+	}
+
+	return cfg.validateAuth()
+}
+
+func (cfg *Config) validateAuth() error {
 	if len(cfg.APIKey) == 0 && len(cfg.GithubToken) == 0 {
 		return ErrAuthRequired
 	}
@@ -140,10 +197,5 @@ func (cfg *Config) Validate() error {
 		return ErrMalformedAPIKey
 	}
 
-	cfg.Telemetry, err = telemetry.ParsePipelineTelemetry(cfg.TelemetryInput)
-	if err != nil {
-		return fmt.Errorf("%w: %w", ErrInvalidTelemetryFormat, err)
-	}
-
 	return nil
 }
@@ -27,6 +27,7 @@ const (
 	DefaultOtelCollectorEndpoint = "https://collector-internet.external.prod-gcp.nav.cloud.nais.io"
 	DefaultTracingDashboardURL   = "https://grafana.nav.cloud.nais.io/d/cdxgyzr3rikn4a/deploy-tracing-drilldown?var-trace_id="
 	DefaultDeployTimeout         = time.Minute * 10
+	DefaultHistoryLimit          = 20
 )
 
 var (
@@ -35,6 +36,7 @@ var (
 	ErrClusterRequired        = errors.New("cluster required; see reference section in the documentation for available environments")
 	ErrMalformedAPIKey        = errors.New("API key must be a hex encoded string")
 	ErrInvalidTelemetryFormat = errors.New("telemetry input format malformed")
+	ErrTeamRequired           = errors.New("team required; specify the team that made the deployment")
 )
 
 type Deployer struct {
@@ -160,6 +162,10 @@ func (d *Deployer) Deploy(ctx context.Context, cfg *Config, deployRequest *pb.De
 	defer span.End()
 	deployRequest.TraceParent = telemetry.TraceParentHeader(ctx)
 
+	if deployRequest.GetDryRun() {
+		log.Infof("Server-side dry run requested; resources will be validated by the cluster, but not persisted.")
+	}
+
 	log.Infof("Sending deployment request to NAIS deploy at %s...", cfg.DeployServerURL)
 
 	sendDeploymentRequest := func() error {
@@ -230,6 +236,15 @@ func (d *Deployer) Deploy(ctx context.Context, cfg *Config, deployRequest *pb.De
 		summary("* Finished at: %s", st.Timestamp().Truncate(time.Second))
 		summary("")
 		summary("%c Final status: *%s* / %s", deployStatus.GetState().StatusEmoji(), deployStatus.GetState(), deployStatus.GetMessage())
+		for _, containerLog := range st.GetLogs() {
+			summary("")
+			summary("<details><summary>Logs from container <code>%s</code> in pod <code>%s</code></summary>", containerLog.GetContainer(), containerLog.GetPod())
+			summary("")
+			summary("```")
+			summary("%s", strings.Join(containerLog.GetLines(), "\n"))
+			summary("```")
+			summary("</details>")
+		}
 	}
 	if err == nil {
 		defer summaryFile.Close()
@@ -248,7 +263,8 @@ func (d *Deployer) Deploy(ctx context.Context, cfg *Config, deployRequest *pb.De
 		return ErrorStatus(deployStatus)
 	}
 
-	if !cfg.Wait {
+	// Diffs are streamed as deployment statuses, so there is nothing to show without waiting for them.
+	if !cfg.Wait && !cfg.Diff {
 		finalStatus(deployStatus)
 		logDeployStatus(deployStatus)
 		return nil
@@ -259,9 +275,15 @@ func (d *Deployer) Deploy(ctx context.Context, cfg *Config, deployRequest *pb.De
 
 	log.Infof("Waiting for deployment to complete...")
 
+	statusRequest := &pb.DeploymentRequest{
+		ID:   deployRequest.GetID(),
+		Team: deployRequest.GetTeam(),
+	}
+	resumeAfter(statusRequest, deployStatus)
+
 	for ctx.Err() == nil {
 		err = retryUnavailable(cfg.RetryInterval, cfg.Retry, func() error {
-			stream, err = d.Client.Status(ctx, deployRequest)
+			stream, err = d.Client.Status(ctx, statusRequest)
 			if err != nil {
 				connectionLost = true
 			} else if connectionLost {
@@ -287,6 +309,7 @@ func (d *Deployer) Deploy(ctx context.Context, cfg *Config, deployRequest *pb.De
 				}
 			}
 			logDeployStatus(deployStatus)
+			resumeAfter(statusRequest, deployStatus)
 			if deployStatus.GetState() == pb.DeploymentState_inactive {
 				log.Warnf("NAIS deploy has been restarted. Re-sending deployment request...")
 				err = sendDeploymentRequest()
@@ -294,6 +317,8 @@ func (d *Deployer) Deploy(ctx context.Context, cfg *Config, deployRequest *pb.De
 					summary("❌ lost connection to NAIS deploy", deployStatus.GetState(), deployStatus.GetMessage())
 					return err
 				}
+				statusRequest.ID = deployRequest.GetID()
+				resumeAfter(statusRequest, deployStatus)
 			} else if deployStatus.GetState().Finished() {
 				finalStatus(deployStatus)
 				return ErrorStatus(deployStatus)
@@ -23,6 +23,13 @@ func makeMockDeployRequest(cfg deployclient.Config) *pb.DeploymentRequest {
 	return request
 }
 
+// Status streams only need to identify the deployment, along with where to resume from.
+func statusOf(request *pb.DeploymentRequest) interface{} {
+	return mock.MatchedBy(func(req *pb.DeploymentRequest) bool {
+		return req.GetID() == request.GetID()
+	})
+}
+
 func TestSimpleSuccessfulDeploy(t *testing.T) {
 	cfg := validConfig()
 	request := makeMockDeployRequest(*cfg)
@@ -44,6 +51,15 @@ func TestSimpleSuccessfulDeploy(t *testing.T) {
 	assert.Equal(t, deployclient.ExitSuccess, deployclient.ErrorExitCode(err))
 }
 
+func TestDiffImpliesServerDryRun(t *testing.T) {
+	cfg := validConfig()
+	cfg.Diff = true
+	request := makeMockDeployRequest(*cfg)
+
+	assert.True(t, request.GetDiff())
+	assert.True(t, request.GetDryRun())
+}
+
 func TestSuccessfulDeploy(t *testing.T) {
 	cfg := validConfig()
 	cfg.Wait = true
@@ -67,7 +83,7 @@ func TestSuccessfulDeploy(t *testing.T) {
 		Message: "happy",
 	}, nil).Once()
 
-	client.On("Status", mock.Anything, request).Return(statusClient, nil).Once()
+	client.On("Status", mock.Anything, statusOf(request)).Return(statusClient, nil).Once()
 
 	d := deployclient.Deployer{Client: client}
 	err := d.Deploy(ctx, cfg, request)
@@ -100,7 +116,7 @@ func TestDeployError(t *testing.T) {
 		Message: "oops, we errored out",
 	}, nil).Once()
 
-	client.On("Status", mock.Anything, request).Return(statusClient, nil).Once()
+	client.On("Status", mock.Anything, statusOf(request)).Return(statusClient, nil).Once()
 
 	d := deployclient.Deployer{Client: client}
 	err := d.Deploy(ctx, cfg, request)
@@ -139,7 +155,7 @@ func TestDeployPolling(t *testing.T) {
 		Message: "finally over",
 	}, nil).Once()
 
-	client.On("Status", mock.Anything, request).Return(statusClient, nil).Once()
+	client.On("Status", mock.Anything, statusOf(request)).Return(statusClient, nil).Once()
 
 	d := deployclient.Deployer{Client: client}
 	err := d.Deploy(ctx, cfg, request)
@@ -171,8 +187,8 @@ func TestDeployWithStatusRetry(t *testing.T) {
 	statusClient := &pb.MockDeploy_StatusClient{}
 
 	// set up status stream
-	client.On("Status", mock.Anything, request).Return(nil, status.Errorf(codes.Unavailable, "oops, more errors")).Times(2)
-	client.On("Status", mock.Anything, request).Return(statusClient, nil).Once()
+	client.On("Status", mock.Anything, statusOf(request)).Return(nil, status.Errorf(codes.Unavailable, "oops, more errors")).Times(2)
+	client.On("Status", mock.Anything, statusOf(request)).Return(statusClient, nil).Once()
 
 	// poll a few times
 	statusClient.On("Recv").Return(&pb.DeploymentStatus{
@@ -186,15 +202,15 @@ func TestDeployWithStatusRetry(t *testing.T) {
 	statusClient.On("Recv").Return(nil, status.Errorf(codes.Unavailable, "not so fast, young man")).Once()
 
 	// re-establish status stream
-	client.On("Status", mock.Anything, request).Return(nil, status.Errorf(codes.Unavailable, "still down")).Times(3)
-	client.On("Status", mock.Anything, request).Return(nil, status.Errorf(codes.Internal, "still down, internal error")).Times(3)
-	client.On("Status", mock.Anything, request).Return(statusClient, nil).Once()
+	client.On("Status", mock.Anything, statusOf(request)).Return(nil, status.Errorf(codes.Unavailable, "still down")).Times(3)
+	client.On("Status", mock.Anything, statusOf(request)).Return(nil, status.Errorf(codes.Internal, "still down, internal error")).Times(3)
+	client.On("Status", mock.Anything, statusOf(request)).Return(statusClient, nil).Once()
 
 	// more internal errors in stream
 	statusClient.On("Recv").Return(nil, status.Errorf(codes.Internal, "internal error again")).Once()
 
 	// re-establish status stream
-	client.On("Status", mock.Anything, request).Return(statusClient, nil).Once()
+	client.On("Status", mock.Anything, statusOf(request)).Return(statusClient, nil).Once()
 
 	// come back to discover deployment is gone
 	statusClient.On("Recv").Return(&pb.DeploymentStatus{
@@ -227,6 +243,56 @@ func TestDeployWithStatusRetry(t *testing.T) {
 	assert.Equal(t, deployclient.ExitSuccess, deployclient.ErrorExitCode(err))
 }
 
+func TestDeployResumesStatusStream(t *testing.T) {
+	cfg := validConfig()
+	cfg.Retry = true
+	cfg.Wait = true
+	cfg.RetryInterval = time.Millisecond
+	request := makeMockDeployRequest(*cfg)
+	request.ID = "1"
+	ctx := context.Background()
+	_, _ = telemetry.New(ctx, "test", "")
+
+	resumesAfter := func(statusID string) interface{} {
+		return mock.MatchedBy(func(req *pb.DeploymentRequest) bool {
+			return req.GetID() == "1" && req.GetStatusSinceID() == statusID && req.GetStatusSinceTime() != nil
+		})
+	}
+
+	client := &pb.MockDeployClient{}
+	client.On("Deploy", mock.Anything, request).Return(&pb.DeploymentStatus{
+		ID:      "a",
+		Request: request,
+		Time:    pb.TimeAsTimestamp(time.Now()),
+		State:   pb.DeploymentState_queued,
+	}, nil).Once()
+
+	statusClient := &pb.MockDeploy_StatusClient{}
+	client.On("Status", mock.Anything, resumesAfter("a")).Return(statusClient, nil).Once()
+	statusClient.On("Recv").Return(&pb.DeploymentStatus{
+		ID:      "b",
+		Request: request,
+		Time:    pb.TimeAsTimestamp(time.Now()),
+		State:   pb.DeploymentState_in_progress,
+	}, nil).Once()
+	statusClient.On("Recv").Return(nil, status.Errorf(codes.Unavailable, "hookd is shutting down")).Once()
+
+	reconnected := &pb.MockDeploy_StatusClient{}
+	client.On("Status", mock.Anything, resumesAfter("b")).Return(reconnected, nil).Once()
+	reconnected.On("Recv").Return(&pb.DeploymentStatus{
+		ID:      "c",
+		Request: request,
+		Time:    pb.TimeAsTimestamp(time.Now()),
+		State:   pb.DeploymentState_success,
+	}, nil).Once()
+
+	d := deployclient.Deployer{Client: client}
+	err := d.Deploy(ctx, cfg, request)
+
+	assert.NoError(t, err)
+	client.AssertExpectations(t)
+}
+
 func TestImmediateTimeout(t *testing.T) {
 	cfg := validConfig()
 	cfg.Wait = true
@@ -22,6 +22,7 @@ const (
 	ExitInternalError
 	ExitTemplateError
 	ExitTimeout
+	ExitDeploymentCancelled
 )
 
 type Error struct {
@@ -68,6 +69,8 @@ func ErrorStatus(status *pb.DeploymentStatus) error {
 		return Errorf(ExitDeploymentFailure, "deployment failed")
 	case pb.DeploymentState_inactive:
 		return Errorf(ExitDeploymentInactive, "deployment has been stopped")
+	case pb.DeploymentState_cancelled:
+		return Errorf(ExitDeploymentCancelled, "deployment was cancelled")
 	}
 }
 
@@ -0,0 +1,128 @@
+package deployclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// History writes a page of the team's deployments to out, newest first, either as a table or as JSON.
+func (d *Deployer) History(ctx context.Context, cfg *Config, out io.Writer) error {
+	request, err := historyRequest(cfg, time.Now())
+	if err != nil {
+		return ErrorWrap(ExitInvocationFailure, err)
+	}
+
+	var response *pb.ListDeploymentsResponse
+	err = retryUnavailable(cfg.RetryInterval, cfg.Retry, func() error {
+		var err error
+		response, err = d.Client.ListDeployments(ctx, request)
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return Errorf(ExitTimeout, "listing deployments timed out: %s", ctx.Err())
+		}
+		return Errorf(ExitUnavailable, "list deployments: %s", formatGrpcError(err))
+	}
+
+	if cfg.Output == OutputJSON {
+		_, err = fmt.Fprintln(out, protojson.Format(response))
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tCLUSTER\tREPOSITORY\tSTATE")
+	for _, deployment := range response.GetDeployments() {
+		req := deployment.GetRequest()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			req.GetID(),
+			pb.TimestampAsTime(req.GetTime()).Local().Format(time.RFC3339),
+			req.GetCluster(),
+			req.GetRepository().FullName(),
+			deployment.GetState(),
+		)
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if len(response.GetNextCursor()) > 0 {
+		log.Infof("More deployments are available; continue with --cursor %s", response.GetNextCursor())
+	}
+
+	return nil
+}
+
+func historyRequest(cfg *Config, now time.Time) (*pb.ListDeploymentsRequest, error) {
+	switch cfg.Output {
+	case "", OutputTable, OutputJSON:
+	default:
+		return nil, fmt.Errorf("output format must be either %s or %s", OutputTable, OutputJSON)
+	}
+
+	request := &pb.ListDeploymentsRequest{
+		Team:    cfg.Team,
+		Cluster: cfg.Cluster,
+		Limit:   int32(cfg.Limit),
+		Cursor:  cfg.Cursor,
+	}
+
+	if len(cfg.Repository) > 0 {
+		request.Repository = fmt.Sprintf("%s/%s", cfg.Owner, cfg.Repository)
+	}
+
+	for _, state := range cfg.State {
+		value, ok := pb.DeploymentState_value[strings.TrimSpace(state)]
+		if !ok {
+			return nil, fmt.Errorf("unknown deployment state %q", state)
+		}
+		request.States = append(request.States, pb.DeploymentState(value))
+	}
+
+	if len(cfg.Since) > 0 {
+		since, err := parseTime(cfg.Since, now)
+		if err != nil {
+			return nil, fmt.Errorf("since: %w", err)
+		}
+		request.Since = pb.TimeAsTimestamp(since)
+	}
+
+	if len(cfg.Until) > 0 {
+		until, err := parseTime(cfg.Until, now)
+		if err != nil {
+			return nil, fmt.Errorf("until: %w", err)
+		}
+		request.Until = pb.TimeAsTimestamp(until)
+	}
+
+	return request, nil
+}
+
+// parseTime accepts either an RFC 3339 timestamp, or a duration that is subtracted from now.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a duration", value)
+	}
+
+	return now.Add(-duration), nil
+}
@@ -0,0 +1,101 @@
+package deployclient_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/deployclient"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestHistory(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	response := &pb.ListDeploymentsResponse{
+		Deployments: []*pb.Deployment{
+			{
+				Request: &pb.DeploymentRequest{
+					ID:         "1",
+					Time:       pb.TimeAsTimestamp(created),
+					Cluster:    "dev-fss",
+					Repository: &pb.GithubRepository{Owner: "navikt", Name: "myrepo"},
+				},
+				State: pb.DeploymentState_success,
+			},
+		},
+		NextCursor: "next",
+	}
+
+	setup := func(t *testing.T) (*deployclient.Config, *pb.MockDeployClient) {
+		cfg := validConfig()
+		cfg.Owner = "navikt"
+		cfg.Team = "aura"
+		cfg.Limit = 10
+		cfg.State = []string{"success", "failure"}
+		cfg.Since = "24h"
+
+		client := pb.NewMockDeployClient(t)
+		client.On("ListDeployments", mock.Anything, mock.MatchedBy(func(req *pb.ListDeploymentsRequest) bool {
+			since := pb.TimestampAsTime(req.GetSince())
+			return req.GetTeam() == "aura" &&
+				req.GetRepository() == "navikt/myrepo" &&
+				req.GetCluster() == "dev-fss" &&
+				req.GetLimit() == 10 &&
+				len(req.GetStates()) == 2 &&
+				time.Since(since) > 23*time.Hour && time.Since(since) < 25*time.Hour &&
+				req.GetUntil() == nil
+		})).Return(response, nil)
+
+		return cfg, client
+	}
+
+	t.Run("table", func(t *testing.T) {
+		cfg, client := setup(t)
+		out := &bytes.Buffer{}
+
+		d := deployclient.Deployer{Client: client}
+		err := d.History(context.Background(), cfg, out)
+		assert.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Equal(t, []string{"ID", "CREATED", "CLUSTER", "REPOSITORY", "STATE"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"1", created.Local().Format(time.RFC3339), "dev-fss", "navikt/myrepo", "success"}, strings.Fields(lines[1]))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		cfg, client := setup(t)
+		cfg.Output = deployclient.OutputJSON
+		out := &bytes.Buffer{}
+
+		d := deployclient.Deployer{Client: client}
+		err := d.History(context.Background(), cfg, out)
+		assert.NoError(t, err)
+
+		decoded := &pb.ListDeploymentsResponse{}
+		err = protojson.Unmarshal(out.Bytes(), decoded)
+		assert.NoError(t, err)
+		assert.Equal(t, "next", decoded.GetNextCursor())
+		assert.Equal(t, "1", decoded.GetDeployments()[0].GetRequest().GetID())
+	})
+}
+
+func TestHistoryInvalidArguments(t *testing.T) {
+	for _, modify := range []func(cfg *deployclient.Config){
+		func(cfg *deployclient.Config) { cfg.Output = "yaml" },
+		func(cfg *deployclient.Config) { cfg.State = []string{"done"} },
+		func(cfg *deployclient.Config) { cfg.Until = "yesterday" },
+	} {
+		cfg := validConfig()
+		modify(cfg)
+
+		d := deployclient.Deployer{Client: pb.NewMockDeployClient(t)}
+		err := d.History(context.Background(), cfg, &bytes.Buffer{})
+		assert.Equal(t, deployclient.ExitInvocationFailure, deployclient.ErrorExitCode(err))
+	}
+}
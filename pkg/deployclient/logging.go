@@ -2,6 +2,7 @@ package deployclient
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"time"
 
@@ -53,4 +54,30 @@ func logDeployStatus(status *pb.DeploymentStatus) {
 		fn = log.Errorf
 	}
 	fn("Status: %s: %s", status.GetState(), status.GetMessage())
+	for _, line := range formatResourceDiff(status.GetDiff()) {
+		fn("%s", line)
+	}
+	for _, containerLog := range status.GetLogs() {
+		fn("Last %d log lines from container %q in pod %q:", len(containerLog.GetLines()), containerLog.GetContainer(), containerLog.GetPod())
+		for _, line := range containerLog.GetLines() {
+			fn("  | %s", line)
+		}
+	}
+}
+
+// formatResourceDiff renders one line per changed field, prefixed with
+// `+` for added fields, `-` for removed fields, and `~` for changed fields.
+func formatResourceDiff(diff *pb.ResourceDiff) []string {
+	lines := make([]string, 0, len(diff.GetFields()))
+	for _, field := range diff.GetFields() {
+		switch {
+		case len(field.GetLive()) == 0:
+			lines = append(lines, fmt.Sprintf("  + %s: %s", field.GetPath(), field.GetDesired()))
+		case len(field.GetDesired()) == 0:
+			lines = append(lines, fmt.Sprintf("  - %s: %s", field.GetPath(), field.GetLive()))
+		default:
+			lines = append(lines, fmt.Sprintf("  ~ %s: %s => %s", field.GetPath(), field.GetLive(), field.GetDesired()))
+		}
+	}
+	return lines
 }
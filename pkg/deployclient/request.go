@@ -12,16 +12,21 @@ func MakeDeploymentRequest(cfg Config, deadline time.Time, kubernetes *pb.Kubern
 	return &pb.DeploymentRequest{
 		Cluster:           cfg.Cluster,
 		Deadline:          pb.TimeAsTimestamp(deadline),
+		ForceOwnership:    cfg.ForceOwnership,
+		Diff:              cfg.Diff,
+		DryRun:            cfg.ServerDryRun || cfg.Diff,
 		GitRefSha:         cfg.Ref,
 		GithubEnvironment: cfg.Environment,
 		Kubernetes:        kubernetes,
+		Prune:             cfg.Prune,
 		Repository: &pb.GithubRepository{
 			Owner: cfg.Owner,
 			Name:  cfg.Repository,
 		},
-		Team:             cfg.Team,
-		Time:             pb.TimeAsTimestamp(time.Now()),
-		TriggerUrl:       annotations[GithubWorkflowRunURL],
-		DeployerUsername: os.Getenv("GITHUB_ACTOR"),
+		RollbackOnFailure: cfg.RollbackOnFailure,
+		Team:              cfg.Team,
+		Time:              pb.TimeAsTimestamp(time.Now()),
+		TriggerUrl:        annotations[GithubWorkflowRunURL],
+		DeployerUsername:  os.Getenv("GITHUB_ACTOR"),
 	}
 }
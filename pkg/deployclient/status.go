@@ -0,0 +1,74 @@
+package deployclient
+
+import (
+	"context"
+
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// Status prints the status history of an existing deployment, and follows it until it has finished.
+// The exit code reflects the final state of the deployment.
+func (d *Deployer) Status(ctx context.Context, cfg *Config, id string) error {
+	request := &pb.DeploymentRequest{
+		ID:           id,
+		Team:         cfg.Team,
+		ReplayStatus: true,
+	}
+
+	log.Infof("Following deployment %s...", id)
+
+	deployStatus, err := d.waitFinished(ctx, cfg, request)
+	if err != nil {
+		return err
+	}
+
+	return ErrorStatus(deployStatus)
+}
+
+// Follow the status stream of a deployment until it reaches a final state, reconnecting if the connection is lost.
+// After reconnecting, the stream resumes after the last status that was printed.
+func (d *Deployer) waitFinished(ctx context.Context, cfg *Config, request *pb.DeploymentRequest) (*pb.DeploymentStatus, error) {
+	request = proto.Clone(request).(*pb.DeploymentRequest)
+	for ctx.Err() == nil {
+		var stream pb.Deploy_StatusClient
+		err := retryUnavailable(cfg.RetryInterval, cfg.Retry, func() error {
+			var err error
+			stream, err = d.Client.Status(ctx, request)
+			return err
+		})
+		if grpcErrorCode(err) == codes.NotFound {
+			return nil, Errorf(ExitNoDeployment, formatGrpcError(err))
+		} else if err != nil {
+			return nil, Errorf(ExitUnavailable, formatGrpcError(err))
+		}
+
+		for ctx.Err() == nil {
+			deployStatus, err := stream.Recv()
+			if err != nil {
+				if cfg.Retry && grpcErrorRetriable(err) {
+					log.Warnf(formatGrpcError(err))
+					break
+				}
+				return nil, Errorf(ExitUnavailable, formatGrpcError(err))
+			}
+			logDeployStatus(deployStatus)
+			resumeAfter(request, deployStatus)
+			if deployStatus.GetState().Finished() {
+				return deployStatus, nil
+			}
+		}
+	}
+
+	return nil, Errorf(ExitTimeout, "timed out: %s", ctx.Err())
+}
+
+// resumeAfter makes the next status stream for the request start after the given status,
+// so that statuses are neither missed nor repeated when reconnecting.
+func resumeAfter(request *pb.DeploymentRequest, st *pb.DeploymentStatus) {
+	request.StatusSinceID = st.GetID()
+	request.StatusSinceTime = st.GetTime()
+	request.ReplayStatus = false
+}
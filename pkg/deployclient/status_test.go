@@ -0,0 +1,55 @@
+package deployclient_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/deployclient"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func replay(replay bool) interface{} {
+	return mock.MatchedBy(func(req *pb.DeploymentRequest) bool {
+		return req.GetID() == "1" && req.GetReplayStatus() == replay
+	})
+}
+
+func TestStatus(t *testing.T) {
+	cfg := validConfig()
+	cfg.Retry = true
+	cfg.RetryInterval = time.Millisecond
+
+	client := &pb.MockDeployClient{}
+
+	// History is replayed on the first connection only.
+	statusClient := &pb.MockDeploy_StatusClient{}
+	client.On("Status", mock.Anything, replay(true)).Return(statusClient, nil).Once()
+	statusClient.On("Recv").Return(&pb.DeploymentStatus{State: pb.DeploymentState_queued}, nil).Once()
+	statusClient.On("Recv").Return(nil, status.Errorf(codes.Unavailable, "hookd is shutting down")).Once()
+
+	reconnected := &pb.MockDeploy_StatusClient{}
+	client.On("Status", mock.Anything, replay(false)).Return(reconnected, nil).Once()
+	reconnected.On("Recv").Return(&pb.DeploymentStatus{State: pb.DeploymentState_failure}, nil).Once()
+
+	d := deployclient.Deployer{Client: client}
+	err := d.Status(context.Background(), cfg, "1")
+	assert.Error(t, err)
+	assert.Equal(t, deployclient.ExitDeploymentFailure, deployclient.ErrorExitCode(err))
+	client.AssertExpectations(t)
+}
+
+func TestStatusNotFound(t *testing.T) {
+	cfg := validConfig()
+
+	client := &pb.MockDeployClient{}
+	client.On("Status", mock.Anything, replay(true)).Return(nil, status.Errorf(codes.NotFound, "deployment \"1\" not found")).Once()
+
+	d := deployclient.Deployer{Client: client}
+	err := d.Status(context.Background(), cfg, "1")
+	assert.Equal(t, deployclient.ExitNoDeployment, deployclient.ErrorExitCode(err))
+}
@@ -1,22 +1,34 @@
 package config
 
 import (
+	"time"
+
 	"github.com/nais/liberator/pkg/conftools"
 	flag "github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	AutoCreateServiceAccount  bool   `json:"auto-create-service-account"`
-	Cluster                   string `json:"cluster"`
-	GRPC                      GRPC   `json:"grpc"`
-	HookdKey                  string `json:"hookd-key"`
-	LogFormat                 string `json:"log-format"`
-	LogLevel                  string `json:"log-level"`
-	MetricsListenAddr         string `json:"metrics-listen-address"`
-	MetricsPath               string `json:"metrics-path"`
-	OpenTelemetryCollectorURL string `json:"otel-exporter-otlp-endpoint"`
-	TeamNamespaces            bool   `json:"team-namespaces"`
+	AutoCreateServiceAccount  bool          `json:"auto-create-service-account"`
+	ClientPoolSize            int           `json:"client-pool-size"`
+	ClientPoolTTL             time.Duration `json:"client-pool-ttl"`
+	Cluster                   string        `json:"cluster"`
+	ConditionWatch            []string      `json:"condition-watch"`
+	DrainTimeout              time.Duration `json:"drain-timeout"`
+	GRPC                      GRPC          `json:"grpc"`
+	HookdKey                  string        `json:"hookd-key"`
+	LogFormat                 string        `json:"log-format"`
+	LogLevel                  string        `json:"log-level"`
+	LogTailLines              int64         `json:"log-tail-lines"`
+	MaxInFlight               int           `json:"max-in-flight"`
+	MaxRestarts               int32         `json:"max-restarts"`
+	MetricsListenAddr         string        `json:"metrics-listen-address"`
+	MetricsPath               string        `json:"metrics-path"`
+	OpenTelemetryCollectorURL string        `json:"otel-exporter-otlp-endpoint"`
+	OutboxMaxEntries          int           `json:"outbox-max-entries"`
+	OutboxPath                string        `json:"outbox-path"`
+	TeamClusterRole           string        `json:"team-cluster-role"`
+	TeamNamespaces            bool          `json:"team-namespaces"`
 }
 
 type GRPC struct {
@@ -26,16 +38,28 @@ type GRPC struct {
 }
 
 const (
+	AutoCreateServiceAccount = "auto-create-service-account"
+	ClientPoolSize           = "client-pool-size"
+	ClientPoolTTL            = "client-pool-ttl"
 	Cluster                  = "cluster"
+	ConditionWatch           = "condition-watch"
+	DrainTimeout             = "drain-timeout"
 	GrpcAuthentication       = "grpc.authentication"
 	GrpcServer               = "grpc.server"
 	GrpcUseTLS               = "grpc.use-tls"
 	HookdKey                 = "hookd-key"
 	LogFormat                = "log-format"
 	LogLevel                 = "log-level"
+	LogTailLines             = "log-tail-lines"
+	MaxInFlight              = "max-in-flight"
+	MaxRestarts              = "max-restarts"
 	MetricsListenAddr        = "metrics-listen-address"
 	MetricsPath              = "metrics-path"
 	OtelExporterOtlpEndpoint = "otel-exporter-otlp-endpoint"
+	OutboxMaxEntries         = "outbox-max-entries"
+	OutboxPath               = "outbox-path"
+	TeamClusterRole          = "team-cluster-role"
+	TeamNamespaces           = "team-namespaces"
 )
 
 func bindNAIS() {
@@ -47,15 +71,27 @@ func Initialize() *Config {
 	conftools.Initialize("deployd")
 	bindNAIS()
 
+	flag.Bool(AutoCreateServiceAccount, false, "Create the team service user and its role binding if they do not exist.")
+	flag.Bool(TeamNamespaces, false, "Create the team namespace if it does not exist.")
+	flag.String(TeamClusterRole, "admin", "ClusterRole granted to auto-created team service users within the team namespace.")
 	flag.Bool(GrpcAuthentication, false, "Use authentication on gRPC connection.")
 	flag.Bool(GrpcUseTLS, false, "Use TLS when connecting to gRPC server.")
 	flag.String(Cluster, "local", "Apply changes only within this cluster.")
+	flag.Int(ClientPoolSize, 100, "Maximum number of impersonated Kubernetes clients to keep for reuse.")
+	flag.Duration(ClientPoolTTL, 15*time.Minute, "Discard impersonated Kubernetes clients after this long.")
+	flag.StringSlice(ConditionWatch, []string{}, "Kinds to watch for a status condition after applying, comma separated: group/Kind=Condition")
+	flag.Duration(DrainTimeout, 2*time.Minute, "How long to let deployments in progress finish when shutting down; unfinished deployments are resumed on the next start.")
 	flag.String(GrpcServer, "127.0.0.1:9090", "gRPC server endpoint on hookd.")
 	flag.String(HookdKey, "", "Pre-shared key used for hookd authentication.")
 	flag.String(LogFormat, "text", "Log format, either 'json' or 'text'.")
 	flag.String(LogLevel, "debug", "Logging verbosity level.")
+	flag.Int64(LogTailLines, 50, "Number of log lines to capture from each failing container when a rollout fails.")
+	flag.Int(MaxInFlight, 20, "Maximum number of deployments to run concurrently; further requests are queued, taking turns between teams.")
+	flag.Int32(MaxRestarts, 3, "Fail a rollout when a crash-looping container has restarted this many times.")
 	flag.String(MetricsListenAddr, "127.0.0.1:8081", "Serve metrics on this address.")
 	flag.String(MetricsPath, "/metrics", "Serve metrics on this endpoint.")
+	flag.Int(OutboxMaxEntries, 10000, "Maximum number of unreported deployment statuses to keep.")
+	flag.String(OutboxPath, "", "Directory for persisting unreported deployment statuses across restarts. If empty, they are kept in memory only.")
 	flag.String(OtelExporterOtlpEndpoint, "", "OpenTelemetry collector endpoint URL.")
 
 	return &Config{}
@@ -1,9 +1,11 @@
 package deployd
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
+	"github.com/nais/deploy/pkg/deployd/diff"
 	"github.com/nais/deploy/pkg/deployd/kubeclient"
 	"github.com/nais/deploy/pkg/deployd/metrics"
 	"github.com/nais/deploy/pkg/deployd/operation"
@@ -16,7 +18,10 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	otrace "go.opentelemetry.io/otel/trace"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
 )
 
 // Annotate a resource with the deployment correlation ID.
@@ -29,11 +34,65 @@ func addCorrelationID(resource *unstructured.Unstructured, correlationID string)
 	resource.SetAnnotations(anno)
 }
 
+// A live resource has already been applied by a deployment if it carries the deployment's correlation ID.
+func appliedBy(live *unstructured.Unstructured, correlationID string) bool {
+	if live == nil {
+		return false
+	}
+	return live.GetAnnotations()[nais_io_v1.DeploymentCorrelationIDAnnotation] == correlationID
+}
+
+// Fetch the current state of a resource from the cluster, or nil if it does not exist.
+func liveResource(ctx context.Context, resourceInterface dynamic.ResourceInterface, name string) (*unstructured.Unstructured, error) {
+	live, err := resourceInterface.Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get live resource: %w", err)
+	}
+	return live, nil
+}
+
+// Report the difference between the live resource and the result of applying the desired resource.
+func diffStatus(request *pb.DeploymentRequest, identifier k8sutils.Identifier, live, applied *unstructured.Unstructured) *pb.DeploymentStatus {
+	resourceDiff := &pb.ResourceDiff{
+		Resource: identifier.String(),
+		Created:  live == nil,
+	}
+
+	var status *pb.DeploymentStatus
+	switch {
+	case resourceDiff.Created:
+		status = pb.NewInProgressStatus(request, "%s would be created", identifier.String())
+	default:
+		resourceDiff.Fields = diff.Resources(live, applied)
+		if len(resourceDiff.Fields) == 0 {
+			status = pb.NewInProgressStatus(request, "%s is unchanged", identifier.String())
+		} else {
+			status = pb.NewInProgressStatus(request, "%s would change %d field(s)", identifier.String(), len(resourceDiff.Fields))
+		}
+	}
+
+	status.Diff = resourceDiff
+	return status
+}
+
+// Run applies all resources in the deployment request, wave by wave, and blocks until they have been rolled out.
 func Run(op *operation.Operation, client kubeclient.Interface) {
-	op.Logger.Infof("Starting deployment")
+	if op.Request.GetResume() {
+		op.Logger.Infof("Resuming deployment after restart")
+		op.StatusChan <- pb.NewInProgressStatus(op.Request, "Resuming deployment after NAIS deploy was restarted")
+	} else {
+		op.Logger.Infof("Starting deployment")
+	}
 
 	failure := func(err error) {
 		op.Cancel()
+		if op.Cancelled() {
+			op.StatusChan <- pb.NewCancelledStatus(op.Request)
+			return
+		}
 		op.StatusChan <- pb.NewFailureStatus(op.Request, err)
 	}
 
@@ -53,109 +112,197 @@ func Run(op *operation.Operation, client kubeclient.Interface) {
 		return
 	}
 
-	wait := sync.WaitGroup{}
+	// Computing a diff requires applying the resources without persisting them.
+	dryRun := op.Request.GetDryRun() || op.Request.GetDiff()
+	deployOptions := strategy.DeployOptions{
+		Force:  op.Request.GetForceOwnership(),
+		DryRun: dryRun,
+	}
+
+	waves, err := orderResources(resources)
+	if err != nil {
+		failure(err)
+		op.Trace.SetStatus(codes.Error, err.Error())
+		op.Trace.End()
+		return
+	}
+
 	errors := make(chan error, len(resources))
 
-	for _, resource := range resources {
-		addCorrelationID(&resource, op.Request.GetID())
-		identifier := k8sutils.ResourceIdentifier(resource)
-
-		logger := op.Logger.WithFields(log.Fields{
-			"name":      identifier.Name,
-			"namespace": identifier.Namespace,
-			"gvk":       identifier.GroupVersionKind,
-		})
-
-		spanName := fmt.Sprintf("%s/%s", identifier.Kind, identifier.Name)
-		_, span := telemetry.Tracer().Start(op.Context, spanName, otrace.WithSpanKind(otrace.SpanKindClient))
-		telemetry.AddDeploymentRequestSpanAttributes(span, op.Request)
-		span.SetAttributes(
-			attribute.KeyValue{
-				Key:   "k8s.kind",
-				Value: attribute.StringValue(identifier.Kind),
-			},
-			attribute.KeyValue{
-				Key:   "k8s.name",
-				Value: attribute.StringValue(identifier.Name),
-			},
-			attribute.KeyValue{
-				Key:   "k8s.namespace",
-				Value: attribute.StringValue(identifier.Namespace),
-			},
-			attribute.KeyValue{
-				Key:   "k8s.apiVersion",
-				Value: attribute.StringValue(identifier.GroupVersion().String()),
-			},
-		)
-
-		resourceInterface, err := client.ResourceInterface(&resource)
-		if err == nil {
-			_, err = strategy.NewDeployStrategy(resourceInterface).Deploy(op.Context, resource, span)
-		}
+	// Logs from failing containers are attached to the final status only.
+	var containerLogs []*pb.ContainerLog
+	containerLogsLock := sync.Mutex{}
 
-		if err != nil {
-			err = fmt.Errorf("%s: %s", identifier.String(), err)
-			span.SetStatus(codes.Error, err.Error())
-			span.End()
-			logger.Error(err)
-			errors <- err
+	for i, wave := range waves {
+		// Later waves depend on earlier ones, so a failed wave or a cancellation stops the rollout.
+		// Dry runs carry on in order to report all problems in one go.
+		if len(errors) > 0 && !dryRun || op.Cancelled() {
 			break
 		}
 
-		span.AddEvent("Resource saved to Kubernetes")
+		if len(waves) > 1 {
+			op.StatusChan <- pb.NewInProgressStatus(op.Request, "Applying wave %d (%d of %d)", wave.number, i+1, len(waves))
+		}
+
+		wait := sync.WaitGroup{}
+
+		for _, resource := range wave.resources {
+			addCorrelationID(&resource, op.Request.GetID())
+			identifier := k8sutils.ResourceIdentifier(resource)
+
+			logger := op.Logger.WithFields(log.Fields{
+				"name":      identifier.Name,
+				"namespace": identifier.Namespace,
+				"gvk":       identifier.GroupVersionKind,
+			})
 
-		metrics.KubernetesResources(op.Request.GetTeam(), identifier.Kind, identifier.Name).Inc()
+			spanName := fmt.Sprintf("%s/%s", identifier.Kind, identifier.Name)
+			_, span := telemetry.Tracer().Start(op.Context, spanName, otrace.WithSpanKind(otrace.SpanKindClient))
+			telemetry.AddDeploymentRequestSpanAttributes(span, op.Request)
+			span.SetAttributes(
+				attribute.KeyValue{
+					Key:   "k8s.kind",
+					Value: attribute.StringValue(identifier.Kind),
+				},
+				attribute.KeyValue{
+					Key:   "k8s.name",
+					Value: attribute.StringValue(identifier.Name),
+				},
+				attribute.KeyValue{
+					Key:   "k8s.namespace",
+					Value: attribute.StringValue(identifier.Namespace),
+				},
+				attribute.KeyValue{
+					Key:   "k8s.apiVersion",
+					Value: attribute.StringValue(identifier.GroupVersion().String()),
+				},
+			)
+
+			var live, applied *unstructured.Unstructured
+			adopted := false
+			resourceInterface, err := client.ResourceInterface(&resource)
+			if err == nil && (op.Request.GetDiff() || op.Request.GetResume()) {
+				live, err = liveResource(op.Context, resourceInterface, resource.GetName())
+			}
+			if err == nil && op.Request.GetResume() {
+				// Resources applied before deployd restarted are only watched.
+				adopted = appliedBy(live, op.Request.GetID())
+			}
+			if err == nil && !adopted {
+				applied, err = strategy.NewDeployStrategy(resourceInterface, deployOptions).Deploy(op.Context, resource, span)
+			}
 
-		op.StatusChan <- pb.NewInProgressStatus(op.Request, "Successfully applied %s", identifier.String())
-		wait.Add(1)
+			if err != nil {
+				err = fmt.Errorf("%s: %s", identifier.String(), err)
+				span.SetStatus(codes.Error, err.Error())
+				span.End()
+				logger.Error(err)
+				errors <- err
+				if dryRun {
+					// Validate every resource so that all problems are reported in one go.
+					op.StatusChan <- pb.NewInProgressStatus(op.Request, "Dry run failed for %s", err)
+					continue
+				}
+				break
+			}
 
-		go func(logger *log.Entry, resource unstructured.Unstructured) {
-			deadline, _ := op.Context.Deadline()
-			op.Logger.Debugf("Monitoring rollout status of '%s/%s' in namespace '%s', deadline %s", identifier.GroupVersionKind, identifier.Name, identifier.Namespace, deadline)
-			strat := strategy.NewWatchStrategy(identifier.GroupVersionKind, client)
-			status := strat.Watch(op, resource, span)
-			if status != nil {
-				span.AddEvent(status.Message)
-				if status.GetState().IsError() {
-					span.SetStatus(codes.Error, status.Message)
-					errors <- fmt.Errorf(status.Message)
-					op.Logger.Error(status.Message)
+			if dryRun {
+				span.SetStatus(codes.Ok, "Resource validated by Kubernetes")
+				span.End()
+				if op.Request.GetDiff() {
+					op.StatusChan <- diffStatus(op.Request, identifier, live, applied)
 				} else {
-					span.SetStatus(codes.Ok, status.Message)
-					op.Logger.Infof(status.Message)
+					op.StatusChan <- pb.NewInProgressStatus(op.Request, "Successfully validated %s (dry run)", identifier.String())
 				}
-				status.State = pb.DeploymentState_in_progress
-				op.StatusChan <- status
+				continue
+			}
+
+			if adopted {
+				span.AddEvent("Resource adopted after restart")
+				op.StatusChan <- pb.NewInProgressStatus(op.Request, "Resuming rollout of %s", identifier.String())
 			} else {
-				span.SetStatus(codes.Ok, "Resource saved to Kubernetes")
+				span.AddEvent("Resource saved to Kubernetes")
+				metrics.KubernetesResources(op.Request.GetTeam(), identifier.Kind, identifier.Name).Inc()
+				op.StatusChan <- pb.NewInProgressStatus(op.Request, "Successfully applied %s", identifier.String())
 			}
+			wait.Add(1)
 
-			op.Logger.Debugf("Finished monitoring rollout status of '%s/%s' in namespace '%s'", identifier.GroupVersionKind, identifier.Name, identifier.Namespace)
-			wait.Done()
-			span.End()
-		}(logger, resource)
-	}
+			go func(logger *log.Entry, resource unstructured.Unstructured) {
+				deadline, _ := op.Context.Deadline()
+				op.Logger.Debugf("Monitoring rollout status of '%s/%s' in namespace '%s', deadline %s", identifier.GroupVersionKind, identifier.Name, identifier.Namespace, deadline)
+				strat := strategy.NewWatchStrategy(identifier.GroupVersionKind, client)
+				status := strat.Watch(op, resource, span)
+				if status != nil {
+					span.AddEvent(status.Message)
+					if status.GetState().IsError() {
+						span.SetStatus(codes.Error, status.Message)
+						errors <- fmt.Errorf(status.Message)
+						op.Logger.Error(status.Message)
+						containerLogsLock.Lock()
+						containerLogs = append(containerLogs, status.GetLogs()...)
+						containerLogsLock.Unlock()
+						status.Logs = nil
+					} else {
+						span.SetStatus(codes.Ok, status.Message)
+						op.Logger.Infof(status.Message)
+					}
+					status.State = pb.DeploymentState_in_progress
+					op.StatusChan <- status
+				} else {
+					span.SetStatus(codes.Ok, "Resource saved to Kubernetes")
+				}
 
-	op.StatusChan <- pb.NewInProgressStatus(op.Request, "All resources saved to Kubernetes; waiting for completion")
+				op.Logger.Debugf("Finished monitoring rollout status of '%s/%s' in namespace '%s'", identifier.GroupVersionKind, identifier.Name, identifier.Namespace)
+				wait.Done()
+				span.End()
+			}(logger, resource)
+		}
+
+		// An apply error stops the loop above, leaving the remaining resources unsaved.
+		if !dryRun && len(errors) == 0 {
+			if len(waves) > 1 {
+				op.StatusChan <- pb.NewInProgressStatus(op.Request, "All resources in wave %d saved to Kubernetes; waiting for completion", wave.number)
+			} else {
+				op.StatusChan <- pb.NewInProgressStatus(op.Request, "All resources saved to Kubernetes; waiting for completion")
+			}
+		}
 
-	go func() {
 		op.Logger.Debugf("Waiting for resources to be successfully rolled out")
 		wait.Wait()
 		op.Logger.Debugf("Finished monitoring all resources")
-		op.Cancel()
+	}
 
-		errCount := len(errors)
-		if errCount > 0 {
-			err := <-errors
-			close(errors)
-			aggregateError := fmt.Errorf("%s (total of %d errors)", err, errCount)
-			op.StatusChan <- pb.NewFailureStatus(op.Request, aggregateError)
-			op.Trace.SetStatus(codes.Error, aggregateError.Error())
-		} else {
-			op.StatusChan <- pb.NewSuccessStatus(op.Request)
-			op.Trace.SetStatus(codes.Ok, "All resources rolled out successfully")
+	// Resources are only pruned once everything else has been rolled out successfully.
+	var pruneErr error
+	errCount := len(errors)
+	if errCount == 0 && !op.Cancelled() && op.Request.GetPrune() && len(op.Request.GetPruneResources()) > 0 {
+		pruneErr = prune(op, client, dryRun)
+	}
+
+	op.Cancel()
+
+	if op.Cancelled() {
+		op.StatusChan <- pb.NewCancelledStatus(op.Request)
+		op.Trace.SetStatus(codes.Error, operation.ErrCancelled.Error())
+	} else if errCount > 0 {
+		err := <-errors
+		close(errors)
+		aggregateError := fmt.Errorf("%s (total of %d errors)", err, errCount)
+		if op.Request.GetRollbackOnFailure() && !dryRun {
+			// The deployment is still reported as failed, so that the caller can act on it.
+			aggregateError = fmt.Errorf("%w; %s", aggregateError, rollback(op, client))
 		}
+		status := pb.NewFailureStatus(op.Request, aggregateError)
+		status.Logs = containerLogs
+		op.StatusChan <- status
+		op.Trace.SetStatus(codes.Error, aggregateError.Error())
+	} else if pruneErr != nil {
+		op.StatusChan <- pb.NewFailureStatus(op.Request, pruneErr)
+		op.Trace.SetStatus(codes.Error, pruneErr.Error())
+	} else {
+		op.StatusChan <- pb.NewSuccessStatus(op.Request)
+		op.Trace.SetStatus(codes.Ok, "All resources rolled out successfully")
+	}
 
-		op.Trace.End()
-	}()
+	op.Trace.End()
 }
@@ -36,6 +36,7 @@ type processCallback func(ctx context.Context, rig *testRig, test testSpec) erro
 
 type testSpec struct {
 	fixture           string               // path to testdata to deploy to cluster
+	dryRun            bool                 // validate resources on the server without persisting them
 	timeout           time.Duration        // time to allow for deploy to reach end state
 	endStatus         *pb.DeploymentStatus // which end state we expect
 	deployedResources []client.Object      // list of Kubernetes resources expected to be applied to the cluster - only checks name and namespace
@@ -60,6 +61,18 @@ var tests = []testSpec{
 		},
 	},
 
+	// Dry runs are validated by the server, but not persisted
+	{
+		fixture: "testdata/configmap-dryrun.json",
+		dryRun:  true,
+		timeout: 2 * time.Second,
+		endStatus: &pb.DeploymentStatus{
+			State:   pb.DeploymentState_success,
+			Message: "Dry run completed successfully; no changes were persisted.",
+		},
+		deployedResources: nil,
+	},
+
 	// Check that deploy times out
 	{
 		fixture: "testdata/application-timeout.json",
@@ -84,7 +97,7 @@ var tests = []testSpec{
 		timeout: 2 * time.Second,
 		endStatus: &pb.DeploymentStatus{
 			State:   pb.DeploymentState_failure,
-			Message: `nais.io/v1alpha1, Kind=Application, Namespace=not-aura, Name=myapplication-unauthorized: get existing resource: applications.nais.io "myapplication-unauthorized" is forbidden: User "system:serviceaccount:aura:serviceuser-aura" cannot get resource "applications" in API group "nais.io" in the namespace "not-aura" (total of 1 errors)`,
+			Message: `nais.io/v1alpha1, Kind=Application, Namespace=not-aura, Name=myapplication-unauthorized: applying resource: applications.nais.io "myapplication-unauthorized" is forbidden: User "system:serviceaccount:aura:serviceuser-aura" cannot patch resource "applications" in API group "nais.io" in the namespace "not-aura" (total of 1 errors)`,
 		},
 		deployedResources: nil,
 	},
@@ -158,7 +171,7 @@ var tests = []testSpec{
 		timeout: 2 * time.Second,
 		endStatus: &pb.DeploymentStatus{
 			State:   pb.DeploymentState_failure,
-			Message: "nais.io/v1alpha1, Kind=Application, Namespace=aura, Name=myapplication-unknown-fields: creating resource: strict decoding error:\n| ⚠️ unknown field \"spec.nestedField\"\n| ⚠️ unknown field \"spec.unknownField\"\n| The fields might be misspelled, incorrectly indented, or unsupported. Fields are case sensitive.\n| Please verify your resource against the reference documentation at https://doc.nais.io/workloads/application/reference/application-spec/ (total of 1 errors)",
+			Message: "nais.io/v1alpha1, Kind=Application, Namespace=aura, Name=myapplication-unknown-fields: applying resource: strict decoding error:\n| ⚠️ unknown field \"spec.nestedField\"\n| ⚠️ unknown field \"spec.unknownField\"\n| The fields might be misspelled, incorrectly indented, or unsupported. Fields are case sensitive.\n| Please verify your resource against the reference documentation at https://doc.nais.io/workloads/application/reference/application-spec/ (total of 1 errors)",
 		},
 		deployedResources: nil,
 	},
@@ -169,7 +182,7 @@ var tests = []testSpec{
 		timeout: 2 * time.Second,
 		endStatus: &pb.DeploymentStatus{
 			State:   pb.DeploymentState_failure,
-			Message: "nais.io/v1alpha1, Kind=Application, Namespace=aura, Name=myapplication: updating resource: strict decoding error:\n| ⚠️ unknown field \"spec.nestedField\"\n| ⚠️ unknown field \"spec.unknownField\"\n| The fields might be misspelled, incorrectly indented, or unsupported. Fields are case sensitive.\n| Please verify your resource against the reference documentation at https://doc.nais.io/workloads/application/reference/application-spec/ (total of 1 errors)",
+			Message: "nais.io/v1alpha1, Kind=Application, Namespace=aura, Name=myapplication: applying resource: strict decoding error:\n| ⚠️ unknown field \"spec.nestedField\"\n| ⚠️ unknown field \"spec.unknownField\"\n| The fields might be misspelled, incorrectly indented, or unsupported. Fields are case sensitive.\n| Please verify your resource against the reference documentation at https://doc.nais.io/workloads/application/reference/application-spec/ (total of 1 errors)",
 		},
 		deployedResources: nil,
 	},
@@ -446,6 +459,7 @@ func subTest(t *testing.T, rig *testRig, test testSpec, team string) {
 			ID:         test.fixture,
 			Team:       team,
 			Kubernetes: kubes,
+			DryRun:     test.dryRun,
 		},
 		Trace:      span,
 		StatusChan: rig.statusChan,
@@ -0,0 +1,96 @@
+package deployd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// WaveAnnotation assigns a resource to a sync wave. Waves are applied in ascending order,
+// and each wave must be rolled out successfully before the next wave is applied.
+// Resources without the annotation belong to wave 0.
+const WaveAnnotation = "deploy.nais.io/wave"
+
+// Dependency classes; resources in a lower class are applied before resources in a higher class within the same wave.
+const (
+	classClusterScoped = iota
+	classConfiguration
+	classDefault
+	classWorkload
+)
+
+var kindClasses = map[string]int{
+	"Namespace":                classClusterScoped,
+	"CustomResourceDefinition": classClusterScoped,
+
+	"ServiceAccount":     classConfiguration,
+	"ConfigMap":          classConfiguration,
+	"Secret":             classConfiguration,
+	"Role":               classConfiguration,
+	"RoleBinding":        classConfiguration,
+	"ClusterRole":        classConfiguration,
+	"ClusterRoleBinding": classConfiguration,
+
+	"Application": classWorkload,
+	"Naisjob":     classWorkload,
+	"Deployment":  classWorkload,
+	"StatefulSet": classWorkload,
+	"DaemonSet":   classWorkload,
+	"Job":         classWorkload,
+	"CronJob":     classWorkload,
+	"Pod":         classWorkload,
+}
+
+type wave struct {
+	number    int
+	resources []unstructured.Unstructured
+}
+
+func kindClass(resource unstructured.Unstructured) int {
+	class, ok := kindClasses[resource.GetKind()]
+	if !ok {
+		return classDefault
+	}
+	return class
+}
+
+func waveNumber(resource unstructured.Unstructured) (int, error) {
+	value, ok := resource.GetAnnotations()[WaveAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s/%s: annotation %s must be an integer: %q", resource.GetKind(), resource.GetName(), WaveAnnotation, value)
+	}
+	return number, nil
+}
+
+// orderResources groups resources into sync waves, and sorts each wave by dependency class.
+// Resources of the same class keep their order from the payload.
+func orderResources(resources []unstructured.Unstructured) ([]wave, error) {
+	byNumber := make(map[int][]unstructured.Unstructured)
+	for _, resource := range resources {
+		number, err := waveNumber(resource)
+		if err != nil {
+			return nil, err
+		}
+		byNumber[number] = append(byNumber[number], resource)
+	}
+
+	waves := make([]wave, 0, len(byNumber))
+	for number, resources := range byNumber {
+		sort.SliceStable(resources, func(i, j int) bool {
+			return kindClass(resources[i]) < kindClass(resources[j])
+		})
+		waves = append(waves, wave{number: number, resources: resources})
+	}
+
+	sort.Slice(waves, func(i, j int) bool {
+		return waves[i].number < waves[j].number
+	})
+
+	return waves, nil
+}
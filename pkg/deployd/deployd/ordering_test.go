@@ -0,0 +1,67 @@
+package deployd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func resource(kind, name, wave string) unstructured.Unstructured {
+	u := unstructured.Unstructured{}
+	u.SetKind(kind)
+	u.SetName(name)
+	if len(wave) > 0 {
+		u.SetAnnotations(map[string]string{WaveAnnotation: wave})
+	}
+	return u
+}
+
+func names(w wave) []string {
+	result := make([]string, len(w.resources))
+	for i := range w.resources {
+		result[i] = w.resources[i].GetName()
+	}
+	return result
+}
+
+func TestOrderResourcesByKind(t *testing.T) {
+	waves, err := orderResources([]unstructured.Unstructured{
+		resource("Application", "app", ""),
+		resource("Topic", "topic", ""),
+		resource("ConfigMap", "config", ""),
+		resource("Namespace", "ns", ""),
+		resource("Secret", "secret", ""),
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, waves, 1)
+	assert.Equal(t, []string{"ns", "config", "secret", "topic", "app"}, names(waves[0]))
+}
+
+func TestOrderResourcesByWave(t *testing.T) {
+	waves, err := orderResources([]unstructured.Unstructured{
+		resource("Application", "consumer", ""),
+		resource("Application", "late", "5"),
+		resource("Topic", "topic", "-1"),
+		resource("AivenApplication", "aiven", "-1"),
+		resource("ConfigMap", "config", ""),
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, waves, 3)
+	assert.Equal(t, -1, waves[0].number)
+	assert.Equal(t, []string{"topic", "aiven"}, names(waves[0]))
+	assert.Equal(t, 0, waves[1].number)
+	assert.Equal(t, []string{"config", "consumer"}, names(waves[1]))
+	assert.Equal(t, 5, waves[2].number)
+	assert.Equal(t, []string{"late"}, names(waves[2]))
+}
+
+func TestOrderResourcesInvalidWave(t *testing.T) {
+	_, err := orderResources([]unstructured.Unstructured{
+		resource("Application", "app", "first"),
+	})
+
+	assert.EqualError(t, err, `Application/app: annotation deploy.nais.io/wave must be an integer: "first"`)
+}
@@ -0,0 +1,116 @@
+package deployd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/nais/deploy/pkg/deployd/kubeclient"
+	"github.com/nais/deploy/pkg/deployd/operation"
+	"github.com/nais/deploy/pkg/k8sutils"
+	"github.com/nais/deploy/pkg/pb"
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ProtectAnnotation prevents a resource from being pruned after it has been removed from the deployment.
+const ProtectAnnotation = "deploy.nais.io/protect"
+
+func protected(resource *unstructured.Unstructured) bool {
+	value, _ := strconv.ParseBool(resource.GetAnnotations()[ProtectAnnotation])
+	return value
+}
+
+// Only resources previously applied by deployd carry the correlation annotation.
+func managedByDeployd(resource *unstructured.Unstructured) bool {
+	_, ok := resource.GetAnnotations()[nais_io_v1.DeploymentCorrelationIDAnnotation]
+	return ok
+}
+
+// prune deletes resources that were part of the previous deployment, but are missing from this one.
+// Every deletion, or reason for skipping one, is reported on the status channel.
+func prune(op *operation.Operation, client kubeclient.Interface, dryRun bool) error {
+	var firstError error
+	errCount := 0
+
+	for _, id := range op.Request.GetPruneResources() {
+		resource := &unstructured.Unstructured{}
+		resource.SetGroupVersionKind(schema.GroupVersionKind{
+			Group:   id.GetGroup(),
+			Version: id.GetVersion(),
+			Kind:    id.GetKind(),
+		})
+		resource.SetNamespace(id.GetNamespace())
+		resource.SetName(id.GetName())
+		identifier := k8sutils.ResourceIdentifier(*resource)
+
+		err := pruneResource(op, client, resource, identifier, dryRun)
+		if err != nil {
+			err = fmt.Errorf("%s: prune: %w", identifier.String(), err)
+			op.Logger.Error(err)
+			if firstError == nil {
+				firstError = err
+			}
+			errCount++
+		}
+	}
+
+	if errCount > 0 {
+		return fmt.Errorf("%s (total of %d errors)", firstError, errCount)
+	}
+
+	return nil
+}
+
+func pruneResource(op *operation.Operation, client kubeclient.Interface, resource *unstructured.Unstructured, identifier k8sutils.Identifier, dryRun bool) error {
+	resourceInterface, err := client.ResourceInterface(resource)
+	if err != nil {
+		return err
+	}
+
+	live, err := liveResource(op.Context, resourceInterface, resource.GetName())
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case live == nil:
+		op.Logger.Debugf("%s no longer exists; nothing to prune", identifier.String())
+		return nil
+	case !managedByDeployd(live):
+		op.StatusChan <- pb.NewInProgressStatus(op.Request, "Not pruning %s: resource is not managed by NAIS deploy", identifier.String())
+		return nil
+	case !appliedBy(live, op.Request.GetPruneDeploymentID()):
+		// Applied by another deployment since, for instance from another repository.
+		op.StatusChan <- pb.NewInProgressStatus(op.Request, "Not pruning %s: resource has been applied by another deployment since", identifier.String())
+		return nil
+	case protected(live):
+		op.StatusChan <- pb.NewInProgressStatus(op.Request, "Not pruning %s: resource is protected by the %s annotation", identifier.String(), ProtectAnnotation)
+		return nil
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	deleteOptions := metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	}
+	if dryRun {
+		deleteOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	err = resourceInterface.Delete(op.Context, resource.GetName(), deleteOptions)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if dryRun {
+		op.StatusChan <- pb.NewInProgressStatus(op.Request, "Would prune %s (dry run)", identifier.String())
+	} else {
+		op.StatusChan <- pb.NewInProgressStatus(op.Request, "Pruned %s", identifier.String())
+	}
+
+	return nil
+}
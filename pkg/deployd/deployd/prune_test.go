@@ -0,0 +1,27 @@
+package deployd
+
+import (
+	"testing"
+
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func annotated(annotations map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func TestProtected(t *testing.T) {
+	assert.False(t, protected(annotated(nil)))
+	assert.False(t, protected(annotated(map[string]string{ProtectAnnotation: "false"})))
+	assert.False(t, protected(annotated(map[string]string{ProtectAnnotation: "yes please"})))
+	assert.True(t, protected(annotated(map[string]string{ProtectAnnotation: "true"})))
+}
+
+func TestManagedByDeployd(t *testing.T) {
+	assert.False(t, managedByDeployd(annotated(nil)))
+	assert.True(t, managedByDeployd(annotated(map[string]string{nais_io_v1.DeploymentCorrelationIDAnnotation: "abc"})))
+}
@@ -0,0 +1,76 @@
+package deployd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nais/deploy/pkg/deployd/kubeclient"
+	"github.com/nais/deploy/pkg/deployd/operation"
+	"github.com/nais/deploy/pkg/deployd/strategy"
+	"github.com/nais/deploy/pkg/k8sutils"
+	"github.com/nais/deploy/pkg/pb"
+)
+
+// Time allowed for re-applying the previous deployment.
+// The deadline of the failed deployment may already have passed, so it cannot be reused.
+const rollbackTimeout = 2 * time.Minute
+
+// rollback re-applies the resources of the last successful deployment after a failed rollout.
+// Progress is reported on the status channel; the returned message summarizes the outcome.
+func rollback(op *operation.Operation, client kubeclient.Interface) string {
+	previousID := op.Request.GetRollbackDeploymentID()
+	if op.Request.GetRollback() == nil {
+		op.StatusChan <- pb.NewInProgressStatus(op.Request, "Rollback on failure requested, but there is no previous successful deployment to roll back to")
+		return "no previous deployment to roll back to"
+	}
+
+	op.StatusChan <- pb.NewInProgressStatus(op.Request, "Deployment failed; rolling back to deployment %s", previousID)
+
+	js, err := op.Request.GetRollback().JSONResources()
+	if err != nil {
+		return fmt.Sprintf("rollback to deployment %s failed: %s", previousID, err)
+	}
+	resources, err := k8sutils.ResourcesFromJSON(js)
+	if err != nil {
+		return fmt.Sprintf("rollback to deployment %s failed: %s", previousID, err)
+	}
+	waves, err := orderResources(resources)
+	if err != nil {
+		return fmt.Sprintf("rollback to deployment %s failed: %s", previousID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(op.Context), rollbackTimeout)
+	defer cancel()
+
+	// The previous deployment owned these fields before, so take them back unconditionally.
+	deployOptions := strategy.DeployOptions{Force: true}
+
+	errCount := 0
+	for _, wave := range waves {
+		for _, resource := range wave.resources {
+			addCorrelationID(&resource, op.Request.GetID())
+			identifier := k8sutils.ResourceIdentifier(resource)
+
+			resourceInterface, err := client.ResourceInterface(&resource)
+			if err == nil {
+				_, err = strategy.NewDeployStrategy(resourceInterface, deployOptions).Deploy(ctx, resource, op.Trace)
+			}
+
+			if err != nil {
+				errCount++
+				op.Logger.Errorf("Roll back %s: %s", identifier.String(), err)
+				op.StatusChan <- pb.NewInProgressStatus(op.Request, "Rollback of %s failed: %s", identifier.String(), err)
+				continue
+			}
+
+			op.StatusChan <- pb.NewInProgressStatus(op.Request, "Rolled back %s", identifier.String())
+		}
+	}
+
+	if errCount > 0 {
+		return fmt.Sprintf("rollback to deployment %s failed for %d of %d resources", previousID, errCount, len(resources))
+	}
+
+	return fmt.Sprintf("rolled back to deployment %s", previousID)
+}
@@ -0,0 +1,177 @@
+// Package diff computes structured differences between the live state of a Kubernetes resource
+// and the state it would have after being applied.
+package diff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/nais/deploy/pkg/pb"
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Fields populated by the API server, or changed on every deployment, that should never show up in a diff.
+var ignoredPaths = [][]string{
+	{"status"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "generation"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "selfLink"},
+	{"metadata", "uid"},
+	{"metadata", "annotations", nais_io_v1.DeploymentCorrelationIDAnnotation},
+}
+
+// Fields of a Secret holding values that must never be revealed in a diff. Only the changed keys are reported.
+var secretFields = []string{"data", "stringData"}
+
+// Shown instead of the value of a secret key.
+const Redacted = "<redacted>"
+
+// Resources returns the difference between the live object in the cluster and the desired object.
+// If live is nil, the resource does not exist yet and will be created.
+func Resources(live, desired *unstructured.Unstructured) []*pb.FieldDiff {
+	var liveObject map[string]interface{}
+	if live != nil {
+		liveObject = strip(live.Object)
+	}
+	desiredObject := strip(desired.Object)
+
+	secret := isSecret(desired)
+	if secret {
+		hashSecretValues(liveObject)
+		hashSecretValues(desiredObject)
+	}
+
+	diffs := make([]*pb.FieldDiff, 0)
+	compare(nil, liveObject, desiredObject, &diffs)
+
+	if secret {
+		redact(diffs)
+	}
+	return diffs
+}
+
+func isSecret(resource *unstructured.Unstructured) bool {
+	gvk := resource.GroupVersionKind()
+	return gvk.Group == "" && gvk.Kind == "Secret"
+}
+
+// hashSecretValues replaces the values of a Secret with their checksums,
+// so that changed keys can be found without the values ever ending up in a diff.
+func hashSecretValues(object map[string]interface{}) {
+	for _, field := range secretFields {
+		values, ok := object[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range values {
+			sum := sha256.Sum256([]byte(encode(value)))
+			values[key] = hex.EncodeToString(sum[:])
+		}
+	}
+}
+
+// redact replaces the values of Secret keys in diffs, leaving only whether they were added, changed or removed.
+func redact(diffs []*pb.FieldDiff) {
+	for _, d := range diffs {
+		for _, field := range secretFields {
+			if !strings.HasPrefix(d.Path, field+".") && !strings.HasPrefix(d.Path, field+"[") && d.Path != field {
+				continue
+			}
+			if len(d.Live) > 0 {
+				d.Live = Redacted
+			}
+			if len(d.Desired) > 0 {
+				d.Desired = Redacted
+			}
+		}
+	}
+}
+
+// strip returns a copy of the object with all ignored fields removed.
+func strip(object map[string]interface{}) map[string]interface{} {
+	object = runtime.DeepCopyJSON(object)
+	for _, path := range ignoredPaths {
+		unstructured.RemoveNestedField(object, path...)
+	}
+	// Removing the last annotation leaves an empty map behind, which would otherwise show up as a change.
+	annotations, found, _ := unstructured.NestedMap(object, "metadata", "annotations")
+	if found && len(annotations) == 0 {
+		unstructured.RemoveNestedField(object, "metadata", "annotations")
+	}
+	return object
+}
+
+// compare walks both objects recursively, and records a diff for every leaf value that differs.
+// Lists are compared as a whole.
+func compare(path []string, live, desired interface{}, diffs *[]*pb.FieldDiff) {
+	liveMap, liveIsMap := live.(map[string]interface{})
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+
+	if (liveIsMap || live == nil) && (desiredIsMap || desired == nil) && (liveIsMap || desiredIsMap) {
+		for _, key := range unionKeys(liveMap, desiredMap) {
+			compare(append(path, key), liveMap[key], desiredMap[key], diffs)
+		}
+		return
+	}
+
+	if reflect.DeepEqual(live, desired) {
+		return
+	}
+
+	*diffs = append(*diffs, &pb.FieldDiff{
+		Path:    formatPath(path),
+		Live:    encode(live),
+		Desired: encode(desired),
+	})
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatPath renders a path in the same style as `kubectl explain`.
+// Keys that cannot be expressed with dot notation, such as annotation names, are quoted.
+func formatPath(path []string) string {
+	s := &strings.Builder{}
+	for i, key := range path {
+		if strings.ContainsAny(key, "./ ") {
+			s.WriteString(fmt.Sprintf("[%q]", key))
+			continue
+		}
+		if i > 0 {
+			s.WriteRune('.')
+		}
+		s.WriteString(key)
+	}
+	return s.String()
+}
+
+func encode(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,127 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/nais/deploy/pkg/deployd/diff"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func configMap(metadata, data map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   metadata,
+		"data":       data,
+	}}
+}
+
+func TestResources(t *testing.T) {
+	live := configMap(map[string]interface{}{
+		"name":              "foo",
+		"namespace":         "aura",
+		"uid":               "1234",
+		"resourceVersion":   "42",
+		"generation":        int64(3),
+		"creationTimestamp": "2024-01-01T00:00:00Z",
+		"managedFields":     []interface{}{map[string]interface{}{"manager": "nais-deploy"}},
+		"annotations": map[string]interface{}{
+			"nais.io/deploymentCorrelationID": "old",
+		},
+	}, map[string]interface{}{
+		"unchanged": "value",
+		"changed":   "before",
+		"removed":   "gone",
+	})
+
+	desired := configMap(map[string]interface{}{
+		"name":      "foo",
+		"namespace": "aura",
+		"annotations": map[string]interface{}{
+			"nais.io/deploymentCorrelationID": "new",
+			"example.com/owner":               "aura",
+		},
+	}, map[string]interface{}{
+		"unchanged": "value",
+		"changed":   "after",
+		"added":     "new",
+	})
+
+	diffs := diff.Resources(live, desired)
+
+	assert.Equal(t, []*pb.FieldDiff{
+		{Path: "data.added", Live: "", Desired: `"new"`},
+		{Path: "data.changed", Live: `"before"`, Desired: `"after"`},
+		{Path: "data.removed", Live: `"gone"`, Desired: ""},
+		{Path: `metadata.annotations["example.com/owner"]`, Live: "", Desired: `"aura"`},
+	}, diffs)
+}
+
+func TestResourcesNoChanges(t *testing.T) {
+	live := configMap(map[string]interface{}{
+		"name":            "foo",
+		"resourceVersion": "1",
+	}, map[string]interface{}{
+		"list": []interface{}{"a", "b"},
+	})
+	desired := configMap(map[string]interface{}{
+		"name":            "foo",
+		"resourceVersion": "2",
+	}, map[string]interface{}{
+		"list": []interface{}{"a", "b"},
+	})
+
+	assert.Empty(t, diff.Resources(live, desired))
+}
+
+func TestResourcesListsComparedAsWhole(t *testing.T) {
+	live := configMap(map[string]interface{}{"name": "foo"}, map[string]interface{}{
+		"list": []interface{}{"a", "b"},
+	})
+	desired := configMap(map[string]interface{}{"name": "foo"}, map[string]interface{}{
+		"list": []interface{}{"a", "c"},
+	})
+
+	assert.Equal(t, []*pb.FieldDiff{
+		{Path: "data.list", Live: `["a","b"]`, Desired: `["a","c"]`},
+	}, diff.Resources(live, desired))
+}
+
+func TestSecretValuesAreRedacted(t *testing.T) {
+	secret := func(data, stringData map[string]interface{}) *unstructured.Unstructured {
+		resource := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "foo", "namespace": "aura"},
+			"data":       data,
+		}}
+		if stringData != nil {
+			resource.Object["stringData"] = stringData
+		}
+		return resource
+	}
+
+	live := secret(map[string]interface{}{
+		"password": "aHVudGVyMg==",
+		"username": "YWRtaW4=",
+		"token":    "c2VjcmV0",
+	}, nil)
+	desired := secret(map[string]interface{}{
+		"password": "Y29ycmVjdGhvcnNl",
+		"username": "YWRtaW4=",
+		"key.pem":  "LS0tLS1CRUdJTg==",
+	}, map[string]interface{}{
+		"api-key": "plaintext",
+	})
+
+	diffs := diff.Resources(live, desired)
+
+	assert.Equal(t, []*pb.FieldDiff{
+		{Path: `data["key.pem"]`, Live: "", Desired: diff.Redacted},
+		{Path: "data.password", Live: diff.Redacted, Desired: diff.Redacted},
+		{Path: "data.token", Live: diff.Redacted, Desired: ""},
+		{Path: "stringData.api-key", Live: "", Desired: diff.Redacted},
+	}, diffs)
+}
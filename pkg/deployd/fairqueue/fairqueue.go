@@ -0,0 +1,228 @@
+// Package fairqueue queues deployment requests, taking turns between teams,
+// so that a burst of requests from one team cannot starve other teams.
+package fairqueue
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/nais/deploy/pkg/deployd/metrics"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+)
+
+type Queue struct {
+	lock       sync.Mutex
+	reportLock sync.Mutex
+	cond       *sync.Cond
+	teams      []string
+	requests   map[string][]*pb.DeploymentRequest
+	positions  map[string]int
+	reported   map[string]int
+	active     map[string]*pb.DeploymentRequest
+	statusChan chan<- *pb.DeploymentStatus
+	closed     bool
+}
+
+// Queued requests report their position again once it has changed by this much since it was last reported.
+const positionReportInterval = 5
+
+// New returns an empty queue. Queued requests are reported on the status channel along with their position in the queue,
+// when they are queued, when they have moved positionReportInterval positions, and when they are first in line.
+func New(statusChan chan<- *pb.DeploymentStatus) *Queue {
+	q := &Queue{
+		requests:   make(map[string][]*pb.DeploymentRequest),
+		positions:  make(map[string]int),
+		reported:   make(map[string]int),
+		active:     make(map[string]*pb.DeploymentRequest),
+		statusChan: statusChan,
+	}
+	q.cond = sync.NewCond(&q.lock)
+	return q
+}
+
+// Push adds a request to the end of its team's queue.
+// Requests that are already queued or running are ignored, so that a request may safely be delivered more than once.
+func (q *Queue) Push(req *pb.DeploymentRequest) {
+	q.lock.Lock()
+
+	if _, queued := q.positions[req.GetID()]; queued || q.active[req.GetID()] != nil {
+		q.lock.Unlock()
+		log.WithFields(req.LogFields()).Debugf("Ignoring deployment request that is already queued or running")
+		return
+	}
+
+	team := req.GetTeam()
+	if len(q.requests[team]) == 0 {
+		q.teams = append(q.teams, team)
+	}
+	q.requests[team] = append(q.requests[team], req)
+
+	statuses := q.positionUpdates()
+	q.cond.Signal()
+	q.report(statuses)
+}
+
+// Pop blocks until a request is available, and returns the first request of the team whose turn it is.
+// Callers must call Done once the request has been processed.
+// Returns false when the queue has been closed.
+func (q *Queue) Pop() (*pb.DeploymentRequest, bool) {
+	q.lock.Lock()
+
+	for len(q.teams) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+
+	if q.closed {
+		q.lock.Unlock()
+		return nil, false
+	}
+
+	team := q.teams[0]
+	req := q.requests[team][0]
+	q.requests[team] = q.requests[team][1:]
+	q.teams = q.teams[1:]
+
+	// The team goes to the back of the line if it has more requests waiting.
+	if len(q.requests[team]) > 0 {
+		q.teams = append(q.teams, team)
+	} else {
+		delete(q.requests, team)
+	}
+	delete(q.positions, req.GetID())
+	delete(q.reported, req.GetID())
+	q.active[req.GetID()] = req
+
+	statuses := q.positionUpdates()
+	q.report(statuses)
+
+	return req, true
+}
+
+// Done marks a request returned by Pop as finished.
+func (q *Queue) Done(req *pb.DeploymentRequest) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	delete(q.active, req.GetID())
+}
+
+// Remove takes a request out of the queue before it has been popped.
+// Returns false if no request with the ID is queued.
+func (q *Queue) Remove(id string) (*pb.DeploymentRequest, bool) {
+	q.lock.Lock()
+
+	if _, queued := q.positions[id]; !queued {
+		q.lock.Unlock()
+		return nil, false
+	}
+
+	var removed *pb.DeploymentRequest
+	for i, team := range q.teams {
+		requests := q.requests[team]
+		for j, req := range requests {
+			if req.GetID() != id {
+				continue
+			}
+			removed = req
+			q.requests[team] = append(requests[:j:j], requests[j+1:]...)
+			if len(q.requests[team]) == 0 {
+				delete(q.requests, team)
+				q.teams = append(q.teams[:i:i], q.teams[i+1:]...)
+			}
+			break
+		}
+		if removed != nil {
+			break
+		}
+	}
+	delete(q.positions, id)
+	delete(q.reported, id)
+
+	statuses := q.positionUpdates()
+	q.report(statuses)
+
+	return removed, removed != nil
+}
+
+// Active returns the requests that have been popped, but are not done yet.
+func (q *Queue) Active() []*pb.DeploymentRequest {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	active := make([]*pb.DeploymentRequest, 0, len(q.active))
+	for _, req := range q.active {
+		active = append(active, req)
+	}
+	return active
+}
+
+// Close wakes up all callers waiting in Pop. Requests still in the queue are discarded.
+func (q *Queue) Close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// Len returns the number of queued requests.
+func (q *Queue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.positions)
+}
+
+// order returns all queued requests in the order they will be dequeued;
+// one request from each team in turn.
+func (q *Queue) order() []*pb.DeploymentRequest {
+	order := make([]*pb.DeploymentRequest, 0, len(q.positions)+1)
+	for round := 0; ; round++ {
+		found := false
+		for _, team := range q.teams {
+			if round < len(q.requests[team]) {
+				order = append(order, q.requests[team][round])
+				found = true
+			}
+		}
+		if !found {
+			return order
+		}
+	}
+}
+
+// positionUpdates records the position of every queued request, and returns queued statuses for the requests
+// whose position should be reported. Reporting every change would send a status to every queued request
+// whenever a request is popped. Must be called with the lock held.
+func (q *Queue) positionUpdates() []*pb.DeploymentStatus {
+	order := q.order()
+	statuses := make([]*pb.DeploymentStatus, 0)
+	for i, req := range order {
+		position := i + 1
+		q.positions[req.GetID()] = position
+
+		reported, ok := q.reported[req.GetID()]
+		switch {
+		case !ok:
+		case position == 1 && reported != 1:
+		case position <= reported-positionReportInterval || position >= reported+positionReportInterval:
+		default:
+			continue
+		}
+		q.reported[req.GetID()] = position
+		st := pb.NewQueuedStatus(req)
+		st.Message = fmt.Sprintf("Waiting for other deployments to finish; position %d in queue", position)
+		statuses = append(statuses, st)
+	}
+	metrics.QueueSize.Set(float64(len(order)))
+	return statuses
+}
+
+// report releases the lock, and sends the statuses without holding it.
+// Statuses are sent in the same order as they were generated, so that a request never reports a stale position.
+func (q *Queue) report(statuses []*pb.DeploymentStatus) {
+	q.reportLock.Lock()
+	defer q.reportLock.Unlock()
+	q.lock.Unlock()
+
+	for _, st := range statuses {
+		q.statusChan <- st
+	}
+}
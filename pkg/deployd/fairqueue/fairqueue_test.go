@@ -0,0 +1,170 @@
+package fairqueue_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/deployd/fairqueue"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func request(id, team string) *pb.DeploymentRequest {
+	return &pb.DeploymentRequest{ID: id, Team: team}
+}
+
+// Drain all statuses currently on the channel, keyed by request ID; only the latest status for each request is kept.
+func drain(t *testing.T, statusChan chan *pb.DeploymentStatus) map[string]string {
+	messages := make(map[string]string)
+	for {
+		select {
+		case st := <-statusChan:
+			assert.Equal(t, pb.DeploymentState_queued, st.GetState())
+			messages[st.GetRequest().GetID()] = st.GetMessage()
+		default:
+			return messages
+		}
+	}
+}
+
+func TestQueueFairness(t *testing.T) {
+	statusChan := make(chan *pb.DeploymentStatus, 100)
+	queue := fairqueue.New(statusChan)
+
+	queue.Push(request("a1", "aura"))
+	queue.Push(request("a2", "aura"))
+	queue.Push(request("a3", "aura"))
+	queue.Push(request("n1", "nais"))
+	queue.Push(request("t1", "tbd"))
+
+	// Positions are reported when requests are queued; a2 and a3 have since been passed by other teams.
+	messages := drain(t, statusChan)
+	assert.Equal(t, "Waiting for other deployments to finish; position 1 in queue", messages["a1"])
+	assert.Equal(t, "Waiting for other deployments to finish; position 2 in queue", messages["a2"])
+	assert.Equal(t, "Waiting for other deployments to finish; position 3 in queue", messages["a3"])
+	assert.Equal(t, "Waiting for other deployments to finish; position 2 in queue", messages["n1"])
+	assert.Equal(t, "Waiting for other deployments to finish; position 3 in queue", messages["t1"])
+
+	order := make([]string, 0)
+	for queue.Len() > 0 {
+		req, ok := queue.Pop()
+		assert.True(t, ok)
+		order = append(order, req.GetID())
+	}
+	assert.Equal(t, []string{"a1", "n1", "t1", "a2", "a3"}, order)
+}
+
+func TestQueuePositionUpdates(t *testing.T) {
+	statusChan := make(chan *pb.DeploymentStatus, 100)
+	queue := fairqueue.New(statusChan)
+
+	queue.Push(request("a1", "aura"))
+	queue.Push(request("a2", "aura"))
+	drain(t, statusChan)
+
+	// Another team's request takes its turn before the second request of the first team.
+	// Only the new request reports its position, as the other one has not moved far.
+	queue.Push(request("n1", "nais"))
+	messages := drain(t, statusChan)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "Waiting for other deployments to finish; position 2 in queue", messages["n1"])
+
+	// Requests report when they are first in line.
+	req, _ := queue.Pop()
+	assert.Equal(t, "a1", req.GetID())
+	messages = drain(t, statusChan)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "Waiting for other deployments to finish; position 1 in queue", messages["n1"])
+}
+
+func TestQueuePositionReportInterval(t *testing.T) {
+	statusChan := make(chan *pb.DeploymentStatus, 100)
+	queue := fairqueue.New(statusChan)
+
+	for i := 0; i < 12; i++ {
+		queue.Push(request(fmt.Sprintf("a%d", i), "aura"))
+	}
+	assert.Len(t, drain(t, statusChan), 12)
+
+	// Only the request that is first in line reports its new position.
+	for i := 0; i < 4; i++ {
+		queue.Pop()
+		messages := drain(t, statusChan)
+		assert.Len(t, messages, 1)
+		assert.Equal(t, "Waiting for other deployments to finish; position 1 in queue", messages[fmt.Sprintf("a%d", i+1)])
+	}
+
+	// After five requests have been popped, every request has moved five positions, e.g. from position 12 to 7.
+	queue.Pop()
+	messages := drain(t, statusChan)
+	assert.Len(t, messages, 7)
+	assert.Equal(t, "Waiting for other deployments to finish; position 1 in queue", messages["a5"])
+	assert.Equal(t, "Waiting for other deployments to finish; position 7 in queue", messages["a11"])
+}
+
+func TestQueueClose(t *testing.T) {
+	queue := fairqueue.New(make(chan *pb.DeploymentStatus, 100))
+	done := make(chan bool)
+
+	go func() {
+		_, ok := queue.Pop()
+		done <- ok
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	queue.Close()
+
+	select {
+	case ok := <-done:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("Pop did not return after the queue was closed")
+	}
+}
+
+func TestQueueIgnoresDuplicates(t *testing.T) {
+	statusChan := make(chan *pb.DeploymentStatus, 100)
+	queue := fairqueue.New(statusChan)
+
+	queue.Push(request("a1", "aura"))
+	queue.Push(request("a1", "aura"))
+	assert.Equal(t, 1, queue.Len())
+
+	req, _ := queue.Pop()
+	queue.Push(request("a1", "aura"))
+	assert.Equal(t, 0, queue.Len(), "request is still running")
+	assert.Len(t, queue.Active(), 1)
+
+	queue.Done(req)
+	assert.Empty(t, queue.Active())
+	queue.Push(request("a1", "aura"))
+	assert.Equal(t, 1, queue.Len())
+}
+
+func TestQueueRemove(t *testing.T) {
+	statusChan := make(chan *pb.DeploymentStatus, 100)
+	queue := fairqueue.New(statusChan)
+
+	queue.Push(request("a1", "aura"))
+	queue.Push(request("a2", "aura"))
+	queue.Push(request("n1", "nais"))
+	drain(t, statusChan)
+
+	req, ok := queue.Remove("a1")
+	assert.True(t, ok)
+	assert.Equal(t, "a1", req.GetID())
+
+	messages := drain(t, statusChan)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "Waiting for other deployments to finish; position 1 in queue", messages["a2"])
+
+	_, ok = queue.Remove("n1")
+	assert.True(t, ok)
+	_, ok = queue.Remove("unknown")
+	assert.False(t, ok)
+	assert.Equal(t, 1, queue.Len())
+
+	req, _ = queue.Pop()
+	assert.Equal(t, "a2", req.GetID())
+}
@@ -1,15 +1,12 @@
 package kubeclient
 
 import (
-	"fmt"
-
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth" // Needed for auth side effect
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/restmapper"
 
 	"github.com/nais/deploy/pkg/deployd/teamconfig"
 )
@@ -30,6 +27,7 @@ type client struct {
 	static  kubernetes.Interface
 	dynamic dynamic.Interface
 	config  *rest.Config
+	mapper  *restMapper
 }
 
 var _ Interface = &client{}
@@ -43,7 +41,7 @@ func (c *client) Impersonate(team string) (Interface, error) {
 	if err != nil {
 		return nil, err
 	}
-	return New(config)
+	return newClient(config, c.mapper)
 }
 
 // Given a unstructured Kubernetes resource, return a dynamic client that knows how to apply it to the cluster.
@@ -64,6 +62,11 @@ func (c *client) ResourceInterface(resource *unstructured.Unstructured) (dynamic
 }
 
 func New(config *rest.Config) (Interface, error) {
+	return newClient(config, nil)
+}
+
+// Create a new client, sharing the REST mapper if one is given.
+func newClient(config *rest.Config, mapper *restMapper) (*client, error) {
 	cli, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, err
@@ -74,27 +77,19 @@ func New(config *rest.Config) (Interface, error) {
 		return nil, err
 	}
 
+	if mapper == nil {
+		mapper = newRESTMapper(cli.Discovery())
+	}
+
 	return &client{
 		static:  cli,
 		dynamic: dyn,
 		config:  config,
+		mapper:  mapper,
 	}, nil
 }
 
 // Given a unstructured Kubernetes resource, return a GroupVersionResource that identifies it in the cluster.
 func (c *client) gvr(resource *unstructured.Unstructured) (*schema.GroupVersionResource, error) {
-	groupResources, err := restmapper.GetAPIGroupResources(c.static.Discovery())
-	if err != nil {
-		return nil, fmt.Errorf("unable to run kubernetes resource discovery: %s", err)
-	}
-	restMapper := restmapper.NewDiscoveryRESTMapper(groupResources)
-
-	gvk := resource.GroupVersionKind()
-	gk := schema.GroupKind{Group: gvk.Group, Kind: gvk.Kind}
-	mapping, err := restMapper.RESTMapping(gk, gvk.Version)
-	if err != nil {
-		return nil, fmt.Errorf("unable to discover resource using REST mapper: %s", err)
-	}
-
-	return &mapping.Resource, nil
+	return c.mapper.resource(resource.GroupVersionKind())
 }
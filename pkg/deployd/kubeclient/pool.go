@@ -0,0 +1,98 @@
+package kubeclient
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// A client that keeps impersonated clients around for reuse, so that their connections are reused between deployments.
+// The pool holds at most `size` clients. Clients are discarded after `ttl`, and the least recently used
+// client is evicted when the pool is full.
+type pool struct {
+	parent  Interface
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	lock    sync.Mutex
+	entries map[string]*poolEntry
+}
+
+type poolEntry struct {
+	client   Interface
+	created  time.Time
+	lastUsed time.Time
+}
+
+var _ Interface = &pool{}
+
+// NewPool returns a client that pools impersonated clients created by the parent client.
+func NewPool(parent Interface, size int, ttl time.Duration) Interface {
+	return &pool{
+		parent:  parent,
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*poolEntry),
+	}
+}
+
+func (p *pool) Kubernetes() kubernetes.Interface {
+	return p.parent.Kubernetes()
+}
+
+func (p *pool) ResourceInterface(resource *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	return p.parent.ResourceInterface(resource)
+}
+
+// Return a pooled client for the team, creating one if necessary.
+func (p *pool) Impersonate(team string) (Interface, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+
+	entry, ok := p.entries[team]
+	if ok && now.Sub(entry.created) < p.ttl {
+		entry.lastUsed = now
+		return entry.client, nil
+	}
+
+	client, err := p.parent.Impersonate(team)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(p.entries, team)
+	p.evict(now)
+	p.entries[team] = &poolEntry{
+		client:   client,
+		created:  now,
+		lastUsed: now,
+	}
+
+	return client, nil
+}
+
+// Remove expired clients, and make room for one more client. Must be called with the lock held.
+func (p *pool) evict(now time.Time) {
+	for team, entry := range p.entries {
+		if now.Sub(entry.created) >= p.ttl {
+			delete(p.entries, team)
+		}
+	}
+
+	for len(p.entries) > 0 && len(p.entries) >= p.size {
+		var oldestTeam string
+		var oldest time.Time
+		for team, entry := range p.entries {
+			if len(oldestTeam) == 0 || entry.lastUsed.Before(oldest) {
+				oldestTeam, oldest = team, entry.lastUsed
+			}
+		}
+		delete(p.entries, oldestTeam)
+	}
+}
@@ -0,0 +1,90 @@
+package kubeclient
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+type fakeClient struct {
+	team    string
+	created *int
+	lock    *sync.Mutex
+}
+
+func (f *fakeClient) Kubernetes() kubernetes.Interface {
+	return nil
+}
+
+func (f *fakeClient) ResourceInterface(resource *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	return nil, nil
+}
+
+func (f *fakeClient) Impersonate(team string) (Interface, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	*f.created++
+	return &fakeClient{team: team, created: f.created, lock: f.lock}, nil
+}
+
+func TestPool(t *testing.T) {
+	created := 0
+	parent := &fakeClient{created: &created, lock: &sync.Mutex{}}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	p := NewPool(parent, 2, time.Minute).(*pool)
+	p.now = func() time.Time { return now }
+
+	impersonate := func(team string) Interface {
+		client, err := p.Impersonate(team)
+		assert.NoError(t, err)
+		assert.Equal(t, team, client.(*fakeClient).team)
+		return client
+	}
+
+	aura := impersonate("aura")
+	assert.Same(t, aura, impersonate("aura"), "client is reused")
+	assert.Equal(t, 1, created)
+
+	now = now.Add(10 * time.Second)
+	impersonate("nais")
+	now = now.Add(10 * time.Second)
+	impersonate("aura")
+
+	// Pool is full; the least recently used client is evicted.
+	impersonate("tbd")
+	assert.Equal(t, 3, created)
+	assert.Len(t, p.entries, 2)
+	assert.Contains(t, p.entries, "aura")
+	assert.Contains(t, p.entries, "tbd")
+
+	// Expired clients are replaced.
+	now = now.Add(time.Minute)
+	assert.NotSame(t, aura, impersonate("aura"))
+	assert.Equal(t, 4, created)
+	assert.Len(t, p.entries, 1)
+}
+
+func TestPoolConcurrency(t *testing.T) {
+	created := 0
+	parent := &fakeClient{created: &created, lock: &sync.Mutex{}}
+	p := NewPool(parent, 10, time.Minute)
+
+	wait := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			_, err := p.Impersonate("aura")
+			assert.NoError(t, err)
+		}()
+	}
+	wait.Wait()
+
+	assert.Equal(t, 1, created)
+}
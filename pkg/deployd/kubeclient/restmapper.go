@@ -0,0 +1,78 @@
+package kubeclient
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nais/deploy/pkg/deployd/metrics"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
+)
+
+// Maps resource kinds to API resources, using API discovery results cached in memory.
+// The cache is shared between all clients, including impersonated ones, as discovery results do not depend on the user.
+type restMapper struct {
+	mapper *restmapper.DeferredDiscoveryRESTMapper
+	lock   sync.Mutex
+	// When the cache was last invalidated because of each kind that was not found.
+	lastReset map[schema.GroupKind]time.Time
+}
+
+// Minimum time between API discovery runs for the same kind. Deployments of that kind fail without running discovery
+// in the meantime, so that many of them in a row, e.g. of a misspelled kind, do not overload the API server.
+// Other kinds are not held back, so that a CRD installed right after a miss is found on its first deployment.
+var restMapperResetInterval = 30 * time.Second
+
+func newRESTMapper(client discovery.DiscoveryInterface) *restMapper {
+	return &restMapper{
+		mapper:    restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(client)),
+		lastReset: make(map[schema.GroupKind]time.Time),
+	}
+}
+
+// Return the GroupVersionResource of the given kind.
+// API discovery is only re-run when the kind is not found in the cache, e.g. after a new CRD has been installed,
+// and at most once per restMapperResetInterval for each kind.
+func (m *restMapper) resource(gvk schema.GroupVersionKind) (*schema.GroupVersionResource, error) {
+	gk := gvk.GroupKind()
+
+	mapping, err := m.mapper.RESTMapping(gk, gvk.Version)
+	if meta.IsNoMatchError(err) && m.reset(gk) {
+		mapping, err = m.mapper.RESTMapping(gk, gvk.Version)
+	} else if err == nil {
+		metrics.RESTMapperCacheHits.Inc()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to discover resource using REST mapper: %w", err)
+	}
+
+	return &mapping.Resource, nil
+}
+
+// reset invalidates the discovery cache, unless that was done for the same kind less than restMapperResetInterval ago.
+// Returns true if the cache was invalidated.
+func (m *restMapper) reset(gk schema.GroupKind) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if time.Since(m.lastReset[gk]) < restMapperResetInterval {
+		return false
+	}
+
+	// Forget kinds that are no longer held back, so that misspelled kinds do not pile up.
+	for kind, last := range m.lastReset {
+		if time.Since(last) >= restMapperResetInterval {
+			delete(m.lastReset, kind)
+		}
+	}
+
+	metrics.RESTMapperRefreshes.Inc()
+	m.mapper.Reset()
+	m.lastReset[gk] = time.Now()
+	return true
+}
@@ -0,0 +1,94 @@
+package kubeclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/deployd/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRESTMapper(t *testing.T) {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true},
+			},
+		},
+	}
+	mapper := newRESTMapper(discovery)
+
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	application := schema.GroupVersionKind{Group: "nais.io", Version: "v1alpha1", Kind: "Application"}
+
+	hits := testutil.ToFloat64(metrics.RESTMapperCacheHits)
+	refreshes := testutil.ToFloat64(metrics.RESTMapperRefreshes)
+
+	for i := 0; i < 3; i++ {
+		gvr, err := mapper.resource(deployment)
+		assert.NoError(t, err)
+		assert.Equal(t, "deployments", gvr.Resource)
+	}
+	assert.Equal(t, hits+3, testutil.ToFloat64(metrics.RESTMapperCacheHits))
+	assert.Equal(t, refreshes, testutil.ToFloat64(metrics.RESTMapperRefreshes))
+
+	discoveryCalls := len(discovery.Actions())
+
+	// Unknown kinds trigger a refresh, which picks up newly installed CRDs.
+	_, err := mapper.resource(application)
+	assert.Error(t, err)
+	assert.Equal(t, refreshes+1, testutil.ToFloat64(metrics.RESTMapperRefreshes))
+	assert.Greater(t, len(discovery.Actions()), discoveryCalls)
+
+	discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{
+		GroupVersion: "nais.io/v1alpha1",
+		APIResources: []metav1.APIResource{
+			{Name: "applications", Kind: "Application", Namespaced: true},
+		},
+	})
+
+	// Discovery is not run again right away, even if the kind has been installed in the meantime.
+	discoveryCalls = len(discovery.Actions())
+	_, err = mapper.resource(application)
+	assert.True(t, meta.IsNoMatchError(err))
+	assert.Equal(t, refreshes+1, testutil.ToFloat64(metrics.RESTMapperRefreshes))
+	assert.Equal(t, discoveryCalls, len(discovery.Actions()))
+
+	// Other kinds are not held back by the miss above.
+	naisjob := schema.GroupVersionKind{Group: "nais.io", Version: "v1", Kind: "Naisjob"}
+	discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{
+		GroupVersion: "nais.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "naisjobs", Kind: "Naisjob", Namespaced: true},
+		},
+	})
+	gvr, err := mapper.resource(naisjob)
+	assert.NoError(t, err)
+	assert.Equal(t, "naisjobs", gvr.Resource)
+	assert.Equal(t, refreshes+2, testutil.ToFloat64(metrics.RESTMapperRefreshes))
+
+	// The refresh picked up the kind that was held back as well.
+	gvr, err = mapper.resource(application)
+	assert.NoError(t, err)
+	assert.Equal(t, "applications", gvr.Resource)
+	assert.Equal(t, refreshes+2, testutil.ToFloat64(metrics.RESTMapperRefreshes))
+
+	// Held back kinds are looked up again once the interval has passed.
+	chaosmonkey := schema.GroupVersionKind{Group: "nais.io", Version: "v1", Kind: "Chaosmonkey"}
+	_, err = mapper.resource(chaosmonkey)
+	assert.True(t, meta.IsNoMatchError(err))
+	assert.Equal(t, refreshes+3, testutil.ToFloat64(metrics.RESTMapperRefreshes))
+
+	mapper.lastReset[chaosmonkey.GroupKind()] = time.Now().Add(-restMapperResetInterval)
+	_, err = mapper.resource(chaosmonkey)
+	assert.True(t, meta.IsNoMatchError(err))
+	assert.Equal(t, refreshes+4, testutil.ToFloat64(metrics.RESTMapperRefreshes))
+}
@@ -21,10 +21,25 @@ func counter(name, help string) prometheus.Counter {
 	})
 }
 
+func gauge(name, help string) prometheus.Gauge {
+	return prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:      name,
+		Help:      help,
+		Namespace: namespace,
+		Subsystem: subsystem,
+	})
+}
+
 var (
 	DeploySuccessful    = counter("deploy_successful", "number of successful deployments")
 	DeployFailed        = counter("deploy_failed", "number of failed deployments")
 	DeployIgnored       = counter("deploy_ignored", "number of ignored/discarded deployments")
+	RESTMapperCacheHits = counter("restmapper_cache_hits", "number of resource kinds mapped using cached API discovery")
+	RESTMapperRefreshes = counter("restmapper_refreshes", "number of API discovery refreshes caused by unknown resource kinds")
+	QueueSize           = gauge("queue_size", "number of deployment requests waiting for a free worker")
+	OutboxSize          = gauge("outbox_size", "number of deployment statuses waiting to be reported to hookd")
+	OutboxOldestAge     = gauge("outbox_oldest_age_seconds", "age of the oldest deployment status waiting to be reported to hookd")
+	OutboxDiscarded     = counter("outbox_discarded", "number of deployment statuses discarded because the outbox was full")
 	kubernetesResources = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name:      "kubernetes_resources",
 		Help:      "number of Kubernetes resources successfully committed to cluster",
@@ -45,6 +60,12 @@ func init() {
 	prometheus.MustRegister(DeploySuccessful)
 	prometheus.MustRegister(DeployFailed)
 	prometheus.MustRegister(DeployIgnored)
+	prometheus.MustRegister(RESTMapperCacheHits)
+	prometheus.MustRegister(RESTMapperRefreshes)
+	prometheus.MustRegister(QueueSize)
+	prometheus.MustRegister(OutboxSize)
+	prometheus.MustRegister(OutboxOldestAge)
+	prometheus.MustRegister(OutboxDiscarded)
 	prometheus.MustRegister(kubernetesResources)
 }
 
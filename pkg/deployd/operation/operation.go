@@ -2,6 +2,7 @@ package operation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/nais/deploy/pkg/k8sutils"
@@ -11,6 +12,9 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
+// ErrCancelled is the cause of an operation's context being cancelled on request of the team.
+var ErrCancelled = errors.New("deployment was cancelled")
+
 type Operation struct {
 	Context    context.Context
 	Cancel     context.CancelFunc
@@ -32,3 +36,9 @@ func (op *Operation) ExtractResources() ([]unstructured.Unstructured, error) {
 
 	return resources, nil
 }
+
+// Cancelled returns true if the operation has been cancelled on request of the team,
+// as opposed to running out of time or failing.
+func (op *Operation) Cancelled() bool {
+	return errors.Is(context.Cause(op.Context), ErrCancelled)
+}
@@ -0,0 +1,44 @@
+package operation
+
+import (
+	"context"
+	"sync"
+)
+
+// Registry keeps track of running deployments, so that they can be cancelled.
+type Registry struct {
+	lock    sync.Mutex
+	cancels map[string]context.CancelCauseFunc
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		cancels: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// Add registers a running deployment along with the function that cancels its context.
+func (r *Registry) Add(id string, cancel context.CancelCauseFunc) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cancels[id] = cancel
+}
+
+// Remove forgets about a deployment once it has finished.
+func (r *Registry) Remove(id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.cancels, id)
+}
+
+// Cancel cancels the context of a running deployment with ErrCancelled as the cause.
+// Returns false if no such deployment is running.
+func (r *Registry) Cancel(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	cancel, ok := r.cancels[id]
+	if ok {
+		cancel(ErrCancelled)
+	}
+	return ok
+}
@@ -0,0 +1,32 @@
+package operation_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nais/deploy/pkg/deployd/operation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := operation.NewRegistry()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	op := &operation.Operation{Context: ctx}
+
+	registry.Add("1", cancel)
+	assert.False(t, registry.Cancel("2"))
+	assert.False(t, op.Cancelled())
+
+	assert.True(t, registry.Cancel("1"))
+	assert.True(t, op.Cancelled())
+
+	registry.Remove("1")
+	assert.False(t, registry.Cancel("1"))
+}
+
+func TestCancelledOnlyOnRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	op := &operation.Operation{Context: ctx}
+	assert.False(t, op.Cancelled(), "operations stopped for other reasons are not cancelled")
+}
@@ -0,0 +1,276 @@
+// Package outbox implements a bounded, disk-backed queue of deployment statuses waiting to be reported to hookd.
+//
+// Every status is written to its own file before it is reported, and removed once hookd has accepted it,
+// so that statuses survive a restart of deployd. Each status is given an ID that is persisted along with it,
+// so that hookd can recognize a status that is reported more than once. Statuses are reported in the order they were added,
+// and a status is never reported before earlier statuses of the same deployment.
+package outbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nais/deploy/pkg/deployd/metrics"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+const fileSuffix = ".status"
+
+type Outbox struct {
+	dir        string
+	maxEntries int
+	lock       sync.Mutex
+	entries    []*entry
+	sequence   uint64
+}
+
+type entry struct {
+	sequence uint64
+	status   *pb.DeploymentStatus
+	added    time.Time
+}
+
+// Open returns an outbox persisting statuses in dir, and loads any statuses left over from a previous run.
+// If dir is empty, statuses are kept in memory only.
+// When more than maxEntries statuses are queued, the oldest intermediate statuses are discarded first.
+func Open(dir string, maxEntries int) (*Outbox, error) {
+	o := &Outbox{
+		dir:        dir,
+		maxEntries: maxEntries,
+		entries:    make([]*entry, 0),
+	}
+
+	if len(dir) == 0 {
+		return o, nil
+	}
+
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("create outbox directory: %w", err)
+	}
+
+	err = o.load()
+	if err != nil {
+		return nil, err
+	}
+
+	o.updateMetrics()
+
+	return o, nil
+}
+
+// Add assigns an ID to the status unless it already has one, persists it and queues it for reporting.
+// The status is queued even if it cannot be persisted, as it may still be reported before deployd restarts.
+func (o *Outbox) Add(status *pb.DeploymentStatus) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if len(status.GetID()) == 0 {
+		status.ID = uuid.New().String()
+	}
+
+	o.sequence++
+	e := &entry{
+		sequence: o.sequence,
+		status:   status,
+		added:    time.Now(),
+	}
+
+	o.entries = append(o.entries, e)
+	for len(o.entries) > o.maxEntries {
+		o.discard()
+	}
+
+	o.updateMetrics()
+
+	return o.write(e)
+}
+
+// Flush reports queued statuses in order, removing every status that is reported without error.
+// If a status cannot be reported, later statuses of the same deployment are held back until the next flush,
+// while statuses of other deployments are still reported.
+func (o *Outbox) Flush(report func(status *pb.DeploymentStatus) error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	blocked := make(map[string]bool)
+	remaining := make([]*entry, 0, len(o.entries))
+
+	for _, e := range o.entries {
+		id := e.status.GetRequest().GetID()
+		if blocked[id] {
+			remaining = append(remaining, e)
+			continue
+		}
+
+		err := report(e.status)
+		if err != nil {
+			log.WithFields(e.status.LogFields()).Errorf("Report deployment status: %s", err)
+			blocked[id] = true
+			remaining = append(remaining, e)
+			continue
+		}
+
+		o.remove(e)
+	}
+
+	o.entries = remaining
+	o.updateMetrics()
+}
+
+// Len returns the number of queued statuses.
+func (o *Outbox) Len() int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return len(o.entries)
+}
+
+// Discard the oldest intermediate status; final statuses are only discarded if there is nothing else to discard.
+// Must be called with the lock held.
+func (o *Outbox) discard() {
+	index := 0
+	for i, e := range o.entries {
+		if !e.status.GetState().Finished() {
+			index = i
+			break
+		}
+	}
+
+	e := o.entries[index]
+	log.WithFields(e.status.LogFields()).Warnf("Outbox is full; discarding deployment status: %s", e.status.GetMessage())
+	metrics.OutboxDiscarded.Inc()
+
+	o.remove(e)
+	o.entries = append(o.entries[:index], o.entries[index+1:]...)
+}
+
+func (o *Outbox) updateMetrics() {
+	metrics.OutboxSize.Set(float64(len(o.entries)))
+	if len(o.entries) == 0 {
+		metrics.OutboxOldestAge.Set(0)
+		return
+	}
+	metrics.OutboxOldestAge.Set(time.Since(o.entries[0].added).Seconds())
+}
+
+func (o *Outbox) path(e *entry) string {
+	// Zero-padded sequence numbers sort in the same order as they were added.
+	return filepath.Join(o.dir, fmt.Sprintf("%020d%s", e.sequence, fileSuffix))
+}
+
+// Write the status to a temporary file first, so that a crash never leaves a partially written status behind.
+func (o *Outbox) write(e *entry) error {
+	if len(o.dir) == 0 {
+		return nil
+	}
+
+	payload, err := proto.Marshal(e.status)
+	if err != nil {
+		return fmt.Errorf("encode deployment status: %w", err)
+	}
+
+	path := o.path(e)
+	tmp := path + ".tmp"
+
+	err = os.WriteFile(tmp, payload, 0o600)
+	if err != nil {
+		return fmt.Errorf("write deployment status to outbox: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("write deployment status to outbox: %w", err)
+	}
+
+	return nil
+}
+
+func (o *Outbox) remove(e *entry) {
+	if len(o.dir) == 0 {
+		return
+	}
+
+	err := os.Remove(o.path(e))
+	if err != nil && !os.IsNotExist(err) {
+		log.Errorf("Remove deployment status from outbox: %s", err)
+	}
+}
+
+// Load statuses persisted by a previous run, in the order they were added.
+func (o *Outbox) load() error {
+	files, err := os.ReadDir(o.dir)
+	if err != nil {
+		return fmt.Errorf("read outbox directory: %w", err)
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			continue
+		}
+
+		// Leftovers from an interrupted write.
+		if strings.HasSuffix(name, ".tmp") {
+			_ = os.Remove(filepath.Join(o.dir, name))
+			continue
+		}
+
+		if !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+
+		sequence, err := strconv.ParseUint(strings.TrimSuffix(name, fileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		payload, err := os.ReadFile(filepath.Join(o.dir, name))
+		if err != nil {
+			return fmt.Errorf("read deployment status from outbox: %w", err)
+		}
+
+		status := &pb.DeploymentStatus{}
+		err = proto.Unmarshal(payload, status)
+		if err != nil {
+			log.Errorf("Discarding unreadable deployment status %s from outbox: %s", name, err)
+			_ = os.Remove(filepath.Join(o.dir, name))
+			continue
+		}
+
+		info, err := file.Info()
+		added := time.Now()
+		if err == nil {
+			added = info.ModTime()
+		}
+
+		o.entries = append(o.entries, &entry{
+			sequence: sequence,
+			status:   status,
+			added:    added,
+		})
+	}
+
+	sort.Slice(o.entries, func(i, j int) bool {
+		return o.entries[i].sequence < o.entries[j].sequence
+	})
+
+	if len(o.entries) > 0 {
+		o.sequence = o.entries[len(o.entries)-1].sequence
+		log.Infof("Loaded %d unreported deployment statuses from outbox", len(o.entries))
+	}
+
+	for len(o.entries) > o.maxEntries {
+		o.discard()
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package outbox_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nais/deploy/pkg/deployd/outbox"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func status(id string, state pb.DeploymentState, message string) *pb.DeploymentStatus {
+	return &pb.DeploymentStatus{
+		Request: &pb.DeploymentRequest{ID: id},
+		State:   state,
+		Message: message,
+	}
+}
+
+// Collects reported statuses, failing for deployment IDs in the unavailable set.
+type reporter struct {
+	reported    []string
+	unavailable map[string]bool
+}
+
+func (r *reporter) report(st *pb.DeploymentStatus) error {
+	if r.unavailable[st.GetRequest().GetID()] {
+		return fmt.Errorf("unavailable")
+	}
+	r.reported = append(r.reported, st.GetMessage())
+	return nil
+}
+
+func TestOutboxReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	box, err := outbox.Open(dir, 100)
+	assert.NoError(t, err)
+	first := status("1", pb.DeploymentState_in_progress, "first")
+	assert.NoError(t, box.Add(first))
+	assert.NotEmpty(t, first.GetID(), "statuses are given an ID when they are added")
+	assert.NoError(t, box.Add(status("1", pb.DeploymentState_success, "second")))
+
+	// A new outbox in the same directory picks up where the previous one left off.
+	box, err = outbox.Open(dir, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, box.Len())
+	assert.NoError(t, box.Add(status("2", pb.DeploymentState_success, "third")))
+
+	// Statuses keep their IDs between attempts, so that hookd stores a status only once.
+	ids := map[string]string{"first": first.GetID()}
+	box.Flush(func(st *pb.DeploymentStatus) error {
+		if id, ok := ids[st.GetMessage()]; ok {
+			assert.Equal(t, id, st.GetID())
+		}
+		ids[st.GetMessage()] = st.GetID()
+		return fmt.Errorf("unavailable")
+	})
+
+	r := &reporter{}
+	box.Flush(func(st *pb.DeploymentStatus) error {
+		if id, ok := ids[st.GetMessage()]; ok {
+			assert.Equal(t, id, st.GetID())
+		}
+		return r.report(st)
+	})
+	assert.Equal(t, []string{"first", "second", "third"}, r.reported)
+	assert.Equal(t, 0, box.Len())
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files, "reported statuses are removed from disk")
+}
+
+func TestOutboxOrderingPerDeployment(t *testing.T) {
+	box, err := outbox.Open(t.TempDir(), 100)
+	assert.NoError(t, err)
+
+	assert.NoError(t, box.Add(status("1", pb.DeploymentState_in_progress, "1a")))
+	assert.NoError(t, box.Add(status("2", pb.DeploymentState_in_progress, "2a")))
+	assert.NoError(t, box.Add(status("1", pb.DeploymentState_success, "1b")))
+	assert.NoError(t, box.Add(status("2", pb.DeploymentState_success, "2b")))
+
+	// Statuses of a deployment that cannot be reported are held back, while others go through.
+	r := &reporter{unavailable: map[string]bool{"1": true}}
+	box.Flush(r.report)
+	assert.Equal(t, []string{"2a", "2b"}, r.reported)
+	assert.Equal(t, 2, box.Len())
+
+	r = &reporter{}
+	box.Flush(r.report)
+	assert.Equal(t, []string{"1a", "1b"}, r.reported)
+	assert.Equal(t, 0, box.Len())
+}
+
+func TestOutboxBounded(t *testing.T) {
+	box, err := outbox.Open("", 2)
+	assert.NoError(t, err)
+
+	assert.NoError(t, box.Add(status("1", pb.DeploymentState_success, "1 finished")))
+	assert.NoError(t, box.Add(status("2", pb.DeploymentState_in_progress, "2 in progress")))
+	assert.NoError(t, box.Add(status("2", pb.DeploymentState_failure, "2 finished")))
+
+	// Intermediate statuses are discarded before final ones.
+	r := &reporter{}
+	box.Flush(r.report)
+	assert.Equal(t, []string{"1 finished", "2 finished"}, r.reported)
+}
+
+func TestOutboxDiscardsPartialWrites(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000001.status.tmp"), []byte("partial"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000002.status"), []byte("garbage"), 0o600))
+
+	box, err := outbox.Open(dir, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, box.Len())
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
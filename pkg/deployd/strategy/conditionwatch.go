@@ -0,0 +1,108 @@
+package strategy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nais/deploy/pkg/deployd/kubeclient"
+	"github.com/nais/deploy/pkg/deployd/operation"
+	"github.com/nais/deploy/pkg/pb"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ConditionKinds maps resource kinds to the status condition that signals a successful rollout.
+type ConditionKinds map[schema.GroupKind]string
+
+// conditionKinds is configured once at startup, before any deployments are processed.
+var conditionKinds = ConditionKinds{}
+
+// SetConditionKinds configures which kinds are watched using the generic condition strategy.
+func SetConditionKinds(kinds ConditionKinds) {
+	conditionKinds = kinds
+}
+
+// ParseConditionKinds parses mappings in the form `group/Kind=Condition`, e.g. `kafka.nais.io/Topic=Ready`.
+// Kinds in the core API group are specified without a group, e.g. `Pod=Ready`.
+func ParseConditionKinds(mappings []string) (ConditionKinds, error) {
+	kinds := make(ConditionKinds, len(mappings))
+	for _, mapping := range mappings {
+		kind, condition, found := strings.Cut(mapping, "=")
+		if !found || len(kind) == 0 || len(condition) == 0 {
+			return nil, fmt.Errorf("invalid condition mapping '%s'; expected group/Kind=Condition", mapping)
+		}
+		gk := schema.GroupKind{Kind: kind}
+		if i := strings.LastIndex(kind, "/"); i >= 0 {
+			gk = schema.GroupKind{Group: kind[:i], Kind: kind[i+1:]}
+		}
+		kinds[gk] = condition
+	}
+	return kinds, nil
+}
+
+// conditionResource waits for a custom resource to report a specific status condition,
+// in the style of kstatus: the controller must have observed the latest generation, and the condition must be true.
+type conditionResource struct {
+	client        kubeclient.Interface
+	conditionType string
+}
+
+func (c conditionResource) Watch(op *operation.Operation, resource unstructured.Unstructured, trace trace.Span) *pb.DeploymentStatus {
+	resourceInterface, err := c.client.ResourceInterface(&resource)
+	if err != nil {
+		return pb.NewErrorStatus(op.Request, fmt.Errorf("unable to set up watch: %w", err))
+	}
+
+	lw := singleObjectListWatch(op.Context, resource.GetName(), resourceInterface.List, resourceInterface.Watch)
+
+	err = untilCondition(op.Context, lw, &unstructured.Unstructured{}, func(obj *unstructured.Unstructured) (bool, error) {
+		done, err := conditionReached(obj, c.conditionType)
+		if !done && err == nil {
+			op.Logger.Debugf("Still waiting for %s condition on %s/%s...", c.conditionType, resource.GetKind(), resource.GetName())
+		}
+		return done, err
+	})
+
+	return rolloutStatus(op, resource, err, trace)
+}
+
+// conditionReached returns true once the resource's status reflects the latest generation and the condition is true.
+// A false condition is reported as an error. Conditions from a previous generation are disregarded.
+// A resource that has been changed since it was created must report which generation it has observed, either for the
+// whole status or for the condition, as its condition may otherwise be left over from a previous generation.
+func conditionReached(obj *unstructured.Unstructured, conditionType string) (bool, error) {
+	generation := obj.GetGeneration()
+
+	observedGeneration, statusObserved, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if statusObserved && observedGeneration < generation {
+		return false, nil
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != conditionType {
+			continue
+		}
+
+		conditionGeneration, conditionObserved, _ := unstructured.NestedInt64(condition, "observedGeneration")
+		if conditionObserved && conditionGeneration < generation {
+			return false, nil
+		}
+		if !statusObserved && !conditionObserved && generation > 1 {
+			return false, nil
+		}
+
+		switch condition["status"] {
+		case "True":
+			return true, nil
+		case "False":
+			reason, _ := condition["reason"].(string)
+			message, _ := condition["message"].(string)
+			return false, fmt.Errorf("%s condition is false: %s: %s", conditionType, reason, message)
+		}
+	}
+
+	return false, nil
+}
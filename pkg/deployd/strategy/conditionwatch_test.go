@@ -0,0 +1,103 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseConditionKinds(t *testing.T) {
+	kinds, err := ParseConditionKinds([]string{"kafka.nais.io/Topic=Ready", "aiven.io/Redis=Running", "Pod=Ready"})
+	assert.NoError(t, err)
+	assert.Equal(t, ConditionKinds{
+		{Group: "kafka.nais.io", Kind: "Topic"}: "Ready",
+		{Group: "aiven.io", Kind: "Redis"}:      "Running",
+		{Group: "", Kind: "Pod"}:                "Ready",
+	}, kinds)
+
+	_, err = ParseConditionKinds([]string{"kafka.nais.io/Topic"})
+	assert.EqualError(t, err, "invalid condition mapping 'kafka.nais.io/Topic'; expected group/Kind=Condition")
+}
+
+func TestNewWatchStrategyConditionKinds(t *testing.T) {
+	SetConditionKinds(ConditionKinds{{Group: "kafka.nais.io", Kind: "Topic"}: "Ready"})
+	defer SetConditionKinds(ConditionKinds{})
+
+	strategy := NewWatchStrategy(schema.GroupVersionKind{Group: "kafka.nais.io", Version: "v1", Kind: "Topic"}, nil)
+	assert.Equal(t, conditionResource{conditionType: "Ready"}, strategy)
+
+	strategy = NewWatchStrategy(schema.GroupVersionKind{Group: "kafka.nais.io", Version: "v1", Kind: "Stream"}, nil)
+	assert.Equal(t, NoOp{}, strategy)
+}
+
+func TestConditionReached(t *testing.T) {
+	resource := func(observedGeneration int64, conditions ...interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"observedGeneration": observedGeneration,
+				"conditions":         conditions,
+			},
+		}}
+		obj.SetGeneration(2)
+		return obj
+	}
+	condition := func(status string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":    "Ready",
+			"status":  status,
+			"reason":  "Oops",
+			"message": "something went wrong",
+		}
+	}
+
+	done, err := conditionReached(resource(2, condition("True")), "Ready")
+	assert.True(t, done)
+	assert.NoError(t, err)
+
+	done, err = conditionReached(resource(2, condition("Unknown")), "Ready")
+	assert.False(t, done)
+	assert.NoError(t, err)
+
+	done, err = conditionReached(resource(1, condition("True")), "Ready")
+	assert.False(t, done, "status from previous generation")
+	assert.NoError(t, err)
+
+	done, err = conditionReached(resource(2, condition("True")), "Synchronized")
+	assert.False(t, done, "condition missing")
+	assert.NoError(t, err)
+
+	done, err = conditionReached(resource(2, condition("False")), "Ready")
+	assert.False(t, done)
+	assert.EqualError(t, err, "Ready condition is false: Oops: something went wrong")
+
+	stale := condition("False")
+	stale["observedGeneration"] = int64(1)
+	done, err = conditionReached(resource(2, stale), "Ready")
+	assert.False(t, done, "condition from previous generation")
+	assert.NoError(t, err)
+
+	unobserved := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{condition("True")},
+		},
+	}}
+	unobserved.SetGeneration(2)
+	done, err = conditionReached(unobserved, "Ready")
+	assert.False(t, done, "generation not observed after a change")
+	assert.NoError(t, err)
+
+	observed := condition("True")
+	observed["observedGeneration"] = int64(2)
+	unobserved.Object["status"] = map[string]interface{}{"conditions": []interface{}{observed}}
+	done, err = conditionReached(unobserved, "Ready")
+	assert.True(t, done, "generation observed by the condition")
+	assert.NoError(t, err)
+
+	unobserved.Object["status"] = map[string]interface{}{"conditions": []interface{}{condition("True")}}
+	unobserved.SetGeneration(1)
+	done, err = conditionReached(unobserved, "Ready")
+	assert.True(t, done, "newly created resource without observed generation")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,88 @@
+package strategy
+
+import (
+	"context"
+
+	"github.com/nais/deploy/pkg/deployd/kubeclient"
+	"github.com/nais/deploy/pkg/deployd/operation"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The daemonset controller labels its pods with the template generation they run, and records the current one in
+// an annotation on the daemonset.
+const daemonSetTemplateGenerationLabel = "pod-template-generation"
+
+type daemonSet struct {
+	client kubeclient.Interface
+}
+
+func (d daemonSet) Watch(op *operation.Operation, resource unstructured.Unstructured, trace trace.Span) *pb.DeploymentStatus {
+	client := d.client.Kubernetes().AppsV1().DaemonSets(resource.GetNamespace())
+	lw := singleObjectListWatch(op.Context, resource.GetName(), client.List, client.Watch)
+
+	var latest *apps.DaemonSet
+
+	err := untilCondition(op.Context, lw, &apps.DaemonSet{}, func(ds *apps.DaemonSet) (bool, error) {
+		latest = ds
+		if daemonSetComplete(ds) {
+			return true, nil
+		}
+
+		op.Logger.WithFields(log.Fields{
+			"daemonset_desired_number_scheduled": ds.Status.DesiredNumberScheduled,
+			"daemonset_updated_number_scheduled": ds.Status.UpdatedNumberScheduled,
+			"daemonset_number_available":         ds.Status.NumberAvailable,
+			"daemonset_observed_generation":      ds.Status.ObservedGeneration,
+		}).Debugf("Still waiting for daemonset to finish rollout...")
+
+		return false, nil
+	})
+
+	status := rolloutStatus(op, resource, err, trace)
+	if err != nil && latest != nil {
+		status.Logs = d.failingContainerLogs(op.Context, latest)
+	}
+
+	return status
+}
+
+// failingContainerLogs captures the logs of failing containers running the current template generation.
+// Pods of all generations are considered if the daemonset does not tell which one is current.
+func (d daemonSet) failingContainerLogs(ctx context.Context, ds *apps.DaemonSet) []*pb.ContainerLog {
+	if ds.Spec.Selector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	templateGeneration, ok := ds.GetAnnotations()[apps.DeprecatedTemplateGeneration]
+	currentGeneration := func(pod *corev1.Pod) bool {
+		return !ok || pod.GetLabels()[daemonSetTemplateGenerationLabel] == templateGeneration
+	}
+	return failingContainerLogs(ctx, d.client.Kubernetes(), ds.GetNamespace(), selector, currentGeneration)
+}
+
+// daemonSetComplete considers a daemonset to be complete once the controller has observed the latest generation,
+// and every node that should run the daemon runs an updated and available pod.
+//
+// Adapted from the `kubectl rollout status` implementation.
+func daemonSetComplete(ds *apps.DaemonSet) bool {
+	if ds.Status.ObservedGeneration < ds.Generation {
+		return false
+	}
+
+	// Pods are only replaced when deleted, so there is no rollout to wait for.
+	if ds.Spec.UpdateStrategy.Type == apps.OnDeleteDaemonSetStrategyType {
+		return true
+	}
+
+	return ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled &&
+		ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled
+}
@@ -2,51 +2,148 @@ package strategy
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/csaupgrade"
 )
 
-func NewDeployStrategy(namespacedResource dynamic.ResourceInterface) DeployStrategy {
-	return createOrUpdateStrategy{client: namespacedResource}
+// FieldManager identifies deployd as the owner of fields it applies to the cluster using server-side apply.
+const FieldManager = "nais-deploy"
+
+// Field managers that owned fields updated by earlier versions of deployd, before server-side apply was used.
+var previousFieldManagers = sets.New("deployd")
+
+// DeployOptions control how resources are applied to the cluster.
+type DeployOptions struct {
+	// Force takes ownership of fields that are managed by other field managers.
+	Force bool
+	// DryRun validates and admits the resource on the server without persisting it.
+	DryRun bool
+}
+
+func NewDeployStrategy(namespacedResource dynamic.ResourceInterface, opts DeployOptions) DeployStrategy {
+	return serverSideApplyStrategy{client: namespacedResource, opts: opts}
 }
 
 type DeployStrategy interface {
 	Deploy(ctx context.Context, resource unstructured.Unstructured, trace trace.Span) (*unstructured.Unstructured, error)
 }
 
-type createOrUpdateStrategy struct {
+// serverSideApplyStrategy applies resources using Kubernetes server-side apply.
+// Only the fields present in the resource are claimed by deployd; fields owned by
+// other field managers, such as replica counts set by a HorizontalPodAutoscaler, are left alone.
+type serverSideApplyStrategy struct {
 	client dynamic.ResourceInterface
+	opts   DeployOptions
 }
 
-func (c createOrUpdateStrategy) Deploy(ctx context.Context, resource unstructured.Unstructured, trace trace.Span) (*unstructured.Unstructured, error) {
-	existing, err := c.client.Get(ctx, resource.GetName(), metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		deployed, err := c.client.Create(ctx, &resource, metav1.CreateOptions{
-			FieldValidation: metav1.FieldValidationStrict,
-		})
+func (c serverSideApplyStrategy) Deploy(ctx context.Context, resource unstructured.Unstructured, trace trace.Span) (*unstructured.Unstructured, error) {
+	// Resource version acts as a precondition when applying; we always want to apply on top of the latest version.
+	resource.SetResourceVersion("")
+	resource.SetManagedFields(nil)
+
+	data, err := resource.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("encoding resource: %w", err)
+	}
+
+	// A dry run must not change anything, so the managed fields are upgraded by the real deployment only.
+	if !c.opts.DryRun {
+		err = upgradeManagedFields(ctx, c.client, resource.GetName())
 		if err != nil {
-			return nil, fmt.Errorf("creating resource: %w", transformStrictDecodingError(resource, err))
+			return nil, fmt.Errorf("upgrading managed fields: %w", err)
 		}
-		return deployed, nil
-	} else if err != nil {
-		return nil, fmt.Errorf("get existing resource: %w", err)
 	}
 
-	resource.SetResourceVersion(existing.GetResourceVersion())
-	updated, err := c.client.Update(ctx, &resource, metav1.UpdateOptions{
+	patchOptions := metav1.PatchOptions{
+		FieldManager:    FieldManager,
+		Force:           &c.opts.Force,
 		FieldValidation: metav1.FieldValidationStrict,
-	})
+	}
+	if c.opts.DryRun {
+		patchOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	applied, err := c.client.Patch(ctx, resource.GetName(), types.ApplyPatchType, data, patchOptions)
 	if err != nil {
-		return nil, fmt.Errorf("updating resource: %w", transformStrictDecodingError(resource, err))
+		return nil, fmt.Errorf("applying resource: %w", transformApplyConflictError(transformStrictDecodingError(resource, err)))
+	}
+
+	return applied, nil
+}
+
+// upgradeManagedFields hands the fields owned by earlier, client-side versions of deployd over to the
+// server-side apply field manager. Otherwise, the old owner keeps fields that are later removed from a manifest,
+// so that they are never pruned, and other field managers may run into unexpected conflicts.
+func upgradeManagedFields(ctx context.Context, client dynamic.ResourceInterface, name string) error {
+	existing, err := client.Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(existing, previousFieldManagers, FieldManager)
+	if err != nil || patch == nil {
+		return err
+	}
+
+	_, err = client.Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// transformApplyConflictError lists each field that is owned by another field manager on a separate line.
+func transformApplyConflictError(err error) error {
+	if !errors.IsConflict(err) {
+		return err
 	}
 
-	return updated, nil
+	status, ok := err.(errors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return err
+	}
+
+	conflicts := make([]metav1.StatusCause, 0)
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type == metav1.CauseTypeFieldManagerConflict {
+			conflicts = append(conflicts, cause)
+		}
+	}
+
+	if len(conflicts) == 0 {
+		return err
+	}
+
+	s := &strings.Builder{}
+	s.WriteString("server-side apply conflict:")
+
+	for _, conflict := range conflicts {
+		s.WriteString("\n| ⚠️ ")
+		s.WriteString(conflict.Field)
+		s.WriteString(": ")
+		s.WriteString(conflict.Message)
+	}
+
+	s.WriteString("\n| The field")
+	if len(conflicts) > 1 {
+		s.WriteString("s are")
+	} else {
+		s.WriteString(" is")
+	}
+	s.WriteString(" managed by another controller or client.")
+	s.WriteString("\n| Remove the conflicting fields from your resource, or deploy with --force-ownership to take ownership of them.")
+
+	return goerrors.New(s.String())
 }
 
 func transformStrictDecodingError(resource unstructured.Unstructured, err error) error {
@@ -54,19 +151,39 @@ func transformStrictDecodingError(resource unstructured.Unstructured, err error)
 
 	// Kubernetes doesn't expose any error types, so we have to rely on the error message for now
 	const strictDecodingError = "strict decoding error:"
+	const fieldNotDeclared = ": field not declared in schema"
 
-	// we only transform strict decoding errors
-	if !strings.Contains(msg, strictDecodingError) {
-		return err
-	}
+	var errs []string
 
-	// we trim the default error message as it is too verbose, e.g:
-	// > Application in version "v1alpha1" cannot be handled as a Application: strict decoding error: unknown field "spec.nestedField", ...
-	if strings.Contains(msg, strictDecodingError) {
+	switch {
+	case strings.Contains(msg, strictDecodingError):
+		// we trim the default error message as it is too verbose, e.g:
+		// > Application in version "v1alpha1" cannot be handled as a Application: strict decoding error: unknown field "spec.nestedField", ...
 		parts := strings.SplitAfterN(msg, strictDecodingError, 2)
 		if len(parts) > 1 {
 			msg = parts[1]
 		}
+		// multiple errors are joined as a comma separated string; split them up again
+		errs = strings.Split(msg, ",")
+
+	case strings.Contains(msg, fieldNotDeclared):
+		// server-side apply reports unknown fields as schema errors, one per line, e.g:
+		// > failed to create typed patch object (...): errors:
+		// >   .spec.nestedField: field not declared in schema
+		// >   .spec.unknownField: field not declared in schema
+		for _, line := range strings.Split(msg, "\n") {
+			if !strings.HasSuffix(line, fieldNotDeclared) {
+				continue
+			}
+			field := strings.TrimSuffix(line, fieldNotDeclared)
+			field = field[strings.LastIndex(field, " ")+1:]
+			errs = append(errs, fmt.Sprintf("unknown field %q", strings.TrimPrefix(field, ".")))
+		}
+		sort.Strings(errs)
+
+	default:
+		// we only transform strict decoding errors
+		return err
 	}
 
 	docs := map[string]string{
@@ -83,8 +200,6 @@ func transformStrictDecodingError(resource unstructured.Unstructured, err error)
 	s := &strings.Builder{}
 	s.WriteString(strictDecodingError)
 
-	// multiple errors are joined as a comma separated string; split them up again
-	errs := strings.Split(msg, ",")
 	for _, e := range errs {
 		s.WriteString("\n| ⚠️ ")
 		s.WriteString(strings.TrimSpace(e))
@@ -101,5 +216,5 @@ func transformStrictDecodingError(resource unstructured.Unstructured, err error)
 		s.WriteString(" at " + u)
 	}
 
-	return fmt.Errorf(s.String())
+	return goerrors.New(s.String())
 }
@@ -0,0 +1,94 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func application() unstructured.Unstructured {
+	resource := unstructured.Unstructured{}
+	resource.SetGroupVersionKind(schema.GroupVersionKind{Group: "nais.io", Version: "v1alpha1", Kind: "Application"})
+	resource.SetName("myapplication")
+	resource.SetNamespace("aura")
+	return resource
+}
+
+const unknownFieldsMessage = "strict decoding error:\n" +
+	"| ⚠️ unknown field \"spec.nestedField\"\n" +
+	"| ⚠️ unknown field \"spec.unknownField\"\n" +
+	"| The fields might be misspelled, incorrectly indented, or unsupported. Fields are case sensitive.\n" +
+	"| Please verify your resource against the reference documentation at https://doc.nais.io/workloads/application/reference/application-spec/"
+
+func TestTransformStrictDecodingError(t *testing.T) {
+	err := fmt.Errorf(`Application in version "v1alpha1" cannot be handled as a Application: strict decoding error: unknown field "spec.nestedField", unknown field "spec.unknownField"`)
+	assert.EqualError(t, transformStrictDecodingError(application(), err), unknownFieldsMessage)
+}
+
+func TestTransformServerSideApplySchemaError(t *testing.T) {
+	err := fmt.Errorf("failed to create typed patch object (aura/myapplication; nais.io/v1alpha1, Kind=Application): errors:\n" +
+		"  .spec.unknownField: field not declared in schema\n" +
+		"  .spec.nestedField: field not declared in schema")
+	assert.EqualError(t, transformStrictDecodingError(application(), err), unknownFieldsMessage)
+}
+
+func TestTransformStrictDecodingErrorPassthrough(t *testing.T) {
+	err := fmt.Errorf("some other error")
+	assert.Equal(t, err, transformStrictDecodingError(application(), err))
+}
+
+func TestTransformApplyConflictError(t *testing.T) {
+	err := errors.NewApplyConflict([]metav1.StatusCause{
+		{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Message: `conflict with "kube-controller-manager" using autoscaling/v2`,
+			Field:   ".spec.replicas",
+		},
+	}, `Apply failed with 1 conflict: conflict with "kube-controller-manager" using autoscaling/v2: .spec.replicas`)
+
+	assert.EqualError(t, transformApplyConflictError(err), "server-side apply conflict:\n"+
+		"| ⚠️ .spec.replicas: conflict with \"kube-controller-manager\" using autoscaling/v2\n"+
+		"| The field is managed by another controller or client.\n"+
+		"| Remove the conflicting fields from your resource, or deploy with --force-ownership to take ownership of them.")
+}
+
+func TestUpgradeManagedFields(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "nais.io", Version: "v1alpha1", Resource: "applications"}
+	existing := application()
+	existing.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:    "deployd",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			APIVersion: "nais.io/v1alpha1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:image":{}}}`)},
+		},
+	})
+
+	scheme := runtime.NewScheme()
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{gvr: "ApplicationList"}, &existing)
+	resource := client.Resource(gvr).Namespace("aura")
+
+	err := upgradeManagedFields(context.Background(), resource, "myapplication")
+	assert.NoError(t, err)
+
+	upgraded, err := resource.Get(context.Background(), "myapplication", metav1.GetOptions{})
+	assert.NoError(t, err)
+	managedFields := upgraded.GetManagedFields()
+	if assert.Len(t, managedFields, 1) {
+		assert.Equal(t, FieldManager, managedFields[0].Manager)
+		assert.Equal(t, metav1.ManagedFieldsOperationApply, managedFields[0].Operation)
+	}
+
+	t.Run("missing resources are left alone", func(t *testing.T) {
+		assert.NoError(t, upgradeManagedFields(context.Background(), resource, "unknown"))
+	})
+}
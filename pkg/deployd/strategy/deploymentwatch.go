@@ -2,9 +2,7 @@ package strategy
 
 import (
 	"context"
-	"fmt"
-	"strconv"
-	"time"
+	"sync"
 
 	"github.com/nais/deploy/pkg/deployd/kubeclient"
 	"github.com/nais/deploy/pkg/deployd/operation"
@@ -12,7 +10,6 @@ import (
 	log "github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/trace"
 	apps "k8s.io/api/apps/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
@@ -22,50 +19,27 @@ type deployment struct {
 }
 
 func (d deployment) Watch(op *operation.Operation, resource unstructured.Unstructured, trace trace.Span) *pb.DeploymentStatus {
-	var cur *apps.Deployment
-	var nova *apps.Deployment
-	var err error
-	var resourceVersion int
-	var updated bool
-
 	client := d.client.Kubernetes().AppsV1().Deployments(resource.GetNamespace())
+	lw := singleObjectListWatch(op.Context, resource.GetName(), client.List, client.Watch)
 
-	ctx, cancel := context.WithCancel(op.Context)
-	defer cancel()
+	// Pod failures abort the rollout early instead of waiting for the deadline.
+	ctx, cancel := context.WithCancelCause(op.Context)
+	defer cancel(nil)
+	podWatch := sync.Once{}
+	var latest *apps.Deployment
 
-	// For native Kubernetes deployment objects, get the current deployment object.
-	for ctx.Err() == nil {
-		cur, err = client.Get(op.Context, resource.GetName(), metav1.GetOptions{})
-		if err == nil {
-			resourceVersion, _ = strconv.Atoi(cur.GetResourceVersion())
-			op.Logger.Debugf("Found current deployment at version %d: %s", resourceVersion, cur.GetSelfLink())
-		} else if errors.IsNotFound(err) {
-			op.Logger.Debugf("Deployment '%s' in namespace '%s' is not currently present in the cluster.", resource.GetName(), resource.GetNamespace())
-		} else {
-			op.Logger.Debugf("Recoverable error while polling for deployment object: %s", err)
-			time.Sleep(requestInterval)
-			continue
-		}
-		break
-	}
+	err := untilCondition(ctx, lw, &apps.Deployment{}, func(nova *apps.Deployment) (bool, error) {
+		latest = nova
+		podWatch.Do(func() {
+			go d.watchPods(ctx, cancel, nova)
+		})
 
-	// Wait until the new deployment object is present in the cluster.
-	for ctx.Err() == nil {
-		nova, err = client.Get(op.Context, resource.GetName(), metav1.GetOptions{})
-		if err != nil {
-			time.Sleep(requestInterval)
-			continue
+		if deploymentComplete(nova, &nova.Status) {
+			return true, nil
 		}
 
-		rv, _ := strconv.Atoi(nova.GetResourceVersion())
-		if rv > resourceVersion {
-			op.Logger.Tracef("New deployment appeared at version %d: %s", rv, cur.GetSelfLink())
-			resourceVersion = rv
-			updated = true
-		}
-
-		if updated && deploymentComplete(nova, &nova.Status) {
-			return pb.NewSuccessStatus(op.Request)
+		if err := progressDeadlineExceeded(nova); err != nil {
+			return false, err
 		}
 
 		op.Logger.WithFields(log.Fields{
@@ -75,14 +49,43 @@ func (d deployment) Watch(op *operation.Operation, resource unstructured.Unstruc
 			"deployment_observed_generation": nova.Status.ObservedGeneration,
 		}).Debugf("Still waiting for deployment to finish rollout...")
 
-		time.Sleep(requestInterval)
+		return false, nil
+	})
+
+	status := rolloutStatus(op, resource, err, trace)
+	if err != nil && latest != nil {
+		status.Logs = d.failingContainerLogs(op.Context, latest)
 	}
 
+	return status
+}
+
+// watchPods aborts the rollout if a pod of the newest ReplicaSet fails.
+func (d deployment) watchPods(ctx context.Context, cancel context.CancelCauseFunc, nova *apps.Deployment) {
+	selector, err := metav1.LabelSelectorAsSelector(nova.Spec.Selector)
+	if err != nil {
+		return
+	}
+	client := d.client.Kubernetes()
+	relevant := latestRevisionPod(ctx, client, nova.GetNamespace(), nova.GetName(), selector)
+	err = watchPodFailures(ctx, client, nova.GetNamespace(), selector, relevant)
 	if err != nil {
-		return pb.NewErrorStatus(op.Request, fmt.Errorf("%s; last error was: %s", ErrDeploymentTimeout, err))
+		cancel(err)
 	}
+}
 
-	return pb.NewErrorStatus(op.Request, ErrDeploymentTimeout)
+// failingContainerLogs captures the logs of failing containers in the newest ReplicaSet.
+func (d deployment) failingContainerLogs(ctx context.Context, nova *apps.Deployment) []*pb.ContainerLog {
+	if nova.Spec.Selector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(nova.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	client := d.client.Kubernetes()
+	relevant := latestRevisionPod(ctx, client, nova.GetNamespace(), nova.GetName(), selector)
+	return failingContainerLogs(ctx, client, nova.GetNamespace(), selector, relevant)
 }
 
 // deploymentComplete considers a deployment to be complete once all of its desired replicas
@@ -3,15 +3,15 @@ package strategy
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/nais/deploy/pkg/deployd/kubeclient"
 	"github.com/nais/deploy/pkg/deployd/operation"
 	"github.com/nais/deploy/pkg/pb"
 	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/batch/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 type job struct {
@@ -19,43 +19,51 @@ type job struct {
 }
 
 func (j job) Watch(op *operation.Operation, resource unstructured.Unstructured, trace trace.Span) *pb.DeploymentStatus {
-	var job *v1.Job
-	var err error
-
 	client := j.client.Kubernetes().BatchV1().Jobs(resource.GetNamespace())
-
-	ctx, cancel := context.WithCancel(op.Context)
-	defer cancel()
-
-	// Wait until the new job object is present in the cluster.
-	for ctx.Err() == nil {
-		job, err = client.Get(op.Context, resource.GetName(), metav1.GetOptions{})
-
+	lw := singleObjectListWatch(op.Context, resource.GetName(), client.List, client.Watch)
+
+	// Pod failures abort the job early instead of waiting for the deadline.
+	ctx, cancel := context.WithCancelCause(op.Context)
+	defer cancel(nil)
+	selector := labels.SelectorFromSet(labels.Set{"job-name": resource.GetName()})
+	allPods := func(pod *corev1.Pod) bool {
+		return true
+	}
+	go func() {
+		err := watchPodFailures(ctx, j.client.Kubernetes(), resource.GetNamespace(), selector, allPods)
 		if err != nil {
-			time.Sleep(requestInterval)
-			continue
+			cancel(err)
 		}
+	}()
 
+	err := untilCondition(ctx, lw, &v1.Job{}, func(job *v1.Job) (bool, error) {
 		if jobComplete(job) {
-			return nil
+			return true, nil
 		}
 
 		if status, condition := jobFailed(job); status {
-			return pb.NewFailureStatus(op.Request, fmt.Errorf("job failed: %s", condition.String()))
+			return false, fmt.Errorf("job failed: %s", condition.String())
 		}
 
 		op.Logger.Debugf("Still waiting for job to complete...")
 
-		time.Sleep(requestInterval)
-	}
+		return false, nil
+	})
 
-	if err != nil {
-		err = fmt.Errorf("%s; last error was: %w", ErrDeploymentTimeout, err)
+	var status *pb.DeploymentStatus
+	switch {
+	case err == nil:
+		return nil
+	case err == ErrDeploymentTimeout:
 		trace.AddEvent(err.Error())
-		return pb.NewErrorStatus(op.Request, err)
+		status = pb.NewErrorStatus(op.Request, ErrDeploymentTimeout)
+	default:
+		status = pb.NewFailureStatus(op.Request, err)
 	}
 
-	return pb.NewErrorStatus(op.Request, ErrDeploymentTimeout)
+	status.Logs = failingContainerLogs(op.Context, j.client.Kubernetes(), resource.GetNamespace(), selector, allPods)
+
+	return status
 }
 
 func jobComplete(job *v1.Job) bool {
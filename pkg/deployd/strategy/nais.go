@@ -13,6 +13,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/nais/deploy/pkg/deployd/kubeclient"
 	"github.com/nais/deploy/pkg/deployd/operation"
@@ -26,6 +27,16 @@ type naisResource struct {
 func (a naisResource) Watch(op *operation.Operation, resource unstructured.Unstructured, trace trace.Span) *pb.DeploymentStatus {
 	var err error
 
+	// A resumed deployment may have finished rolling out while deployd was down, in which case no more events follow.
+	if op.Request.GetResume() && a.rolledOut(op.Context, resource, op.Request.GetID()) {
+		return &pb.DeploymentStatus{
+			Request: op.Request,
+			Message: fmt.Sprintf("%s/%s (%s): rolled out while deployd was restarting", resource.GetKind(), resource.GetName(), events.RolloutComplete),
+			State:   pb.DeploymentState_success,
+			Time:    pb.TimeAsTimestamp(time.Now()),
+		}
+	}
+
 	eventsClient := a.client.Kubernetes().CoreV1().Events(resource.GetNamespace())
 	deadline, _ := op.Context.Deadline()
 	timeoutSecs := int64(time.Until(deadline).Seconds())
@@ -43,11 +54,28 @@ func (a naisResource) Watch(op *operation.Operation, resource unstructured.Unstr
 	defer eventWatcher.Stop()
 	watchStart := time.Now().Truncate(time.Second)
 
+	// Pod failures abort the rollout early instead of waiting for the deadline.
+	selector, relevant := a.pods(ctx, resource, watchStart)
+	podFailure := make(chan error, 1)
+	go func() {
+		err := watchPodFailures(ctx, a.client.Kubernetes(), resource.GetNamespace(), selector, relevant)
+		if err != nil {
+			podFailure <- err
+		}
+	}()
+
+	withLogs := func(status *pb.DeploymentStatus) *pb.DeploymentStatus {
+		// The pod filter is still in use by the pod watch, so logs are fetched using a filter of their own.
+		_, relevant := a.pods(op.Context, resource, watchStart)
+		status.Logs = failingContainerLogs(op.Context, a.client.Kubernetes(), resource.GetNamespace(), selector, relevant)
+		return status
+	}
+
 	for {
 		select {
 		case watchEvent, ok := <-eventWatcher.ResultChan():
 			if !ok {
-				return pb.NewErrorStatus(op.Request, ErrDeploymentTimeout)
+				return withLogs(pb.NewErrorStatus(op.Request, ErrDeploymentTimeout))
 			}
 
 			event, ok := watchEvent.Object.(*v1.Event)
@@ -68,7 +96,9 @@ func (a naisResource) Watch(op *operation.Operation, resource unstructured.Unstr
 				continue // the event is annotated with a correlation id, but it's not the one we're rolling out. ignore it
 			}
 
-			if event.LastTimestamp.Time.Before(watchStart) {
+			// Events reported for a resumed deployment before deployd restarted still apply to it.
+			resumed := op.Request.GetResume() && eventHasCorrelationID
+			if event.LastTimestamp.Time.Before(watchStart) && !resumed {
 				op.Logger.Tracef("Ignoring old event %s", event.Name)
 				continue
 			}
@@ -78,6 +108,10 @@ func (a naisResource) Watch(op *operation.Operation, resource unstructured.Unstr
 				return pb.NewFailureStatus(op.Request, fmt.Errorf("this application has been redeployed, aborting monitoring"))
 			}
 
+			if status.GetState() == pb.DeploymentState_failure {
+				return withLogs(status)
+			}
+
 			if status.GetState().Finished() {
 				return status
 			}
@@ -85,12 +119,51 @@ func (a naisResource) Watch(op *operation.Operation, resource unstructured.Unstr
 			trace.AddEvent(status.Message)
 			op.StatusChan <- status
 
+		case err := <-podFailure:
+			trace.AddEvent(err.Error())
+			return withLogs(pb.NewFailureStatus(op.Request, err))
+
 		case <-op.Context.Done():
-			return pb.NewErrorStatus(op.Request, ErrDeploymentTimeout)
+			return withLogs(pb.NewErrorStatus(op.Request, ErrDeploymentTimeout))
 		}
 	}
 }
 
+// rolledOut returns true if naiserator reports that the rollout of a deployment has completed.
+func (a naisResource) rolledOut(ctx context.Context, resource unstructured.Unstructured, correlationID string) bool {
+	resourceInterface, err := a.client.ResourceInterface(&resource)
+	if err != nil {
+		return false
+	}
+	live, err := resourceInterface.Get(ctx, resource.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	return rolloutComplete(live, correlationID)
+}
+
+func rolloutComplete(live *unstructured.Unstructured, correlationID string) bool {
+	id, _, _ := unstructured.NestedString(live.Object, "status", "correlationID")
+	state, _, _ := unstructured.NestedString(live.Object, "status", "synchronizationState")
+	return id == correlationID && state == events.RolloutComplete
+}
+
+// pods returns the selector and filter for the pods that take part in rolling out an Application or a Naisjob.
+// Naiserator labels every pod with the name of the resource. Pods created before the watch started are left over from
+// earlier deployments or runs, and disregarded. For an Application, only pods of the newest ReplicaSet of its Deployment
+// are relevant, as pods of previous revisions may be rescheduled while they are being scaled down.
+func (a naisResource) pods(ctx context.Context, resource unstructured.Unstructured, watchStart time.Time) (labels.Selector, func(pod *v1.Pod) bool) {
+	selector := labels.SelectorFromSet(labels.Set{"app": resource.GetName()})
+	recent := createdSince(watchStart)
+	if resource.GetKind() != "Application" {
+		return selector, recent
+	}
+	latest := latestRevisionPod(ctx, a.client.Kubernetes(), resource.GetNamespace(), resource.GetName(), selector)
+	return selector, func(pod *v1.Pod) bool {
+		return recent(pod) && latest(pod)
+	}
+}
+
 func EventString(event *v1.Event) string {
 	return fmt.Sprintf("%s/%s (%s): %s", event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Reason, event.Message)
 }
@@ -0,0 +1,253 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// Number of restarts after which a crash-looping container is considered to have failed.
+var maxRestarts int32 = 3
+
+// SetMaxRestarts configures how many times a container may restart before the rollout is failed.
+func SetMaxRestarts(restarts int32) {
+	maxRestarts = restarts
+}
+
+// Number of log lines captured from each failing container.
+var logTailLines int64 = 50
+
+// SetLogTailLines configures how many log lines are captured from each failing container.
+func SetLogTailLines(lines int64) {
+	logTailLines = lines
+}
+
+// Upper bound on the number of containers to capture logs from, so that a large deployment does not flood the status.
+const maxLoggedContainers = 5
+
+// Time allowed for fetching logs. The deadline of the deployment may already have passed, so it cannot be reused.
+const logFetchTimeout = 10 * time.Second
+
+// Container waiting reasons that will not resolve without a new deployment.
+// ErrImagePull and CreateContainerError are left out, as they are often caused by transient problems and retried.
+// An image that cannot be pulled ends up in ImagePullBackOff.
+var terminalWaitingReasons = map[string]bool{
+	"CreateContainerConfigError": true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+}
+
+// diagnosePod returns an error describing the first container in the pod that is stuck in a terminal state,
+// or nil if all containers are healthy or still starting up.
+func diagnosePod(pod *corev1.Pod, maxRestarts int32) error {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+
+	for _, status := range statuses {
+		waiting := status.State.Waiting
+		if waiting == nil {
+			continue
+		}
+
+		var reason string
+		switch {
+		case terminalWaitingReasons[waiting.Reason]:
+			reason = waiting.Reason
+		case waiting.Reason == "CrashLoopBackOff" && status.RestartCount >= maxRestarts:
+			reason = fmt.Sprintf("%s after %d restarts", waiting.Reason, status.RestartCount)
+		default:
+			continue
+		}
+
+		s := &strings.Builder{}
+		fmt.Fprintf(s, "container %q in pod %q: %s", status.Name, pod.GetName(), reason)
+		if len(waiting.Message) > 0 {
+			fmt.Fprintf(s, ": %s", waiting.Message)
+		}
+		if terminated := status.LastTerminationState.Terminated; terminated != nil {
+			fmt.Fprintf(s, "; last termination: %s (exit code %d)", terminated.Reason, terminated.ExitCode)
+			if len(terminated.Message) > 0 {
+				fmt.Fprintf(s, ": %s", strings.TrimSpace(terminated.Message))
+			}
+		}
+		return fmt.Errorf("%s", s.String())
+	}
+
+	return nil
+}
+
+// watchPodFailures watches pods matching the selector, and returns as soon as a relevant pod is stuck in a terminal state.
+// Returns nil once the context is done.
+func watchPodFailures(ctx context.Context, client kubernetes.Interface, namespace string, selector labels.Selector, relevant func(pod *corev1.Pod) bool) error {
+	pods := client.CoreV1().Pods(namespace)
+	lw := filteredListWatch(ctx, func(options *metav1.ListOptions) {
+		options.LabelSelector = selector.String()
+	}, pods.List, pods.Watch)
+
+	err := untilCondition(ctx, lw, &corev1.Pod{}, func(pod *corev1.Pod) (bool, error) {
+		err := diagnosePod(pod, maxRestarts)
+		if err != nil && relevant(pod) {
+			return false, err
+		}
+		return false, nil
+	})
+	if err == ErrDeploymentTimeout || err == ErrResourceDeleted {
+		return nil
+	}
+	return err
+}
+
+// latestRevisionPod returns a filter that matches pods belonging to the newest ReplicaSet of a deployment.
+// Pods of previous revisions are being scaled down, and their state does not affect the rollout.
+//
+// The ReplicaSets of the deployment are listed once, and listed again only when a pod belongs to one that has not been
+// seen yet, such as the ReplicaSet created for a new revision. The filter is not safe for concurrent use.
+func latestRevisionPod(ctx context.Context, client kubernetes.Interface, namespace, deploymentName string, selector labels.Selector) func(pod *corev1.Pod) bool {
+	var revisions map[string]int64
+	var latest int64
+
+	list := func() {
+		replicaSets, err := client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			log.Errorf("List replicasets of deployment %q: %s", deploymentName, err)
+			return
+		}
+		revisions = make(map[string]int64, len(replicaSets.Items))
+		latest = 0
+		for _, replicaSet := range replicaSets.Items {
+			owner := metav1.GetControllerOf(&replicaSet)
+			if owner == nil || owner.Kind != "Deployment" || owner.Name != deploymentName {
+				continue
+			}
+			// The deployment controller gives the newest ReplicaSet the highest revision, also when rolling back.
+			revision, _ := strconv.ParseInt(replicaSet.GetAnnotations()[revisionAnnotation], 10, 64)
+			revisions[replicaSet.GetName()] = revision
+			if revision > latest {
+				latest = revision
+			}
+		}
+	}
+
+	return func(pod *corev1.Pod) bool {
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil || owner.Kind != "ReplicaSet" {
+			return false
+		}
+		revision, ok := revisions[owner.Name]
+		if !ok {
+			list()
+			revision, ok = revisions[owner.Name]
+		}
+		return ok && revision == latest
+	}
+}
+
+// createdSince returns a filter that matches pods created at or after the given time.
+func createdSince(since time.Time) func(pod *corev1.Pod) bool {
+	since = since.Truncate(time.Second)
+	return func(pod *corev1.Pod) bool {
+		return !pod.GetCreationTimestamp().Time.Before(since)
+	}
+}
+
+// failingContainerLogs fetches the last lines logged by failing containers in relevant pods matching the selector.
+// Errors are logged and otherwise ignored, as the logs are only a debugging aid.
+func failingContainerLogs(ctx context.Context, client kubernetes.Interface, namespace string, selector labels.Selector, relevant func(pod *corev1.Pod) bool) []*pb.ContainerLog {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), logFetchTimeout)
+	defer cancel()
+
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		log.Errorf("List pods for container logs: %s", err)
+		return nil
+	}
+
+	logs := make([]*pb.ContainerLog, 0)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !relevant(pod) {
+			continue
+		}
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if len(logs) >= maxLoggedContainers {
+				return logs
+			}
+			if !containerFailing(status) {
+				continue
+			}
+			lines, err := containerLogLines(ctx, client, pod, status)
+			if err != nil {
+				log.Errorf("Fetch logs for container %q in pod %q: %s", status.Name, pod.GetName(), err)
+				continue
+			}
+			logs = append(logs, &pb.ContainerLog{
+				Pod:       pod.GetName(),
+				Container: status.Name,
+				Lines:     lines,
+			})
+		}
+	}
+
+	return logs
+}
+
+// containerFailing returns true if the container has run at least once, and then crashed or exited with an error.
+// Containers that never started, e.g. because the image could not be pulled, have no logs.
+func containerFailing(status corev1.ContainerStatus) bool {
+	if status.Ready {
+		return false
+	}
+	if terminated := status.State.Terminated; terminated != nil {
+		return terminated.ExitCode != 0
+	}
+	return status.LastTerminationState.Terminated != nil
+}
+
+func containerLogLines(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod, status corev1.ContainerStatus) ([]string, error) {
+	tailLines := logTailLines
+	options := &corev1.PodLogOptions{
+		Container: status.Name,
+		TailLines: &tailLines,
+		// Restarted containers have no interesting output yet; the crash is logged by the previous instance.
+		Previous: status.State.Terminated == nil && status.LastTerminationState.Terminated != nil,
+	}
+	raw, err := client.CoreV1().Pods(pod.GetNamespace()).GetLogs(pod.GetName(), options).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimRight(string(raw), "\n")
+	if len(text) == 0 {
+		return []string{}, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// progressDeadlineExceeded returns an error if the deployment controller has given up on the current rollout.
+func progressDeadlineExceeded(deployment *apps.Deployment) error {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return nil
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == apps.DeploymentProgressing && condition.Status == corev1.ConditionFalse && condition.Reason == "ProgressDeadlineExceeded" {
+			return fmt.Errorf("%s: %s", condition.Reason, condition.Message)
+		}
+	}
+	return nil
+}
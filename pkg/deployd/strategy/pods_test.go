@@ -0,0 +1,289 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func waitingPod(reason, message string, restarts int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-abc", Namespace: "aura"},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:         "app",
+					RestartCount: restarts,
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: message},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestDiagnosePod(t *testing.T) {
+	t.Run("image pull failure", func(t *testing.T) {
+		err := diagnosePod(waitingPod("ImagePullBackOff", "Back-off pulling image \"app:missing\"", 0), 3)
+		assert.EqualError(t, err, `container "app" in pod "app-abc": ImagePullBackOff: Back-off pulling image "app:missing"`)
+	})
+
+	t.Run("still starting", func(t *testing.T) {
+		assert.NoError(t, diagnosePod(waitingPod("ContainerCreating", "", 0), 3))
+	})
+
+	t.Run("crash loop below threshold", func(t *testing.T) {
+		assert.NoError(t, diagnosePod(waitingPod("CrashLoopBackOff", "", 2), 3))
+	})
+
+	t.Run("crash loop with last termination", func(t *testing.T) {
+		pod := waitingPod("CrashLoopBackOff", "", 3)
+		pod.Status.ContainerStatuses[0].LastTerminationState.Terminated = &corev1.ContainerStateTerminated{
+			Reason:   "Error",
+			ExitCode: 1,
+			Message:  "missing DATABASE_URL\n",
+		}
+		err := diagnosePod(pod, 3)
+		assert.EqualError(t, err, `container "app" in pod "app-abc": CrashLoopBackOff after 3 restarts; last termination: Error (exit code 1): missing DATABASE_URL`)
+	})
+
+	t.Run("init container", func(t *testing.T) {
+		pod := waitingPod("ContainerCreating", "", 0)
+		pod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+			{
+				Name: "init",
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{Reason: "CreateContainerConfigError", Message: "secret \"db\" not found"},
+				},
+			},
+		}
+		err := diagnosePod(pod, 3)
+		assert.EqualError(t, err, `container "init" in pod "app-abc": CreateContainerConfigError: secret "db" not found`)
+	})
+}
+
+func TestProgressDeadlineExceeded(t *testing.T) {
+	deployment := testDeployment(2, 1)
+	assert.NoError(t, progressDeadlineExceeded(deployment))
+
+	deployment.Status.Conditions = []apps.DeploymentCondition{
+		{
+			Type:    apps.DeploymentProgressing,
+			Status:  corev1.ConditionFalse,
+			Reason:  "ProgressDeadlineExceeded",
+			Message: `ReplicaSet "app-123" has timed out progressing.`,
+		},
+	}
+	assert.EqualError(t, progressDeadlineExceeded(deployment), `ProgressDeadlineExceeded: ReplicaSet "app-123" has timed out progressing.`)
+
+	deployment.Status.ObservedGeneration = 1
+	assert.NoError(t, progressDeadlineExceeded(deployment), "condition belongs to a previous generation")
+}
+
+func TestDeploymentWatchPodFailure(t *testing.T) {
+	controller := true
+	nova := testDeployment(2, 1)
+	nova.Annotations = map[string]string{revisionAnnotation: "2"}
+	nova.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}}
+
+	replicaSetOf := func(name, revision string) *apps.ReplicaSet {
+		return &apps.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "aura",
+				Labels:          map[string]string{"app": "app"},
+				Annotations:     map[string]string{revisionAnnotation: revision},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "app", Controller: &controller}},
+			},
+		}
+	}
+	replicaSet := replicaSetOf("app-2", "2")
+	oldReplicaSet := replicaSetOf("app-1", "1")
+
+	pod := func(name, owner, reason string) *corev1.Pod {
+		p := waitingPod(reason, "", 0)
+		p.Name = name
+		p.Labels = map[string]string{"app": "app"}
+		p.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: owner, Controller: &controller}}
+		return p
+	}
+
+	t.Run("pod of newest replicaset fails", func(t *testing.T) {
+		client := fakeKubeClient{fake.NewSimpleClientset(nova, replicaSet, pod("app-2-abc", "app-2", "ImagePullBackOff"))}
+		resource := watchResource("app", "aura")
+		resource.SetKind("Deployment")
+		op, cancel := newTestOperation(5 * time.Second)
+		defer cancel()
+		status := deployment{client: client}.Watch(op, resource, trace.SpanFromContext(op.Context))
+
+		assert.Equal(t, pb.DeploymentState_failure, status.GetState())
+		assert.Equal(t, `deployment/app: container "app" in pod "app-2-abc": ImagePullBackOff`, status.GetMessage())
+	})
+
+	t.Run("pod of previous replicaset is ignored", func(t *testing.T) {
+		client := fakeKubeClient{fake.NewSimpleClientset(nova, replicaSet, oldReplicaSet, pod("app-1-abc", "app-1", "ImagePullBackOff"))}
+		status := runWatch(t, deployment{client: client}, 500*time.Millisecond)
+
+		assert.Equal(t, pb.DeploymentState_error, status.GetState())
+		assert.Equal(t, ErrDeploymentTimeout.Error(), status.GetMessage())
+	})
+}
+
+func TestJobWatchPodFailure(t *testing.T) {
+	testJob := &batch.Job{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "aura"}}
+	pod := waitingPod("CreateContainerConfigError", `secret "db" not found`, 0)
+	pod.Labels = map[string]string{"job-name": "app"}
+
+	client := fakeKubeClient{fake.NewSimpleClientset(testJob, pod)}
+	status := runWatch(t, job{client: client}, 5*time.Second)
+
+	assert.Equal(t, pb.DeploymentState_failure, status.GetState())
+	assert.Equal(t, `container "app" in pod "app-abc": CreateContainerConfigError: secret "db" not found`, status.GetMessage())
+}
+
+func TestContainerFailing(t *testing.T) {
+	crashed := &corev1.ContainerStateTerminated{ExitCode: 1}
+
+	assert.False(t, containerFailing(corev1.ContainerStatus{
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+	}), "never started")
+	assert.False(t, containerFailing(corev1.ContainerStatus{
+		Ready:                true,
+		LastTerminationState: corev1.ContainerState{Terminated: crashed},
+	}), "recovered")
+	assert.False(t, containerFailing(corev1.ContainerStatus{
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}},
+	}), "completed")
+	assert.True(t, containerFailing(corev1.ContainerStatus{
+		State: corev1.ContainerState{Terminated: crashed},
+	}))
+	assert.True(t, containerFailing(corev1.ContainerStatus{
+		State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		LastTerminationState: corev1.ContainerState{Terminated: crashed},
+	}))
+}
+
+func TestJobWatchFailureLogs(t *testing.T) {
+	testJob := &batch.Job{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "aura"}}
+	pod := waitingPod("CrashLoopBackOff", "", 3)
+	pod.Labels = map[string]string{"job-name": "app"}
+	pod.Status.ContainerStatuses[0].LastTerminationState.Terminated = &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}
+
+	client := fakeKubeClient{fake.NewSimpleClientset(testJob, pod)}
+	status := runWatch(t, job{client: client}, 5*time.Second)
+
+	assert.Equal(t, pb.DeploymentState_failure, status.GetState())
+	assert.Len(t, status.GetLogs(), 1)
+	assert.Equal(t, "app-abc", status.GetLogs()[0].GetPod())
+	assert.Equal(t, "app", status.GetLogs()[0].GetContainer())
+	// The fake clientset always returns the same log output.
+	assert.Equal(t, []string{"fake logs"}, status.GetLogs()[0].GetLines())
+}
+
+func TestDaemonSetWatchFailureLogs(t *testing.T) {
+	ds := &apps.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   "aura",
+			Annotations: map[string]string{apps.DeprecatedTemplateGeneration: "2"},
+		},
+		Spec:   apps.DaemonSetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}}},
+		Status: apps.DaemonSetStatus{DesiredNumberScheduled: 1},
+	}
+	pod := func(name, generation string) *corev1.Pod {
+		p := waitingPod("CrashLoopBackOff", "", 3)
+		p.Name = name
+		p.Labels = map[string]string{"app": "app", daemonSetTemplateGenerationLabel: generation}
+		p.Status.ContainerStatuses[0].LastTerminationState.Terminated = &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}
+		return p
+	}
+
+	client := fakeKubeClient{fake.NewSimpleClientset(ds, pod("app-old", "1"), pod("app-new", "2"))}
+	status := runWatch(t, daemonSet{client: client}, 500*time.Millisecond)
+
+	assert.Equal(t, pb.DeploymentState_error, status.GetState())
+	assert.Len(t, status.GetLogs(), 1)
+	assert.Equal(t, "app-new", status.GetLogs()[0].GetPod())
+}
+
+func TestLatestRevisionPodListsOnce(t *testing.T) {
+	controller := true
+	replicaSet := &apps.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "app-2",
+			Namespace:       "aura",
+			Labels:          map[string]string{"app": "app"},
+			Annotations:     map[string]string{revisionAnnotation: "2"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "app", Controller: &controller}},
+		},
+	}
+	pod := waitingPod("ImagePullBackOff", "", 0)
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "app-2", Controller: &controller}}
+
+	client := fake.NewSimpleClientset(replicaSet)
+	relevant := latestRevisionPod(context.Background(), client, "aura", "app", labels.SelectorFromSet(labels.Set{"app": "app"}))
+	for i := 0; i < 3; i++ {
+		assert.True(t, relevant(pod))
+	}
+	assert.Len(t, client.Actions(), 1)
+}
+
+func TestNaisWatchPodFailure(t *testing.T) {
+	controller := true
+	application := func() unstructured.Unstructured {
+		resource := watchResource("app", "aura")
+		resource.SetKind("Application")
+		return resource
+	}
+
+	replicaSet := &apps.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "app-2",
+			Namespace:       "aura",
+			Labels:          map[string]string{"app": "app"},
+			Annotations:     map[string]string{revisionAnnotation: "2"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "app", Controller: &controller}},
+		},
+	}
+	pod := func(created time.Time) *corev1.Pod {
+		p := waitingPod("CrashLoopBackOff", "", 3)
+		p.Labels = map[string]string{"app": "app"}
+		p.CreationTimestamp = metav1.NewTime(created)
+		p.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "app-2", Controller: &controller}}
+		p.Status.ContainerStatuses[0].LastTerminationState.Terminated = &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}
+		return p
+	}
+
+	t.Run("failing pod of new revision", func(t *testing.T) {
+		client := fakeKubeClient{fake.NewSimpleClientset(replicaSet, pod(time.Now().Add(time.Minute)))}
+		op, cancel := newTestOperation(5 * time.Second)
+		defer cancel()
+		status := naisResource{client: client}.Watch(op, application(), trace.SpanFromContext(op.Context))
+
+		assert.Equal(t, pb.DeploymentState_failure, status.GetState())
+		assert.Equal(t, `container "app" in pod "app-abc": CrashLoopBackOff after 3 restarts; last termination: Error (exit code 1)`, status.GetMessage())
+		assert.Len(t, status.GetLogs(), 1)
+	})
+
+	t.Run("pods left over from earlier deployments are ignored", func(t *testing.T) {
+		client := fakeKubeClient{fake.NewSimpleClientset(replicaSet, pod(time.Now().Add(-time.Hour)))}
+		op, cancel := newTestOperation(500 * time.Millisecond)
+		defer cancel()
+		status := naisResource{client: client}.Watch(op, application(), trace.SpanFromContext(op.Context))
+
+		assert.Equal(t, pb.DeploymentState_error, status.GetState())
+		assert.Empty(t, status.GetLogs())
+	})
+}
@@ -0,0 +1,98 @@
+package strategy
+
+import (
+	"context"
+
+	"github.com/nais/deploy/pkg/deployd/kubeclient"
+	"github.com/nais/deploy/pkg/deployd/operation"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type statefulSet struct {
+	client kubeclient.Interface
+}
+
+func (s statefulSet) Watch(op *operation.Operation, resource unstructured.Unstructured, trace trace.Span) *pb.DeploymentStatus {
+	client := s.client.Kubernetes().AppsV1().StatefulSets(resource.GetNamespace())
+	lw := singleObjectListWatch(op.Context, resource.GetName(), client.List, client.Watch)
+
+	var latest *apps.StatefulSet
+
+	err := untilCondition(op.Context, lw, &apps.StatefulSet{}, func(sts *apps.StatefulSet) (bool, error) {
+		latest = sts
+		if statefulSetComplete(sts) {
+			return true, nil
+		}
+
+		op.Logger.WithFields(log.Fields{
+			"statefulset_replicas":            sts.Status.Replicas,
+			"statefulset_ready_replicas":      sts.Status.ReadyReplicas,
+			"statefulset_updated_replicas":    sts.Status.UpdatedReplicas,
+			"statefulset_current_revision":    sts.Status.CurrentRevision,
+			"statefulset_update_revision":     sts.Status.UpdateRevision,
+			"statefulset_observed_generation": sts.Status.ObservedGeneration,
+		}).Debugf("Still waiting for statefulset to finish rollout...")
+
+		return false, nil
+	})
+
+	status := rolloutStatus(op, resource, err, trace)
+	if err != nil && latest != nil {
+		status.Logs = s.failingContainerLogs(op.Context, latest)
+	}
+
+	return status
+}
+
+// failingContainerLogs captures the logs of failing containers running the update revision.
+func (s statefulSet) failingContainerLogs(ctx context.Context, sts *apps.StatefulSet) []*pb.ContainerLog {
+	if sts.Spec.Selector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(sts.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	updateRevision := func(pod *corev1.Pod) bool {
+		return pod.GetLabels()[apps.ControllerRevisionHashLabelKey] == sts.Status.UpdateRevision
+	}
+	return failingContainerLogs(ctx, s.client.Kubernetes(), sts.GetNamespace(), selector, updateRevision)
+}
+
+// statefulSetComplete considers a statefulset to be complete once the controller has observed the latest generation,
+// all replicas are ready, and all replicas run the latest revision.
+// For partitioned rolling updates, only the replicas at or above the partition are required to be updated.
+//
+// Adapted from the `kubectl rollout status` implementation.
+func statefulSetComplete(sts *apps.StatefulSet) bool {
+	if sts.Status.ObservedGeneration < sts.Generation {
+		return false
+	}
+
+	// Pods are only replaced when deleted, so there is no rollout to wait for.
+	if sts.Spec.UpdateStrategy.Type == apps.OnDeleteStatefulSetStrategyType {
+		return true
+	}
+
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	if sts.Status.ReadyReplicas < replicas {
+		return false
+	}
+
+	rollingUpdate := sts.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate != nil && rollingUpdate.Partition != nil && *rollingUpdate.Partition > 0 {
+		return sts.Status.UpdatedReplicas >= replicas-*rollingUpdate.Partition
+	}
+
+	return sts.Status.UpdatedReplicas == replicas && sts.Status.UpdateRevision == sts.Status.CurrentRevision
+}
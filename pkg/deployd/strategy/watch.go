@@ -1,20 +1,27 @@
 package strategy
 
 import (
+	"context"
 	"fmt"
-	"time"
+	"strings"
 
 	"github.com/nais/deploy/pkg/deployd/kubeclient"
 	"github.com/nais/deploy/pkg/deployd/operation"
 	"github.com/nais/deploy/pkg/pb"
 	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
 )
 
 var (
-	requestInterval      = time.Second * 5
 	ErrDeploymentTimeout = fmt.Errorf("timeout while waiting for deployment to succeed")
+	ErrResourceDeleted   = fmt.Errorf("resource was deleted while waiting for rollout")
 )
 
 type WatchStrategy interface {
@@ -37,9 +44,88 @@ func NewWatchStrategy(gvk schema.GroupVersionKind, client kubeclient.Interface)
 		return deployment{client: client}
 	}
 
+	if gvk.Group == "apps" && gvk.Kind == "StatefulSet" {
+		return statefulSet{client: client}
+	}
+
+	if gvk.Group == "apps" && gvk.Kind == "DaemonSet" {
+		return daemonSet{client: client}
+	}
+
 	if gvk.Group == "batch" && gvk.Kind == "Job" && gvk.Version == "v1" {
 		return job{client: client}
 	}
 
+	if conditionType, ok := conditionKinds[gvk.GroupKind()]; ok {
+		return conditionResource{client: client, conditionType: conditionType}
+	}
+
 	return NoOp{}
 }
+
+type listFunc[T runtime.Object] func(ctx context.Context, opts metav1.ListOptions) (T, error)
+type watchFunc func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+
+// singleObjectListWatch lists and watches only the object with the given name.
+func singleObjectListWatch[T runtime.Object](ctx context.Context, name string, list listFunc[T], watchObjects watchFunc) *cache.ListWatch {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	return filteredListWatch(ctx, func(options *metav1.ListOptions) {
+		options.FieldSelector = fieldSelector
+	}, list, watchObjects)
+}
+
+// filteredListWatch lists and watches objects, restricting every request using the filter.
+func filteredListWatch[T runtime.Object](ctx context.Context, filter func(options *metav1.ListOptions), list listFunc[T], watchObjects watchFunc) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			filter(&options)
+			return list(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			filter(&options)
+			return watchObjects(ctx, options)
+		},
+	}
+}
+
+// untilCondition waits for the condition to be met by an object, using an informer instead of polling the API server.
+// The informer re-lists whenever the watch expires (410 Gone), so no updates are lost on long rollouts.
+// Deletion of the object is reported as ErrResourceDeleted.
+func untilCondition[T runtime.Object](ctx context.Context, lw cache.ListerWatcher, objType T, condition func(obj T) (bool, error)) error {
+	_, err := watchtools.UntilWithSync(ctx, lw, objType, nil, func(event watch.Event) (bool, error) {
+		switch event.Type {
+		case watch.Deleted:
+			return false, ErrResourceDeleted
+		case watch.Added, watch.Modified:
+			obj, ok := event.Object.(T)
+			if !ok {
+				return false, nil
+			}
+			return condition(obj)
+		}
+		return false, nil
+	})
+	if ctx.Err() != nil {
+		// Watches may be aborted early with a specific cause, e.g. when a pod fails.
+		if cause := context.Cause(ctx); cause != ctx.Err() {
+			return cause
+		}
+		return ErrDeploymentTimeout
+	}
+	return err
+}
+
+// rolloutStatus converts the outcome of waiting for a workload to roll out into a deployment status.
+// Workload strategies share these semantics: success once rolled out, error on timeout, and failure otherwise.
+func rolloutStatus(op *operation.Operation, resource unstructured.Unstructured, err error, trace trace.Span) *pb.DeploymentStatus {
+	switch {
+	case err == nil:
+		return pb.NewSuccessStatus(op.Request)
+	case err == ErrDeploymentTimeout:
+		return pb.NewErrorStatus(op.Request, ErrDeploymentTimeout)
+	default:
+		err = fmt.Errorf("%s/%s: %w", strings.ToLower(resource.GetKind()), resource.GetName(), err)
+		trace.AddEvent(err.Error())
+		return pb.NewFailureStatus(op.Request, err)
+	}
+}
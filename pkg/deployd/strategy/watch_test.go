@@ -0,0 +1,322 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/deployd/kubeclient"
+	"github.com/nais/deploy/pkg/deployd/operation"
+	"github.com/nais/deploy/pkg/pb"
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/nais/liberator/pkg/events"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+type fakeKubeClient struct {
+	kubernetes.Interface
+}
+
+var _ kubeclient.Interface = fakeKubeClient{}
+
+func (f fakeKubeClient) Kubernetes() kubernetes.Interface {
+	return f.Interface
+}
+
+func (f fakeKubeClient) ResourceInterface(resource *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f fakeKubeClient) Impersonate(team string) (kubeclient.Interface, error) {
+	return f, nil
+}
+
+func newTestOperation(timeout time.Duration) (*operation.Operation, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	return &operation.Operation{
+		Context: ctx,
+		Cancel:  cancel,
+		Logger:  log.NewEntry(log.StandardLogger()),
+		Request: &pb.DeploymentRequest{ID: "test"},
+	}, cancel
+}
+
+func watchResource(name, namespace string) unstructured.Unstructured {
+	resource := unstructured.Unstructured{}
+	resource.SetName(name)
+	resource.SetNamespace(namespace)
+	return resource
+}
+
+func testDeployment(replicas, available int32) *apps.Deployment {
+	return &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "aura", Generation: 2},
+		Spec:       apps.DeploymentSpec{Replicas: &replicas},
+		Status: apps.DeploymentStatus{
+			Replicas:           replicas,
+			UpdatedReplicas:    replicas,
+			AvailableReplicas:  available,
+			ObservedGeneration: 2,
+		},
+	}
+}
+
+func runWatch(t *testing.T, strategy WatchStrategy, timeout time.Duration) *pb.DeploymentStatus {
+	t.Helper()
+	op, cancel := newTestOperation(timeout)
+	defer cancel()
+	return strategy.Watch(op, watchResource("app", "aura"), trace.SpanFromContext(op.Context))
+}
+
+func TestDeploymentWatchComplete(t *testing.T) {
+	client := fakeKubeClient{fake.NewSimpleClientset(testDeployment(2, 2))}
+	status := runWatch(t, deployment{client: client}, 5*time.Second)
+
+	assert.Equal(t, pb.DeploymentState_success, status.GetState())
+}
+
+func TestDeploymentWatchUpdated(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testDeployment(2, 1))
+	watching := make(chan struct{})
+	once := sync.Once{}
+
+	// Signal once the watch has been established, so that the update is not lost.
+	clientset.PrependWatchReactor("deployments", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w, err := clientset.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		once.Do(func() { close(watching) })
+		return true, w, err
+	})
+
+	go func() {
+		<-watching
+		_, err := clientset.AppsV1().Deployments("aura").UpdateStatus(context.Background(), testDeployment(2, 2), metav1.UpdateOptions{})
+		assert.NoError(t, err)
+	}()
+
+	status := runWatch(t, deployment{client: fakeKubeClient{clientset}}, 5*time.Second)
+
+	assert.Equal(t, pb.DeploymentState_success, status.GetState())
+}
+
+func TestDeploymentWatchTimeout(t *testing.T) {
+	client := fakeKubeClient{fake.NewSimpleClientset(testDeployment(2, 1))}
+	status := runWatch(t, deployment{client: client}, 500*time.Millisecond)
+
+	assert.Equal(t, pb.DeploymentState_error, status.GetState())
+	assert.Equal(t, ErrDeploymentTimeout.Error(), status.GetMessage())
+}
+
+func TestJobWatch(t *testing.T) {
+	testJob := func(condition batch.JobConditionType) *batch.Job {
+		return &batch.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "aura"},
+			Status: batch.JobStatus{
+				Conditions: []batch.JobCondition{
+					{Type: condition, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"},
+				},
+			},
+		}
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		client := fakeKubeClient{fake.NewSimpleClientset(testJob(batch.JobComplete))}
+		status := runWatch(t, job{client: client}, 5*time.Second)
+		assert.Nil(t, status)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		client := fakeKubeClient{fake.NewSimpleClientset(testJob(batch.JobFailed))}
+		status := runWatch(t, job{client: client}, 5*time.Second)
+		assert.Equal(t, pb.DeploymentState_failure, status.GetState())
+		assert.Contains(t, status.GetMessage(), "job failed")
+	})
+}
+
+func TestStatefulSetComplete(t *testing.T) {
+	replicas := int32(3)
+	partition := int32(2)
+
+	statefulSet := func(mutate func(sts *apps.StatefulSet)) *apps.StatefulSet {
+		sts := &apps.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "aura", Generation: 2},
+			Spec: apps.StatefulSetSpec{
+				Replicas: &replicas,
+				UpdateStrategy: apps.StatefulSetUpdateStrategy{
+					Type: apps.RollingUpdateStatefulSetStrategyType,
+				},
+			},
+			Status: apps.StatefulSetStatus{
+				ObservedGeneration: 2,
+				ReadyReplicas:      3,
+				UpdatedReplicas:    3,
+				CurrentRevision:    "app-2",
+				UpdateRevision:     "app-2",
+			},
+		}
+		mutate(sts)
+		return sts
+	}
+
+	assert.True(t, statefulSetComplete(statefulSet(func(sts *apps.StatefulSet) {})))
+	assert.False(t, statefulSetComplete(statefulSet(func(sts *apps.StatefulSet) {
+		sts.Status.ObservedGeneration = 1
+	})))
+	assert.False(t, statefulSetComplete(statefulSet(func(sts *apps.StatefulSet) {
+		sts.Status.ReadyReplicas = 2
+	})))
+	assert.False(t, statefulSetComplete(statefulSet(func(sts *apps.StatefulSet) {
+		sts.Status.CurrentRevision = "app-1"
+	})))
+	assert.True(t, statefulSetComplete(statefulSet(func(sts *apps.StatefulSet) {
+		sts.Spec.UpdateStrategy.RollingUpdate = &apps.RollingUpdateStatefulSetStrategy{Partition: &partition}
+		sts.Status.UpdatedReplicas = 1
+		sts.Status.CurrentRevision = "app-1"
+	})))
+	assert.True(t, statefulSetComplete(statefulSet(func(sts *apps.StatefulSet) {
+		sts.Spec.UpdateStrategy.Type = apps.OnDeleteStatefulSetStrategyType
+		sts.Status.ReadyReplicas = 0
+	})))
+}
+
+func TestDaemonSetComplete(t *testing.T) {
+	daemonSet := func(mutate func(ds *apps.DaemonSet)) *apps.DaemonSet {
+		ds := &apps.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "aura", Generation: 2},
+			Spec: apps.DaemonSetSpec{
+				UpdateStrategy: apps.DaemonSetUpdateStrategy{
+					Type: apps.RollingUpdateDaemonSetStrategyType,
+				},
+			},
+			Status: apps.DaemonSetStatus{
+				ObservedGeneration:     2,
+				DesiredNumberScheduled: 4,
+				UpdatedNumberScheduled: 4,
+				NumberAvailable:        4,
+			},
+		}
+		mutate(ds)
+		return ds
+	}
+
+	assert.True(t, daemonSetComplete(daemonSet(func(ds *apps.DaemonSet) {})))
+	assert.False(t, daemonSetComplete(daemonSet(func(ds *apps.DaemonSet) {
+		ds.Status.ObservedGeneration = 1
+	})))
+	assert.False(t, daemonSetComplete(daemonSet(func(ds *apps.DaemonSet) {
+		ds.Status.UpdatedNumberScheduled = 3
+	})))
+	assert.False(t, daemonSetComplete(daemonSet(func(ds *apps.DaemonSet) {
+		ds.Status.NumberAvailable = 3
+	})))
+}
+
+func TestStatefulSetWatchTimeout(t *testing.T) {
+	replicas := int32(2)
+	sts := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "aura"},
+		Spec:       apps.StatefulSetSpec{Replicas: &replicas},
+	}
+	client := fakeKubeClient{fake.NewSimpleClientset(sts)}
+	status := runWatch(t, statefulSet{client: client}, 500*time.Millisecond)
+
+	assert.Equal(t, pb.DeploymentState_error, status.GetState())
+	assert.Equal(t, ErrDeploymentTimeout.Error(), status.GetMessage())
+}
+
+func TestDaemonSetWatchDeleted(t *testing.T) {
+	ds := &apps.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "aura"},
+		Status:     apps.DaemonSetStatus{DesiredNumberScheduled: 1},
+	}
+	clientset := fake.NewSimpleClientset(ds)
+	watching := make(chan struct{})
+	once := sync.Once{}
+	clientset.PrependWatchReactor("daemonsets", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w, err := clientset.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		once.Do(func() { close(watching) })
+		return true, w, err
+	})
+
+	go func() {
+		<-watching
+		err := clientset.AppsV1().DaemonSets("aura").Delete(context.Background(), "app", metav1.DeleteOptions{})
+		assert.NoError(t, err)
+	}()
+
+	resource := watchResource("app", "aura")
+	resource.SetKind("DaemonSet")
+	op, cancel := newTestOperation(5 * time.Second)
+	defer cancel()
+	status := daemonSet{client: fakeKubeClient{clientset}}.Watch(op, resource, trace.SpanFromContext(op.Context))
+
+	assert.Equal(t, pb.DeploymentState_failure, status.GetState())
+	assert.Equal(t, "daemonset/app: "+ErrResourceDeleted.Error(), status.GetMessage())
+}
+
+func TestNaisWatchResumed(t *testing.T) {
+	application := watchResource("app", "aura")
+	application.SetKind("Application")
+
+	completed := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app.rollout",
+			Namespace:   "aura",
+			Annotations: map[string]string{nais_io_v1.DeploymentCorrelationIDAnnotation: "test"},
+		},
+		InvolvedObject:      corev1.ObjectReference{Kind: "Application", Name: "app"},
+		Reason:              events.RolloutComplete,
+		ReportingController: "naiserator",
+		LastTimestamp:       metav1.NewTime(time.Now().Add(-time.Minute)),
+	}
+
+	t.Run("rollout completed before deployd restarted", func(t *testing.T) {
+		op, cancel := newTestOperation(5 * time.Second)
+		defer cancel()
+		op.Request.Resume = true
+		clientset := fake.NewSimpleClientset()
+		go func() {
+			// The API server replays existing events when a watch starts; the fake does not.
+			time.Sleep(100 * time.Millisecond)
+			_, _ = clientset.CoreV1().Events("aura").Create(context.Background(), completed, metav1.CreateOptions{})
+		}()
+		status := naisResource{client: fakeKubeClient{clientset}}.Watch(op, application, trace.SpanFromContext(op.Context))
+		assert.Equal(t, pb.DeploymentState_success, status.GetState())
+	})
+
+	t.Run("old events are ignored for new deployments", func(t *testing.T) {
+		op, cancel := newTestOperation(500 * time.Millisecond)
+		defer cancel()
+		clientset := fake.NewSimpleClientset()
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_, _ = clientset.CoreV1().Events("aura").Create(context.Background(), completed, metav1.CreateOptions{})
+		}()
+		status := naisResource{client: fakeKubeClient{clientset}}.Watch(op, application, trace.SpanFromContext(op.Context))
+		assert.Equal(t, pb.DeploymentState_error, status.GetState())
+	})
+
+	t.Run("status of live application", func(t *testing.T) {
+		live := &unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"correlationID":        "test",
+				"synchronizationState": events.RolloutComplete,
+			},
+		}}
+		assert.True(t, rolloutComplete(live, "test"))
+		assert.False(t, rolloutComplete(live, "other"))
+	})
+}
@@ -0,0 +1,113 @@
+package teamconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrBootstrap is returned when the team's namespace or service user could not be created.
+var ErrBootstrap = errors.New("unable to prepare team in cluster")
+
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+type BootstrapOptions struct {
+	// Create the team's self-named namespace.
+	Namespace bool
+	// Create the `serviceuser-TEAM` service account, and bind it to ClusterRole within the team namespace.
+	ServiceAccount bool
+	ClusterRole    string
+}
+
+// Bootstrap ensures that the resources needed to impersonate the team exist in the cluster.
+// Existing resources are left untouched.
+func Bootstrap(ctx context.Context, client kubernetes.Interface, team string, opts BootstrapOptions) error {
+	labels := map[string]string{
+		managedByLabel: "deployd",
+	}
+
+	if opts.Namespace {
+		namespace := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   team,
+				Labels: labels,
+			},
+		}
+		err := ensure(ctx, client.CoreV1().Namespaces().Get, client.CoreV1().Namespaces().Create, namespace)
+		if err != nil {
+			return fmt.Errorf("%w: namespace %q: %s", ErrBootstrap, team, err)
+		}
+	}
+
+	if !opts.ServiceAccount {
+		return nil
+	}
+
+	name := serviceAccountName(team)
+
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: team,
+			Labels:    labels,
+		},
+	}
+	serviceAccounts := client.CoreV1().ServiceAccounts(team)
+	err := ensure(ctx, serviceAccounts.Get, serviceAccounts.Create, serviceAccount)
+	if err != nil {
+		return fmt.Errorf("%w: service account %q: %s", ErrBootstrap, name, err)
+	}
+
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: team,
+			Labels:    labels,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      name,
+				Namespace: team,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     opts.ClusterRole,
+		},
+	}
+	roleBindings := client.RbacV1().RoleBindings(team)
+	err = ensure(ctx, roleBindings.Get, roleBindings.Create, roleBinding)
+	if err != nil {
+		return fmt.Errorf("%w: role binding %q: %s", ErrBootstrap, name, err)
+	}
+
+	return nil
+}
+
+type getFunc[T metav1.Object] func(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+type createFunc[T metav1.Object] func(ctx context.Context, obj T, opts metav1.CreateOptions) (T, error)
+
+// Create the object unless it already exists.
+// Concurrent deployments for the same team may race to create it, so conflicts are not errors.
+func ensure[T metav1.Object](ctx context.Context, get getFunc[T], create createFunc[T], obj T) error {
+	_, err := get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err == nil {
+		return nil
+	} else if !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	_, err = create(ctx, obj, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
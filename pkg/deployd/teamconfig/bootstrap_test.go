@@ -0,0 +1,78 @@
+package teamconfig_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nais/deploy/pkg/deployd/teamconfig"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var allOptions = teamconfig.BootstrapOptions{
+	Namespace:      true,
+	ServiceAccount: true,
+	ClusterRole:    "admin",
+}
+
+func TestBootstrap(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+
+	err := teamconfig.Bootstrap(ctx, client, "aura", allOptions)
+	assert.NoError(t, err)
+
+	_, err = client.CoreV1().Namespaces().Get(ctx, "aura", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	_, err = client.CoreV1().ServiceAccounts("aura").Get(ctx, "serviceuser-aura", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	roleBinding, err := client.RbacV1().RoleBindings("aura").Get(ctx, "serviceuser-aura", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", roleBinding.RoleRef.Name)
+	assert.Equal(t, "serviceuser-aura", roleBinding.Subjects[0].Name)
+	assert.Equal(t, "aura", roleBinding.Subjects[0].Namespace)
+
+	// Running again is a no-op.
+	err = teamconfig.Bootstrap(ctx, client, "aura", allOptions)
+	assert.NoError(t, err)
+}
+
+func TestBootstrapKeepsExistingResources(t *testing.T) {
+	ctx := context.Background()
+	existing := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "serviceuser-aura",
+			Namespace: "aura",
+			Labels:    map[string]string{"team": "aura"},
+		},
+	}
+	client := fake.NewSimpleClientset(existing)
+
+	err := teamconfig.Bootstrap(ctx, client, "aura", teamconfig.BootstrapOptions{ServiceAccount: true, ClusterRole: "admin"})
+	assert.NoError(t, err)
+
+	serviceAccount, err := client.CoreV1().ServiceAccounts("aura").Get(ctx, "serviceuser-aura", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, existing.Labels, serviceAccount.Labels)
+
+	_, err = client.CoreV1().Namespaces().Get(ctx, "aura", metav1.GetOptions{})
+	assert.Error(t, err, "namespace is not created unless requested")
+}
+
+func TestBootstrapError(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "rolebindings", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+
+	err := teamconfig.Bootstrap(context.Background(), client, "aura", allOptions)
+	assert.ErrorIs(t, err, teamconfig.ErrBootstrap)
+	assert.EqualError(t, err, `unable to prepare team in cluster: role binding "serviceuser-aura": forbidden`)
+}
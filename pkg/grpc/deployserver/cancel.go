@@ -0,0 +1,50 @@
+package deployserver
+
+import (
+	"context"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	database_mapper "github.com/nais/deploy/pkg/hookd/database/mapper"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Cancel forwards a cancellation to the deployd instance running the deployment, and returns the last known status.
+// The deployment is reported as cancelled by deployd once it has stopped.
+func (ds *deployServer) Cancel(ctx context.Context, request *pb.DeploymentRequest) (*pb.DeploymentStatus, error) {
+	logger := log.WithFields(request.LogFields())
+
+	deployment, err := ds.teamDeployment(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := ds.deploymentStore.DeploymentStatus(ctx, deployment.ID)
+	if err != nil && !database.IsErrNotFound(err) {
+		logger.Error(err)
+		return nil, ErrDatabaseUnavailable
+	}
+
+	req := database_mapper.PbRequest(*deployment)
+	current := pb.NewQueuedStatus(req)
+	if len(statuses) > 0 {
+		current = database_mapper.PbStatus(statuses[0])
+		current.Request = req
+	}
+
+	if current.GetState().Finished() {
+		return nil, status.Errorf(codes.FailedPrecondition, "deployment has already finished with state %s", current.GetState())
+	}
+
+	err = ds.dispatchServer.CancelDeployment(ctx, req)
+	if err != nil {
+		logger.Errorf("Dispatch cancellation: %s", err)
+		return nil, err
+	}
+
+	logger.Infof("Cancellation of deployment requested")
+
+	return current, nil
+}
@@ -0,0 +1,58 @@
+package deployserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/grpc/dispatchserver"
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestCancel(t *testing.T) {
+	cluster := "dev"
+	deployment := &database.Deployment{ID: "1", Team: "aura", Cluster: &cluster, Created: time.Now()}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("team", "aura"))
+
+	setup := func(t *testing.T, state string) (pb.DeployServer, *dispatchserver.MockDispatchServer) {
+		store := database.NewMockDeploymentStore(t)
+		dispatch := dispatchserver.NewMockDispatchServer(t)
+		store.On("Deployment", mock.Anything, "1").Return(deployment, nil)
+		store.On("DeploymentStatus", mock.Anything, "1").Return([]database.DeploymentStatus{
+			{DeploymentID: "1", Status: state, Created: time.Now()},
+		}, nil).Maybe()
+		return New(dispatch, store, nil, nil), dispatch
+	}
+
+	t.Run("running deployment is cancelled in its cluster", func(t *testing.T) {
+		server, dispatch := setup(t, "in_progress")
+		dispatch.On("CancelDeployment", mock.Anything, mock.MatchedBy(func(req *pb.DeploymentRequest) bool {
+			return req.GetID() == "1" && req.GetCluster() == "dev" && req.GetTeam() == "aura"
+		})).Return(nil)
+
+		st, err := server.Cancel(ctx, &pb.DeploymentRequest{ID: "1"})
+		assert.NoError(t, err)
+		assert.Equal(t, pb.DeploymentState_in_progress, st.GetState())
+	})
+
+	t.Run("finished deployment cannot be cancelled", func(t *testing.T) {
+		server, _ := setup(t, "success")
+
+		_, err := server.Cancel(ctx, &pb.DeploymentRequest{ID: "1"})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("deployment of another team is not found", func(t *testing.T) {
+		server, _ := setup(t, "in_progress")
+		otherTeam := metadata.NewIncomingContext(context.Background(), metadata.Pairs("team", "other"))
+
+		_, err := server.Cancel(otherTeam, &pb.DeploymentRequest{ID: "1", Team: "aura"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
@@ -2,6 +2,7 @@ package deployserver
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/nais/api/pkg/apiclient/protoapi"
@@ -12,10 +13,16 @@ import (
 	"github.com/nais/deploy/pkg/pb"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
-var ErrDatabaseUnavailable = status.Errorf(codes.Unavailable, "database is unavailable; try again later")
+var (
+	ErrDatabaseUnavailable = status.Errorf(codes.Unavailable, "database is unavailable; try again later")
+	ErrStatusStreamBehind  = status.Errorf(codes.Unavailable, "status stream fell behind; reconnect to resume after the last status received")
+)
 
 type deployServer struct {
 	pb.UnimplementedDeployServer
@@ -56,6 +63,9 @@ func (ds *deployServer) addToDatabase(ctx context.Context, request *pb.Deploymen
 		logger.Infof("Resource %d: %s", i+1, identifiers[i])
 	}
 
+	// A diff is a dry run that also reports what would change.
+	dryRun := request.GetDryRun() || request.GetDiff()
+
 	cluster := request.GetCluster()
 	deployment := database.Deployment{
 		ID:               request.GetID(),
@@ -63,15 +73,30 @@ func (ds *deployServer) addToDatabase(ctx context.Context, request *pb.Deploymen
 		Cluster:          &cluster,
 		Created:          pb.TimestampAsTime(request.GetTime()),
 		GitHubRepository: request.GetRepository().FullNamePtr(),
+		DryRun:           dryRun,
 	}
 
 	// Write deployment request to database
 	err = ds.deploymentStore.WriteDeployment(ctx, deployment)
 
 	if err == nil {
-		naisApiDeploymentID, err := ds.writeDeploymentToNaisApi(ctx, request, cluster)
-		if err != nil {
-			logger.WithError(err).Error("Write deployment to Nais API")
+		var naisApiDeploymentID *string
+
+		// Keep the request around, so that it can be resumed, and later deployments can roll back to this one.
+		if !dryRun {
+			err = ds.writePayload(ctx, request)
+			if err != nil {
+				logger.Error(err)
+				return ErrDatabaseUnavailable
+			}
+		}
+
+		// Dry runs never change anything in the cluster, and are not reported as deployments.
+		if !dryRun {
+			naisApiDeploymentID, err = ds.writeDeploymentToNaisApi(ctx, request, cluster)
+			if err != nil {
+				logger.WithError(err).Error("Write deployment to Nais API")
+			}
 		}
 
 		// Write metadata of Kubernetes resources to database
@@ -112,6 +137,23 @@ func (ds *deployServer) addToDatabase(ctx context.Context, request *pb.Deploymen
 	return nil
 }
 
+// writePayload stores the request as sent by the client, with the options hookd decided on.
+// Resources to roll back to or prune are left out, as they are looked up again when the request is resumed;
+// otherwise every payload would contain the one before it.
+func (ds *deployServer) writePayload(ctx context.Context, request *pb.DeploymentRequest) error {
+	stored := proto.Clone(request).(*pb.DeploymentRequest)
+	stored.Rollback = nil
+	stored.PruneResources = nil
+	stored.Resume = false
+	stored.Cancel = false
+
+	payload, err := protojson.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("encode deployment payload: %w", err)
+	}
+	return ds.deploymentStore.WriteDeploymentPayload(ctx, request.GetID(), payload, request.GetDeadline().AsTime())
+}
+
 func (ds *deployServer) writeDeploymentResourceToNaisApi(ctx context.Context, naisApiDeploymentID *string, meta k8sutils.Identifier) error {
 	_, err := ds.apiClient.CreateDeploymentK8SResource(ctx, protoapi.CreateDeploymentK8SResourceRequest_builder{
 		DeploymentId: naisApiDeploymentID,
@@ -166,6 +208,14 @@ func (ds *deployServer) Deploy(ctx context.Context, request *pb.DeploymentReques
 	}
 	request.ID = uuidstr
 
+	// Only hookd decides what deployd prunes, rolls back to or resumes.
+	request.PruneResources = nil
+	request.PruneDeploymentID = ""
+	request.Rollback = nil
+	request.RollbackDeploymentID = ""
+	request.Resume = false
+	request.Cancel = false
+
 	logger := log.WithFields(request.LogFields())
 	logger.Infof("Received deployment request")
 
@@ -177,6 +227,22 @@ func (ds *deployServer) Deploy(ctx context.Context, request *pb.DeploymentReques
 		}
 	}
 
+	if request.GetPrune() {
+		err = ds.addPruneResources(ctx, request)
+		if err != nil {
+			logger.Errorf("Find resources to prune: %s", err)
+			return nil, err
+		}
+	}
+
+	if request.GetRollbackOnFailure() {
+		err = ds.addRollback(ctx, request)
+		if err != nil {
+			logger.Errorf("Find deployment to roll back to: %s", err)
+			return nil, err
+		}
+	}
+
 	logger.Debugf("Writing deployment to database")
 	err = ds.addToDatabase(ctx, request)
 	if err != nil {
@@ -205,21 +271,35 @@ func (ds *deployServer) Status(request *pb.DeploymentRequest, server pb.Deploy_S
 	logger.Debugf("Status stream opened")
 	defer logger.Debugf("Status stream closed")
 
-	dbStatus, err := ds.deploymentStore.DeploymentStatus(server.Context(), request.GetID())
-	if err == nil && len(dbStatus) > 0 {
-		err = server.Send(database_mapper.PbStatus(dbStatus[0]))
-	}
+	_, err := ds.teamDeployment(server.Context(), request)
 	if err != nil {
 		return err
 	}
 
-	ch := make(chan *pb.DeploymentStatus, 16)
+	// Subscribe before reading stored statuses, so that nothing reported in between is lost.
+	ch := ds.dispatchServer.SubscribeStatus(server.Context(), request.GetID())
 
-	// Listen for status updates until context is closed
-	go ds.dispatchServer.StreamStatus(server.Context(), ch)
+	dbStatus, err := ds.deploymentStore.DeploymentStatus(server.Context(), request.GetID())
+	if err != nil && !database.IsErrNotFound(err) {
+		logger.Error(err)
+		return ErrDatabaseUnavailable
+	}
+
+	// Statuses that were stored when the stream opened have either been replayed, or are before the cursor.
+	stored := make(map[string]bool, len(dbStatus))
+	for _, st := range dbStatus {
+		stored[st.ID] = true
+	}
+
+	for _, st := range statusReplay(dbStatus, request) {
+		err = server.Send(database_mapper.PbStatus(st))
+		if err != nil {
+			return err
+		}
+	}
 
 	for st := range ch {
-		if st.GetRequest().GetID() != request.GetID() {
+		if stored[st.GetID()] {
 			continue
 		}
 		err := server.Send(st)
@@ -229,5 +309,83 @@ func (ds *deployServer) Status(request *pb.DeploymentRequest, server pb.Deploy_S
 			return err
 		}
 	}
+
+	// The subscription ends early if the client does not keep up with status updates.
+	if server.Context().Err() == nil {
+		logger.Warnf("Status stream fell behind and was closed")
+		return ErrStatusStreamBehind
+	}
+
 	return nil
 }
+
+// statusReplay returns the stored statuses that a status stream starts with, oldest first.
+// Stored statuses are ordered newest first.
+//
+// A stream resumes after the status given by ID, or after the given time if the ID is unknown.
+// If the cursor matches neither, every status is replayed, since a duplicate is better than a gap.
+// Without a cursor, only the latest status is sent, unless all of them are asked for.
+func statusReplay(stored []database.DeploymentStatus, request *pb.DeploymentRequest) []database.DeploymentStatus {
+	var replay []database.DeploymentStatus
+
+	sinceID := request.GetStatusSinceID()
+	sinceTime := request.GetStatusSinceTime()
+
+	switch {
+	case len(sinceID) > 0 || sinceTime != nil:
+		replay = stored
+		found := false
+		for i := range stored {
+			if len(sinceID) > 0 && stored[i].ID == sinceID {
+				replay = stored[:i]
+				found = true
+				break
+			}
+		}
+		if !found && sinceTime != nil {
+			since := pb.TimestampAsTime(sinceTime)
+			i := 0
+			for i < len(stored) && stored[i].Created.After(since) {
+				i++
+			}
+			replay = stored[:i]
+		}
+	case request.GetReplayStatus():
+		replay = stored
+	case len(stored) > 0:
+		replay = stored[:1]
+	}
+
+	oldestFirst := make([]database.DeploymentStatus, 0, len(replay))
+	for i := len(replay) - 1; i >= 0; i-- {
+		oldestFirst = append(oldestFirst, replay[i])
+	}
+	return oldestFirst
+}
+
+// teamDeployment looks up the deployment in the request, making sure that it belongs to the team making the request.
+// Other teams' deployments are indistinguishable from deployments that do not exist.
+func (ds *deployServer) teamDeployment(ctx context.Context, request *pb.DeploymentRequest) (*database.Deployment, error) {
+	deployment, err := ds.deploymentStore.Deployment(ctx, request.GetID())
+	if database.IsErrNotFound(err) {
+		return nil, status.Errorf(codes.NotFound, "deployment %q not found", request.GetID())
+	} else if err != nil {
+		log.WithFields(request.LogFields()).Error(err)
+		return nil, ErrDatabaseUnavailable
+	}
+
+	if deployment.Team != requestTeam(ctx, request) {
+		return nil, status.Errorf(codes.NotFound, "deployment %q not found", request.GetID())
+	}
+
+	return deployment, nil
+}
+
+// The team in the request metadata is the one that has been authenticated, so it takes precedence over the request body.
+func requestTeam(ctx context.Context, request interface{ GetTeam() string }) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok && len(md.Get("team")) == 1 {
+		return md.Get("team")[0]
+	}
+	return request.GetTeam()
+}
@@ -0,0 +1,224 @@
+package deployserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/grpc/dispatchserver"
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusReplay(t *testing.T) {
+	cluster := "dev"
+	now := time.Now()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("team", "aura"))
+
+	// Stored statuses are returned newest first.
+	statuses := []database.DeploymentStatus{
+		{ID: "c", DeploymentID: "1", Status: "in_progress", Message: "third", Created: now},
+		{ID: "b", DeploymentID: "1", Status: "in_progress", Message: "second", Created: now.Add(-time.Second)},
+		{ID: "a", DeploymentID: "1", Status: "queued", Message: "first", Created: now.Add(-2 * time.Second)},
+	}
+
+	// Reported while the stream was opening; the first one was also stored in time to be read from the database.
+	live := []*pb.DeploymentStatus{
+		{ID: "c", Request: &pb.DeploymentRequest{ID: "1"}, Message: "third"},
+		{ID: "d", Request: &pb.DeploymentRequest{ID: "1"}, Message: "fourth"},
+	}
+
+	for _, tt := range []struct {
+		name     string
+		request  *pb.DeploymentRequest
+		expected []string
+	}{
+		{
+			name:     "latest status only",
+			request:  &pb.DeploymentRequest{ID: "1"},
+			expected: []string{"third", "fourth"},
+		},
+		{
+			name:     "full history in order",
+			request:  &pb.DeploymentRequest{ID: "1", ReplayStatus: true},
+			expected: []string{"first", "second", "third", "fourth"},
+		},
+		{
+			name:     "resume after status ID",
+			request:  &pb.DeploymentRequest{ID: "1", StatusSinceID: "a", ReplayStatus: true},
+			expected: []string{"second", "third", "fourth"},
+		},
+		{
+			name:     "resume after time when status ID is unknown",
+			request:  &pb.DeploymentRequest{ID: "1", StatusSinceID: "unknown", StatusSinceTime: pb.TimeAsTimestamp(now.Add(-time.Second))},
+			expected: []string{"third", "fourth"},
+		},
+		{
+			name:     "unknown cursor replays everything",
+			request:  &pb.DeploymentRequest{ID: "1", StatusSinceID: "unknown"},
+			expected: []string{"first", "second", "third", "fourth"},
+		},
+		{
+			name:     "resume after latest status",
+			request:  &pb.DeploymentRequest{ID: "1", StatusSinceID: "c"},
+			expected: []string{"fourth"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store := database.NewMockDeploymentStore(t)
+			store.On("Deployment", mock.Anything, "1").Return(&database.Deployment{ID: "1", Team: "aura", Cluster: &cluster}, nil)
+			store.On("DeploymentStatus", mock.Anything, "1").Return(statuses, nil)
+
+			dispatch := dispatchserver.NewMockDispatchServer(t)
+			ch := make(chan *pb.DeploymentStatus, len(live))
+			for _, st := range live {
+				ch <- st
+			}
+			close(ch)
+			dispatch.On("SubscribeStatus", mock.Anything, "1").Return((<-chan *pb.DeploymentStatus)(ch))
+
+			// The client hangs up after the live statuses.
+			ctx, cancel := context.WithCancel(ctx)
+			cancel()
+
+			sent := make([]string, 0)
+			stream := pb.NewMockDeploy_StatusServer(t)
+			stream.On("Context").Return(ctx)
+			stream.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+				sent = append(sent, args.Get(0).(*pb.DeploymentStatus).GetMessage())
+			}).Return(nil)
+
+			server := New(dispatch, store, nil, nil)
+			err := server.Status(tt.request, stream)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, sent)
+		})
+	}
+}
+
+func TestStatusStreamBehind(t *testing.T) {
+	cluster := "dev"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("team", "aura"))
+
+	store := database.NewMockDeploymentStore(t)
+	store.On("Deployment", mock.Anything, "1").Return(&database.Deployment{ID: "1", Team: "aura", Cluster: &cluster}, nil)
+	store.On("DeploymentStatus", mock.Anything, "1").Return(nil, database.ErrNotFound)
+
+	// The subscription is closed while the client is still connected.
+	ch := make(chan *pb.DeploymentStatus)
+	close(ch)
+	dispatch := dispatchserver.NewMockDispatchServer(t)
+	dispatch.On("SubscribeStatus", mock.Anything, "1").Return((<-chan *pb.DeploymentStatus)(ch))
+
+	stream := pb.NewMockDeploy_StatusServer(t)
+	stream.On("Context").Return(ctx)
+
+	server := New(dispatch, store, nil, nil)
+	err := server.Status(&pb.DeploymentRequest{ID: "1"}, stream)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestDiffIsStoredAsDryRun(t *testing.T) {
+	kube, err := pb.KubernetesFromJSONResources([]byte(`[{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"config","namespace":"aura"}}]`))
+	assert.NoError(t, err)
+
+	db := database.NewMockDeploymentStore(t)
+	db.On("WriteDeployment", mock.Anything, mock.MatchedBy(func(deployment database.Deployment) bool {
+		return deployment.DryRun
+	})).Return(nil)
+	db.On("WriteDeploymentResource", mock.Anything, mock.Anything).Return(nil)
+
+	// Neither the payload nor the Nais API is written to, as nothing is deployed.
+	ds := &deployServer{deploymentStore: db}
+	err = ds.addToDatabase(context.Background(), &pb.DeploymentRequest{ID: "1", Cluster: "dev", Diff: true, Kubernetes: kube})
+	assert.NoError(t, err)
+}
+
+func TestDeployIgnoresServerFields(t *testing.T) {
+	kube, err := pb.KubernetesFromJSONResources([]byte(`[{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"config","namespace":"aura"}}]`))
+	assert.NoError(t, err)
+
+	db := database.NewMockDeploymentStore(t)
+	db.On("WriteDeployment", mock.Anything, mock.Anything).Return(nil)
+	db.On("WriteDeploymentResource", mock.Anything, mock.Anything).Return(nil)
+
+	dispatch := dispatchserver.NewMockDispatchServer(t)
+	dispatch.On("SendDeploymentRequest", mock.Anything, mock.MatchedBy(func(request *pb.DeploymentRequest) bool {
+		return len(request.GetPruneResources()) == 0 &&
+			len(request.GetPruneDeploymentID()) == 0 &&
+			request.GetRollback() == nil &&
+			len(request.GetRollbackDeploymentID()) == 0 &&
+			!request.GetResume() &&
+			!request.GetCancel()
+	})).Return(nil)
+	dispatch.On("HandleDeploymentStatus", mock.Anything, mock.Anything).Return(nil)
+
+	server := New(dispatch, db, nil, nil)
+	_, err = server.Deploy(context.Background(), &pb.DeploymentRequest{
+		Cluster:              "dev",
+		DryRun:               true,
+		Kubernetes:           kube,
+		PruneResources:       []*pb.ResourceIdentifier{{Version: "v1", Kind: "ConfigMap", Name: "other", Namespace: "aura"}},
+		PruneDeploymentID:    "previous",
+		Rollback:             kube,
+		RollbackDeploymentID: "previous",
+		Resume:               true,
+		Cancel:               true,
+	})
+	assert.NoError(t, err)
+}
+
+func TestStoredPayloadLeavesOutLookedUpResources(t *testing.T) {
+	kube, err := pb.KubernetesFromJSONResources([]byte(`[{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"config","namespace":"aura"}}]`))
+	assert.NoError(t, err)
+
+	db := database.NewMockDeploymentStore(t)
+	db.On("WriteDeploymentPayload", mock.Anything, "1", mock.MatchedBy(func(payload []byte) bool {
+		stored, err := pb.RequestFromPayload(payload)
+		return err == nil &&
+			stored.GetRollback() == nil &&
+			len(stored.GetPruneResources()) == 0 &&
+			stored.GetRollbackDeploymentID() == "previous" &&
+			stored.GetPruneDeploymentID() == "previous" &&
+			len(stored.GetKubernetes().GetResources()) == 1
+	}), mock.Anything).Return(nil)
+
+	request := &pb.DeploymentRequest{
+		ID:                   "1",
+		Kubernetes:           kube,
+		Rollback:             kube,
+		RollbackDeploymentID: "previous",
+		PruneResources:       []*pb.ResourceIdentifier{{Version: "v1", Kind: "ConfigMap", Name: "removed", Namespace: "aura"}},
+		PruneDeploymentID:    "previous",
+	}
+	ds := &deployServer{deploymentStore: db}
+	err = ds.writePayload(context.Background(), request)
+	assert.NoError(t, err)
+	assert.NotNil(t, request.GetRollback(), "the request sent to deployd is left as is")
+}
+
+func TestRollbackWithoutPreviousDeployment(t *testing.T) {
+	kube, err := pb.KubernetesFromJSONResources([]byte(`[{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"config","namespace":"aura"}}]`))
+	assert.NoError(t, err)
+
+	db := database.NewMockDeploymentStore(t)
+	db.On("LastSuccessfulDeployment", mock.Anything, "navikt/app", "dev").Return(nil, database.ErrNotFound)
+
+	request := &pb.DeploymentRequest{
+		Cluster:              "dev",
+		Repository:           &pb.GithubRepository{Owner: "navikt", Name: "app"},
+		RollbackOnFailure:    true,
+		Rollback:             kube,
+		RollbackDeploymentID: "chosen-by-client",
+	}
+	ds := &deployServer{deploymentStore: db}
+	err = ds.addRollback(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Nil(t, request.GetRollback())
+	assert.Empty(t, request.GetRollbackDeploymentID())
+}
@@ -0,0 +1,146 @@
+package deployserver
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	database_mapper "github.com/nais/deploy/pkg/hookd/database/mapper"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// ListDeployments returns a page of the team's deployments, newest first.
+func (ds *deployServer) ListDeployments(ctx context.Context, request *pb.ListDeploymentsRequest) (*pb.ListDeploymentsResponse, error) {
+	filter := database.DeploymentFilter{
+		Team:       requestTeam(ctx, request),
+		Cluster:    request.GetCluster(),
+		Repository: request.GetRepository(),
+		Limit:      int(request.GetLimit()),
+	}
+
+	if len(filter.Team) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "team is required")
+	}
+
+	for _, state := range request.GetStates() {
+		filter.States = append(filter.States, state.String())
+	}
+	if request.GetSince() != nil {
+		filter.Since = pb.TimestampAsTime(request.GetSince())
+	}
+	if request.GetUntil() != nil {
+		filter.Until = pb.TimestampAsTime(request.GetUntil())
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	} else if filter.Limit > MaxListLimit {
+		filter.Limit = MaxListLimit
+	}
+
+	if len(request.GetCursor()) > 0 {
+		cursor, err := decodeCursor(request.GetCursor())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid cursor: %s", err)
+		}
+		filter.After = cursor
+	}
+
+	// Fetch one more than requested, to find out if there is another page.
+	limit := filter.Limit
+	filter.Limit++
+
+	deployments, err := ds.deploymentStore.DeploymentHistory(ctx, filter)
+	if err != nil {
+		log.WithField("team", filter.Team).Errorf("List deployments: %s", err)
+		return nil, ErrDatabaseUnavailable
+	}
+
+	response := &pb.ListDeploymentsResponse{}
+	if len(deployments) > limit {
+		deployments = deployments[:limit]
+		last := deployments[limit-1]
+		response.NextCursor = encodeCursor(database.DeploymentCursor{
+			Created: last.Created,
+			ID:      last.ID,
+		})
+	}
+
+	for _, deployment := range deployments {
+		response.Deployments = append(response.Deployments, database_mapper.PbDeployment(*deployment))
+	}
+
+	return response, nil
+}
+
+// GetDeployment returns a single deployment of the team, along with its statuses and resources.
+func (ds *deployServer) GetDeployment(ctx context.Context, request *pb.DeploymentRequest) (*pb.Deployment, error) {
+	logger := log.WithFields(request.LogFields())
+
+	deployment, err := ds.teamDeployment(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := ds.deploymentStore.DeploymentStatus(ctx, deployment.ID)
+	if err != nil && !database.IsErrNotFound(err) {
+		logger.Error(err)
+		return nil, ErrDatabaseUnavailable
+	}
+
+	resources, err := ds.deploymentStore.DeploymentResources(ctx, deployment.ID)
+	if err != nil && !database.IsErrNotFound(err) {
+		logger.Error(err)
+		return nil, ErrDatabaseUnavailable
+	}
+
+	result := database_mapper.PbDeployment(*deployment)
+	for _, st := range statuses {
+		result.Statuses = append(result.Statuses, database_mapper.PbStatus(st))
+	}
+	for _, resource := range resources {
+		result.Resources = append(result.Resources, database_mapper.PbResource(resource))
+	}
+
+	return result, nil
+}
+
+// Cursors are opaque to clients, and consist of the creation time and ID of the last deployment on a page.
+func encodeCursor(cursor database.DeploymentCursor) string {
+	raw := fmt.Sprintf("%d/%s", cursor.Created.UnixNano(), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*database.DeploymentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), "/")
+	if !ok || len(id) == 0 {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	return &database.DeploymentCursor{
+		Created: time.Unix(0, n),
+		ID:      id,
+	}, nil
+}
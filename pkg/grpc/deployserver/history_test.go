@@ -0,0 +1,110 @@
+package deployserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestListDeployments(t *testing.T) {
+	now := time.Now()
+	repository := "navikt/example"
+	success := "success"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("team", "aura"))
+
+	deployments := []*database.Deployment{
+		{ID: "3", Team: "aura", Created: now, GitHubRepository: &repository, State: &success},
+		{ID: "2", Team: "aura", Created: now.Add(-time.Minute), GitHubRepository: &repository, State: &success},
+		{ID: "1", Team: "aura", Created: now.Add(-2 * time.Minute), GitHubRepository: &repository, State: &success},
+	}
+
+	t.Run("filters are passed on and the team is taken from metadata", func(t *testing.T) {
+		store := database.NewMockDeploymentStore(t)
+		store.On("DeploymentHistory", mock.Anything, mock.MatchedBy(func(filter database.DeploymentFilter) bool {
+			return filter.Team == "aura" &&
+				filter.Repository == repository &&
+				assert.ObjectsAreEqual([]string{"success", "failure"}, filter.States) &&
+				filter.Since.Equal(now.Add(-time.Hour)) &&
+				filter.Until.IsZero() &&
+				filter.After == nil &&
+				filter.Limit == DefaultListLimit+1
+		})).Return(deployments, nil)
+
+		server := New(nil, store, nil, nil)
+		response, err := server.ListDeployments(ctx, &pb.ListDeploymentsRequest{
+			Team:       "other",
+			Repository: repository,
+			States:     []pb.DeploymentState{pb.DeploymentState_success, pb.DeploymentState_failure},
+			Since:      pb.TimeAsTimestamp(now.Add(-time.Hour)),
+		})
+		assert.NoError(t, err)
+		assert.Len(t, response.GetDeployments(), 3)
+		assert.Empty(t, response.GetNextCursor())
+		assert.Equal(t, "navikt", response.GetDeployments()[0].GetRequest().GetRepository().GetOwner())
+		assert.Equal(t, pb.DeploymentState_success, response.GetDeployments()[0].GetState())
+	})
+
+	t.Run("cursor continues after the last deployment on the page", func(t *testing.T) {
+		store := database.NewMockDeploymentStore(t)
+		store.On("DeploymentHistory", mock.Anything, mock.MatchedBy(func(filter database.DeploymentFilter) bool {
+			return filter.After == nil && filter.Limit == 3
+		})).Return(deployments, nil)
+
+		server := New(nil, store, nil, nil)
+		response, err := server.ListDeployments(ctx, &pb.ListDeploymentsRequest{Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, response.GetDeployments(), 2)
+		assert.NotEmpty(t, response.GetNextCursor())
+
+		store.On("DeploymentHistory", mock.Anything, mock.MatchedBy(func(filter database.DeploymentFilter) bool {
+			return filter.After != nil && filter.After.ID == "2" && filter.After.Created.Equal(deployments[1].Created)
+		})).Return(deployments[2:], nil)
+
+		response, err = server.ListDeployments(ctx, &pb.ListDeploymentsRequest{Limit: 2, Cursor: response.GetNextCursor()})
+		assert.NoError(t, err)
+		assert.Len(t, response.GetDeployments(), 1)
+		assert.Empty(t, response.GetNextCursor())
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		server := New(nil, database.NewMockDeploymentStore(t), nil, nil)
+		_, err := server.ListDeployments(ctx, &pb.ListDeploymentsRequest{Cursor: "not a cursor"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestGetDeployment(t *testing.T) {
+	cluster := "dev"
+	now := time.Now()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("team", "aura"))
+
+	store := database.NewMockDeploymentStore(t)
+	store.On("Deployment", mock.Anything, "1").Return(&database.Deployment{ID: "1", Team: "aura", Cluster: &cluster, Created: now}, nil)
+	store.On("DeploymentStatus", mock.Anything, "1").Return([]database.DeploymentStatus{
+		{DeploymentID: "1", Status: "in_progress", Message: "second", Created: now},
+		{DeploymentID: "1", Status: "queued", Message: "first", Created: now.Add(-time.Second)},
+	}, nil).Maybe()
+	store.On("DeploymentResources", mock.Anything, "1").Return([]database.DeploymentResource{
+		{DeploymentID: "1", Kind: "Application", Name: "example", Namespace: "aura"},
+	}, nil).Maybe()
+
+	server := New(nil, store, nil, nil)
+
+	deployment, err := server.GetDeployment(ctx, &pb.DeploymentRequest{ID: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "dev", deployment.GetRequest().GetCluster())
+	assert.Equal(t, []string{"second", "first"}, []string{deployment.GetStatuses()[0].GetMessage(), deployment.GetStatuses()[1].GetMessage()})
+	assert.Equal(t, "example", deployment.GetResources()[0].GetName())
+
+	otherTeam := metadata.NewIncomingContext(context.Background(), metadata.Pairs("team", "other"))
+	_, err = server.GetDeployment(otherTeam, &pb.DeploymentRequest{ID: "1", Team: "aura"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
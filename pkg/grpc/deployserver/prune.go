@@ -0,0 +1,74 @@
+package deployserver
+
+import (
+	"context"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	database_mapper "github.com/nais/deploy/pkg/hookd/database/mapper"
+	"github.com/nais/deploy/pkg/k8sutils"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// addPruneResources finds resources that were part of the last successful deployment
+// from the same repository into the same cluster, but are missing from this request.
+// deployd deletes these resources after the deployment has been rolled out successfully.
+func (ds *deployServer) addPruneResources(ctx context.Context, request *pb.DeploymentRequest) error {
+	logger := log.WithFields(request.LogFields())
+
+	repository := request.GetRepository().FullNamePtr()
+	if repository == nil {
+		return status.Errorf(codes.InvalidArgument, "pruning requires the repository to be specified")
+	}
+
+	resources, err := k8sutils.ResourcesFromDeploymentRequest(request)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid Kubernetes resources in request: %s", err)
+	}
+
+	previous, err := ds.previousDeployment(ctx, request, *repository)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		logger.Infof("No previous successful deployment; nothing to prune")
+		return nil
+	}
+
+	previousResources, err := ds.deploymentStore.DeploymentResources(ctx, previous.ID)
+	if err != nil {
+		logger.Error(err)
+		return ErrDatabaseUnavailable
+	}
+
+	request.PruneDeploymentID = previous.ID
+	request.PruneResources = database_mapper.PruneCandidates(previousResources, k8sutils.Identifiers(resources))
+	for _, id := range request.PruneResources {
+		logger.Infof("Resource will be pruned: %s/%s in namespace %q", id.GetKind(), id.GetName(), id.GetNamespace())
+	}
+
+	return nil
+}
+
+// previousDeployment returns the last successful deployment from a repository into the same cluster,
+// or nil if there is none. Deployments made by other teams are disregarded.
+func (ds *deployServer) previousDeployment(ctx context.Context, request *pb.DeploymentRequest, repository string) (*database.Deployment, error) {
+	logger := log.WithFields(request.LogFields())
+
+	previous, err := ds.deploymentStore.LastSuccessfulDeployment(ctx, repository, request.GetCluster())
+	if database.IsErrNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		logger.Error(err)
+		return nil, ErrDatabaseUnavailable
+	}
+
+	if previous.Team != request.GetTeam() {
+		logger.Warnf("Previous deployment %s belongs to team %q; disregarding", previous.ID, previous.Team)
+		return nil, nil
+	}
+
+	return previous, nil
+}
@@ -0,0 +1,56 @@
+package deployserver
+
+import (
+	"context"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// addRollback attaches the resources of the last successful deployment from the same repository into the same cluster,
+// so that deployd can re-apply them if this deployment fails.
+func (ds *deployServer) addRollback(ctx context.Context, request *pb.DeploymentRequest) error {
+	logger := log.WithFields(request.LogFields())
+
+	// Nothing is rolled back to unless a previous deployment is found below.
+	request.Rollback = nil
+	request.RollbackDeploymentID = ""
+
+	repository := request.GetRepository().FullNamePtr()
+	if repository == nil {
+		return status.Errorf(codes.InvalidArgument, "rollback on failure requires the repository to be specified")
+	}
+
+	previous, err := ds.previousDeployment(ctx, request, *repository)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		logger.Infof("No previous successful deployment; nothing to roll back to")
+		return nil
+	}
+
+	payload, err := ds.deploymentStore.DeploymentPayload(ctx, previous.ID)
+	if database.IsErrNotFound(err) {
+		logger.Infof("Resources of previous deployment %s are not stored; nothing to roll back to", previous.ID)
+		return nil
+	} else if err != nil {
+		logger.Error(err)
+		return ErrDatabaseUnavailable
+	}
+
+	previousRequest, err := pb.RequestFromPayload(payload)
+	if err != nil {
+		logger.Errorf("Decode resources of previous deployment %s: %s", previous.ID, err)
+		return nil
+	}
+
+	request.Rollback = previousRequest.GetKubernetes()
+	request.RollbackDeploymentID = previous.ID
+	logger.Infof("Deployment will be rolled back to %s on failure", previous.ID)
+
+	return nil
+}
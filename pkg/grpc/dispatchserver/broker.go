@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/nais/api/pkg/apiclient/protoapi"
 	"github.com/nais/deploy/pkg/hookd/database"
 	database_mapper "github.com/nais/deploy/pkg/hookd/database/mapper"
@@ -19,13 +20,6 @@ import (
 )
 
 func (s *dispatchServer) SendDeploymentRequest(ctx context.Context, request *pb.DeploymentRequest) error {
-	s.onlineClustersLock.RLock()
-	c, online := s.onlineClustersMap[request.Cluster]
-	s.onlineClustersLock.RUnlock()
-	if !online {
-		return status.Errorf(codes.Unavailable, "cluster '%s' is offline", request.Cluster)
-	}
-
 	ctx = telemetry.WithTraceParent(ctx, request.TraceParent)
 	s.traceSpansLock.Lock()
 	ctx, span := telemetry.Tracer().Start(ctx, "Deploy", otrace.WithSpanKind(otrace.SpanKindServer))
@@ -33,14 +27,12 @@ func (s *dispatchServer) SendDeploymentRequest(ctx context.Context, request *pb.
 	request.TraceParent = telemetry.TraceParentHeader(ctx)
 	s.traceSpansLock.Unlock()
 
-	wait := make(chan error, 1)
-	c <- &requestWithWait{request: request, wait: wait}
-	if err := <-wait; err != nil {
+	if err := s.send(ctx, request); err != nil {
 		span.End()
 		s.traceSpansLock.Lock()
 		delete(s.traceSpans, request.ID)
 		s.traceSpansLock.Unlock()
-		return fmt.Errorf("send deployment request: %w", err)
+		return err
 	}
 
 	log.WithFields(request.LogFields()).Debugf("Deployment request sent to deployd")
@@ -48,39 +40,129 @@ func (s *dispatchServer) SendDeploymentRequest(ctx context.Context, request *pb.
 	return nil
 }
 
+func (s *dispatchServer) CancelDeployment(ctx context.Context, request *pb.DeploymentRequest) error {
+	cancellation := &pb.DeploymentRequest{
+		ID:      request.GetID(),
+		Team:    request.GetTeam(),
+		Cluster: request.GetCluster(),
+		Cancel:  true,
+	}
+
+	if err := s.send(ctx, cancellation); err != nil {
+		return err
+	}
+
+	log.WithFields(request.LogFields()).Debugf("Deployment cancellation sent to deployd")
+
+	return nil
+}
+
+// Send a request on the deployment stream of the request's cluster.
+// If the active deployd instance is connected to another hookd replica, the request is forwarded to that replica.
+func (s *dispatchServer) send(ctx context.Context, request *pb.DeploymentRequest) error {
+	conns := s.targets(request)
+	r := s.replica.Load()
+	if r == nil || s.activeConnection(request.GetCluster()) != nil {
+		if len(conns) == 0 {
+			return status.Errorf(codes.Unavailable, "cluster '%s' is offline", request.Cluster)
+		}
+		return s.deliverAll(ctx, conns, request)
+	}
+
+	// Cancellations also go to the instances standing by here, as they may still be finishing the deployment.
+	// Reaching any instance is enough for a cancellation to succeed.
+	if len(conns) > 0 && s.deliverAll(ctx, conns, request) == nil {
+		err := s.forward(ctx, r, request)
+		if err != nil {
+			log.WithFields(request.LogFields()).Debugf("Forward deployment cancellation to other hookd replicas: %s", err)
+		}
+		return nil
+	}
+
+	return s.forward(ctx, r, request)
+}
+
+// deliverAll sends a request on several deployment streams, and succeeds if at least one of them got it.
+func (s *dispatchServer) deliverAll(ctx context.Context, conns []*connection, request *pb.DeploymentRequest) error {
+	var err error
+	delivered := false
+	for _, conn := range conns {
+		connErr := s.deliver(ctx, conn, request)
+		if connErr != nil {
+			conn.logger().WithFields(request.LogFields()).Errorf("Send deployment request: %s", connErr)
+			err = connErr
+			continue
+		}
+		delivered = true
+	}
+
+	if delivered {
+		return nil
+	}
+	return err
+}
+
+// deliver a request on a deployment stream connected to this replica.
+func (s *dispatchServer) deliver(ctx context.Context, conn *connection, request *pb.DeploymentRequest) error {
+	// The deployd stream may go away while the request is waiting to be picked up, e.g. when hookd is draining.
+	wait := make(chan error, 1)
+	select {
+	case conn.requests <- &requestWithWait{request: request, wait: wait}:
+	case <-conn.ctx.Done():
+		wait <- fmt.Errorf("deployd instance '%s' disconnected", conn.instance)
+	case <-ctx.Done():
+		wait <- ctx.Err()
+	}
+	if err := <-wait; err != nil {
+		return fmt.Errorf("send deployment request: %w", err)
+	}
+
+	return nil
+}
+
 func (s *dispatchServer) HandleDeploymentStatus(ctx context.Context, st *pb.DeploymentStatus) error {
-	s.statusStreamsLock.RLock()
-	for _, ch := range s.statusStreams {
-		ch <- st
+	// Statuses from deployd carry an ID that stays the same when they are reported again.
+	// Older versions of deployd leave it to hookd.
+	if len(st.GetID()) == 0 {
+		st.ID = uuid.New().String()
 	}
-	s.statusStreamsLock.RUnlock()
 
+	// The status is stored before it is streamed to clients, so that they can always resume from it.
 	dbStatus := database_mapper.DeploymentStatus(st)
-	err := s.db.WriteDeploymentStatus(ctx, dbStatus)
+	written, err := s.db.WriteDeploymentStatus(ctx, dbStatus)
 	if err != nil {
 		if database.IsErrForeignKeyViolation(err) {
 			return status.Error(codes.FailedPrecondition, err.Error())
 		}
 		return status.Errorf(codes.Unavailable, "write deployment status to database: %s", err)
 	}
+	if !written {
+		log.WithFields(st.LogFields()).Debugf("Deployment status has already been received")
+		return nil
+	}
+
+	s.statusStreams.publish(st)
+
+	if r := s.replica.Load(); r != nil {
+		err := s.publishStatus(ctx, r, st)
+		if err != nil {
+			log.WithFields(st.LogFields()).Errorf("Publish deployment status to other hookd replicas: %s", err)
+		}
+	}
 
 	metrics.UpdateQueue(st)
 	logger := log.WithFields(st.LogFields())
 	logger.Debugf("Saved deployment status in database")
 
-	err = s.writeDeploymentStatusToNaisApi(ctx, st)
-	if err != nil {
-		logger.WithError(err).Errorf("Write deployment status to Nais API")
+	if !st.GetRequest().GetDryRun() && !st.GetRequest().GetDiff() {
+		err = s.writeDeploymentStatusToNaisApi(ctx, st)
+		if err != nil {
+			logger.WithError(err).Errorf("Write deployment status to Nais API")
+		}
 	}
 
 	if st.GetState().Finished() {
-		deployID := st.GetRequest().GetID()
-		s.traceSpansLock.Lock()
-		if span, ok := s.traceSpans[deployID]; ok {
-			span.End()
-			delete(s.traceSpans, deployID)
-		}
-		s.traceSpansLock.Unlock()
+		s.finished(st)
 		logger.Infof("Deployment finished")
 	}
 
@@ -108,7 +190,7 @@ func convertDeploymentState(deploymentState pb.DeploymentState) *protoapi.Deploy
 		ret = protoapi.DeploymentState_error
 	case pb.DeploymentState_failure:
 		ret = protoapi.DeploymentState_failure
-	case pb.DeploymentState_inactive:
+	case pb.DeploymentState_inactive, pb.DeploymentState_cancelled:
 		ret = protoapi.DeploymentState_inactive
 	case pb.DeploymentState_in_progress:
 		ret = protoapi.DeploymentState_in_progress
@@ -0,0 +1,64 @@
+package dispatchserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/nais/deploy/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleDeploymentStatus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, _ = telemetry.New(ctx, "test", "")
+
+	st := func(id string) *pb.DeploymentStatus {
+		return &pb.DeploymentStatus{
+			ID:      id,
+			Request: &pb.DeploymentRequest{ID: "1", DryRun: true},
+			State:   pb.DeploymentState_in_progress,
+		}
+	}
+
+	t.Run("statuses are streamed once they have been stored", func(t *testing.T) {
+		db := database.NewMockDeploymentStore(t)
+		s := New(db, nil).(*dispatchServer)
+		statuses := s.SubscribeStatus(ctx, "1")
+
+		db.On("WriteDeploymentStatus", mock.Anything, mock.MatchedBy(func(status database.DeploymentStatus) bool {
+			return status.ID == "a"
+		})).Run(func(mock.Arguments) {
+			assert.Empty(t, statuses, "status was streamed before it was stored")
+		}).Return(true, nil).Once()
+
+		assert.NoError(t, s.HandleDeploymentStatus(ctx, st("a")))
+		assert.Len(t, statuses, 1)
+	})
+
+	t.Run("statuses reported again are not streamed again", func(t *testing.T) {
+		db := database.NewMockDeploymentStore(t)
+		s := New(db, nil).(*dispatchServer)
+		statuses := s.SubscribeStatus(ctx, "1")
+
+		db.On("WriteDeploymentStatus", mock.Anything, mock.Anything).Return(false, nil).Once()
+
+		assert.NoError(t, s.HandleDeploymentStatus(ctx, st("a")))
+		assert.Empty(t, statuses)
+	})
+
+	t.Run("statuses that cannot be stored are not streamed", func(t *testing.T) {
+		db := database.NewMockDeploymentStore(t)
+		s := New(db, nil).(*dispatchServer)
+		statuses := s.SubscribeStatus(ctx, "1")
+
+		db.On("WriteDeploymentStatus", mock.Anything, mock.Anything).Return(false, errors.New("database is down")).Once()
+
+		assert.Error(t, s.HandleDeploymentStatus(ctx, st("a")))
+		assert.Empty(t, statuses)
+	})
+}
@@ -0,0 +1,213 @@
+package dispatchserver
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/nais/deploy/pkg/hookd/metrics"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/peer"
+)
+
+// connection is a deployment stream opened by a single deployd instance.
+//
+// A cluster may be served by several deployd instances, connected to any hookd replica. One instance at a time is
+// active and receives all deployment requests, while the others stand by. The active instance holds a lease for the
+// cluster, which a standby instance acquires when the active one goes away, taking over the deployments it left
+// unfinished.
+type connection struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	cluster  string
+	instance string
+	resume   bool
+	startup  time.Time
+	requests chan *requestWithWait
+	// Whether this instance holds the lease for its cluster; guarded by onlineClustersLock.
+	active bool
+}
+
+func newConnection(ctx context.Context, opts *pb.GetDeploymentOpts) *connection {
+	instance := opts.GetInstance()
+	if len(instance) == 0 {
+		// Older versions of deployd do not identify themselves.
+		if p, ok := peer.FromContext(ctx); ok {
+			instance = p.Addr.String()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &connection{
+		ctx:      ctx,
+		cancel:   cancel,
+		cluster:  opts.GetCluster(),
+		instance: instance,
+		resume:   opts.GetResume(),
+		startup:  opts.GetStartupTime().AsTime(),
+		requests: make(chan *requestWithWait),
+	}
+}
+
+func (c *connection) logger() *log.Entry {
+	return log.WithFields(log.Fields{
+		pb.LogFieldCluster:         c.cluster,
+		pb.LogFieldDeploydInstance: c.instance,
+	})
+}
+
+// register adds a connection to its cluster.
+// A connection from an instance that is already connected replaces the old connection, which is closed.
+// The new connection stays active if the old one was, in which case true is returned.
+func (s *dispatchServer) register(conn *connection) bool {
+	s.onlineClustersLock.Lock()
+	defer s.onlineClustersLock.Unlock()
+
+	conns := s.onlineClustersMap[conn.cluster]
+	i := slices.IndexFunc(conns, func(c *connection) bool { return c.instance == conn.instance })
+	if i >= 0 {
+		old := conns[i]
+		conn.active = old.active
+		conns[i] = conn
+		old.cancel()
+	} else {
+		conns = append(conns, conn)
+	}
+	s.onlineClustersMap[conn.cluster] = conns
+	s.reportConnections(conn.cluster)
+
+	return conn.active
+}
+
+// unregister removes a connection from its cluster, and returns true if it was the active one.
+func (s *dispatchServer) unregister(conn *connection) bool {
+	s.onlineClustersLock.Lock()
+	defer s.onlineClustersLock.Unlock()
+
+	conns := s.onlineClustersMap[conn.cluster]
+	i := slices.Index(conns, conn)
+	if i < 0 {
+		// Replaced by a newer connection from the same instance.
+		return false
+	}
+
+	conns = slices.Delete(conns, i, i+1)
+	if len(conns) == 0 {
+		delete(s.onlineClustersMap, conn.cluster)
+	} else {
+		s.onlineClustersMap[conn.cluster] = conns
+	}
+	s.reportConnections(conn.cluster)
+
+	return conn.active
+}
+
+// reportConnections must be called with onlineClustersLock held.
+func (s *dispatchServer) reportConnections(cluster string) {
+	conns := s.onlineClustersMap[cluster]
+	instances := make([]string, 0, len(conns))
+	active := ""
+	for _, c := range conns {
+		instances = append(instances, c.instance)
+		if c.active {
+			active = c.instance
+		}
+	}
+	metrics.SetDeploydConnections(cluster, instances, active)
+}
+
+// activeConnection returns the connection that receives deployment requests for a cluster, if connected to this replica.
+func (s *dispatchServer) activeConnection(cluster string) *connection {
+	s.onlineClustersLock.RLock()
+	defer s.onlineClustersLock.RUnlock()
+
+	for _, conn := range s.onlineClustersMap[cluster] {
+		if conn.active {
+			return conn
+		}
+	}
+	return nil
+}
+
+// targets returns the connections a request should be sent on.
+// Cancellations go to every instance, as the deployment may still be running on one that is no longer active.
+func (s *dispatchServer) targets(request *pb.DeploymentRequest) []*connection {
+	if !request.GetCancel() {
+		if conn := s.activeConnection(request.GetCluster()); conn != nil {
+			return []*connection{conn}
+		}
+		return nil
+	}
+
+	s.onlineClustersLock.RLock()
+	defer s.onlineClustersLock.RUnlock()
+	return slices.Clone(s.onlineClustersMap[request.GetCluster()])
+}
+
+// sent records that a deployment request has been sent to deployd on a connection.
+// The instance is stored with the deployment, so that another instance can take it over.
+func (s *dispatchServer) sent(conn *connection, request *pb.DeploymentRequest) {
+	logger := conn.logger().WithFields(request.LogFields())
+
+	if !request.GetCancel() {
+		err := s.db.WriteDeploymentInstance(conn.ctx, request.GetID(), conn.instance)
+		if err != nil {
+			logger.Errorf("Record deployd instance for deployment: %s", err)
+		}
+	}
+
+	metrics.DeploydRequestSent(conn.cluster, conn.instance)
+	logger.Debugf("Deployment request sent on deployment stream")
+}
+
+// finished ends the trace of a deployment once it has finished.
+func (s *dispatchServer) finished(st *pb.DeploymentStatus) {
+	if !st.GetState().Finished() {
+		return
+	}
+
+	deployID := st.GetRequest().GetID()
+
+	s.traceSpansLock.Lock()
+	defer s.traceSpansLock.Unlock()
+	if span, ok := s.traceSpans[deployID]; ok {
+		span.End()
+		delete(s.traceSpans, deployID)
+	}
+}
+
+// takeOver hands the unfinished deployments of the instance that was active before to the instance that is now active.
+// They are resumed if possible, like after a restart of deployd; otherwise they are marked as inactive.
+// The instance that went away may still be finishing them while draining, in which case both report on them.
+func (s *dispatchServer) takeOver(conn *connection, previous string) {
+	logger := conn.logger()
+
+	deploys, err := s.db.DispatchedDeployments(conn.ctx, conn.cluster, previous)
+	if err != nil {
+		logger.Errorf("Take over deployments from deployd instance '%s': %s", previous, err)
+		return
+	}
+
+	logger.Infof("Taking over %d unfinished deployment(s) from deployd instance '%s'", len(deploys), previous)
+
+	resumable, err := s.handleUnfinished(conn.ctx, deploys, conn.resume)
+	if err != nil {
+		logger.Errorf("Take over deployments from deployd instance '%s': %s", previous, err)
+	}
+
+	s.resume(conn, resumable)
+}
+
+// resume sends unfinished deployments to deployd again.
+func (s *dispatchServer) resume(conn *connection, requests []*pb.DeploymentRequest) {
+	for _, req := range requests {
+		logger := conn.logger().WithFields(req.LogFields())
+		logger.Infof("Resuming unfinished deployment")
+		err := s.deliver(conn.ctx, conn, req)
+		if err != nil {
+			logger.Errorf("Resume deployment: %s", err)
+		}
+	}
+}
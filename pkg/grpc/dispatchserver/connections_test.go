@@ -0,0 +1,123 @@
+package dispatchserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/nais/deploy/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+)
+
+type fakeDeploymentStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	requests chan *pb.DeploymentRequest
+}
+
+func (f *fakeDeploymentStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeDeploymentStream) Send(request *pb.DeploymentRequest) error {
+	f.requests <- request
+	return nil
+}
+
+// connect opens a deployment stream from a deployd instance, and returns the requests sent on it.
+func connect(t *testing.T, server *dispatchServer, cluster, instance string) <-chan *pb.DeploymentRequest {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stream := &fakeDeploymentStream{
+		ctx:      ctx,
+		requests: make(chan *pb.DeploymentRequest, 16),
+	}
+
+	go server.Deployments(&pb.GetDeploymentOpts{Cluster: cluster, Instance: instance, Resume: true}, stream)
+
+	assert.Eventually(t, func() bool {
+		server.onlineClustersLock.RLock()
+		defer server.onlineClustersLock.RUnlock()
+		for _, conn := range server.onlineClustersMap[cluster] {
+			if conn.ctx.Err() == nil && conn.instance == instance {
+				return true
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+
+	return stream.requests
+}
+
+func received(t *testing.T, requests <-chan *pb.DeploymentRequest) *pb.DeploymentRequest {
+	select {
+	case req := <-requests:
+		return req
+	case <-time.After(time.Second):
+		t.Fatal("no deployment request received")
+		return nil
+	}
+}
+
+func TestStandbyConnections(t *testing.T) {
+	ctx := context.Background()
+	_, _ = telemetry.New(ctx, "test", "")
+	cluster := "dev"
+	inProgress := "in_progress"
+
+	store := database.NewMockDeploymentStore(t)
+	store.On("HistoricDeployments", mock.Anything, cluster, mock.Anything, mock.Anything).Return(nil, nil)
+	store.On("WriteDeploymentInstance", mock.Anything, "1", mock.Anything).Return(nil)
+	store.On("DispatchedDeployments", mock.Anything, cluster, "deployd-1").Return([]*database.Deployment{{ID: "1", Team: "aura", Cluster: &cluster, State: &inProgress}}, nil)
+	store.On("DeploymentDeadline", mock.Anything, "1").Return(time.Now().Add(time.Hour), nil)
+	store.On("DeploymentPayload", mock.Anything, "1").Return([]byte(`{"resources":[{"kind":"ConfigMap"}]}`), nil)
+
+	server := New(store, nil).(*dispatchServer)
+
+	activeCtx, disconnectActive := context.WithCancel(ctx)
+	active := &fakeDeploymentStream{ctx: activeCtx, requests: make(chan *pb.DeploymentRequest, 16)}
+	go server.Deployments(&pb.GetDeploymentOpts{Cluster: cluster, Instance: "deployd-1", Resume: true}, active)
+	assert.Eventually(t, func() bool { return server.activeConnection(cluster) != nil }, time.Second, time.Millisecond)
+
+	standby := connect(t, server, cluster, "deployd-2")
+
+	t.Run("requests are sent to the active instance only", func(t *testing.T) {
+		err := server.SendDeploymentRequest(ctx, &pb.DeploymentRequest{ID: "1", Cluster: cluster})
+		assert.NoError(t, err)
+		assert.Equal(t, "1", received(t, active.requests).GetID())
+		assert.Len(t, standby, 0)
+	})
+
+	t.Run("cancellations are sent to every instance", func(t *testing.T) {
+		err := server.CancelDeployment(ctx, &pb.DeploymentRequest{ID: "1", Cluster: cluster})
+		assert.NoError(t, err)
+		assert.True(t, received(t, active.requests).GetCancel())
+		assert.True(t, received(t, standby).GetCancel())
+	})
+
+	t.Run("standby instance takes over unfinished deployments", func(t *testing.T) {
+		disconnectActive()
+
+		req := received(t, standby)
+		assert.Equal(t, "1", req.GetID())
+		assert.True(t, req.GetResume())
+		assert.Equal(t, "deployd-2", server.activeConnection(cluster).instance)
+	})
+
+	t.Run("reconnecting instance replaces its old connection", func(t *testing.T) {
+		old := server.activeConnection(cluster)
+		connect(t, server, cluster, "deployd-2")
+
+		server.onlineClustersLock.RLock()
+		defer server.onlineClustersLock.RUnlock()
+		conns := server.onlineClustersMap[cluster]
+		assert.Len(t, conns, 1)
+		assert.NotSame(t, old, conns[0])
+		assert.Error(t, old.ctx.Err())
+		assert.True(t, conns[0].active, "the new connection stays active")
+	})
+}
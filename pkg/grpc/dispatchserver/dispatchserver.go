@@ -5,12 +5,11 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/trace"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 
 	"github.com/nais/api/pkg/apiclient/protoapi"
 	"github.com/nais/deploy/pkg/hookd/database"
@@ -23,20 +22,24 @@ import (
 type DispatchServer interface {
 	pb.DispatchServer
 	SendDeploymentRequest(ctx context.Context, deployment *pb.DeploymentRequest) error
+	CancelDeployment(ctx context.Context, deployment *pb.DeploymentRequest) error
 	HandleDeploymentStatus(ctx context.Context, status *pb.DeploymentStatus) error
-	StreamStatus(context.Context, chan<- *pb.DeploymentStatus)
+	SubscribeStatus(ctx context.Context, deploymentID string) <-chan *pb.DeploymentStatus
+	Replicate(ctx context.Context, store database.DispatchStore, replicaID string) error
 }
 
 type dispatchServer struct {
 	pb.UnimplementedDispatchServer
 	onlineClustersLock sync.RWMutex
-	onlineClustersMap  map[string]chan<- *requestWithWait
-	statusStreamsLock  sync.RWMutex
-	statusStreams      map[context.Context]chan<- *pb.DeploymentStatus
+	onlineClustersMap  map[string][]*connection
+	electionLock       sync.Mutex
+	localLeases        *memoryLeases
+	statusStreams      *subscriptions
 	traceSpans         map[string]trace.Span
 	traceSpansLock     sync.RWMutex
 	db                 database.DeploymentStore
 	apiClient          protoapi.DeploymentsClient
+	replica            atomic.Pointer[replica]
 }
 
 var _ DispatchServer = &dispatchServer{}
@@ -48,8 +51,9 @@ type requestWithWait struct {
 
 func New(db database.DeploymentStore, apiClient protoapi.DeploymentsClient) DispatchServer {
 	server := &dispatchServer{
-		onlineClustersMap: make(map[string]chan<- *requestWithWait),
-		statusStreams:     make(map[context.Context]chan<- *pb.DeploymentStatus),
+		onlineClustersMap: make(map[string][]*connection),
+		localLeases:       newMemoryLeases(),
+		statusStreams:     newSubscriptions(),
 		traceSpans:        make(map[string]trace.Span),
 		db:                db,
 		apiClient:         apiClient,
@@ -75,62 +79,77 @@ func (s *dispatchServer) reportOnlineClusters() {
 	log.Infof("Online clusters: %s", strings.Join(clusters, ", "))
 }
 
-func (s *dispatchServer) invalidateHistoric(ctx context.Context, cluster string, timestamp time.Time) error {
-	deploys, err := s.db.HistoricDeployments(ctx, cluster, timestamp)
+// handleHistoric takes care of unfinished deployments sent to a deployd instance before it started.
+// Deployments are marked as inactive, unless deployd asks to resume them; resumable deployments are returned.
+func (s *dispatchServer) handleHistoric(ctx context.Context, cluster, instance string, timestamp time.Time, resume bool) ([]*pb.DeploymentRequest, error) {
+	deploys, err := s.db.HistoricDeployments(ctx, cluster, instance, timestamp)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return s.handleUnfinished(ctx, deploys, resume)
+}
+
+// handleUnfinished marks deployments as inactive, unless they can and should be resumed; resumable deployments are returned.
+func (s *dispatchServer) handleUnfinished(ctx context.Context, deploys []*database.Deployment, resume bool) ([]*pb.DeploymentRequest, error) {
+	resumable := make([]*pb.DeploymentRequest, 0)
+
 	for _, deploy := range deploys {
+		if resume {
+			req, err := s.resumableRequest(ctx, deploy)
+			if err != nil {
+				return nil, err
+			}
+			if req != nil {
+				resumable = append(resumable, req)
+				continue
+			}
+		}
+
 		req := database_mapper.PbRequest(*deploy)
-		err = s.HandleDeploymentStatus(ctx, pb.NewInactiveStatus(req))
+		err := s.HandleDeploymentStatus(ctx, pb.NewInactiveStatus(req))
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	return resumable, nil
 }
 
 func (s *dispatchServer) Deployments(opts *pb.GetDeploymentOpts, stream pb.Dispatch_DeploymentsServer) error {
-	c := make(chan *requestWithWait)
-	s.onlineClustersLock.RLock()
-	_, clusterAlreadyConnected := s.onlineClustersMap[opts.Cluster]
-	s.onlineClustersLock.RUnlock()
-	if clusterAlreadyConnected {
-		log.Warnf("Rejected connection from cluster '%s': already connected", opts.Cluster)
-		return fmt.Errorf("cluster already connected: %s", opts.Cluster)
+	conn := newConnection(stream.Context(), opts)
+	defer conn.cancel()
+	logger := conn.logger()
+
+	logger.Infof("Connection opened from cluster '%s' by deployd instance '%s'", conn.cluster, conn.instance)
+	if s.register(conn) {
+		// Reconnected while still active; deployd may have restarted in the meantime.
+		go s.activate(conn, conn.instance)
 	}
-
-	s.onlineClustersLock.Lock()
-	s.onlineClustersMap[opts.Cluster] = c
-	log.Infof("Connection opened from cluster '%s'", opts.Cluster)
-	s.onlineClustersLock.Unlock()
 	s.reportOnlineClusters()
 
 	defer func() {
-		s.onlineClustersLock.Lock()
-		delete(s.onlineClustersMap, opts.Cluster)
-		s.onlineClustersLock.Unlock()
+		if s.unregister(conn) {
+			s.resign(conn)
+		}
 		s.reportOnlineClusters()
 	}()
 
-	// invalidate older deployments
-	err := s.invalidateHistoric(stream.Context(), opts.GetCluster(), opts.GetStartupTime().AsTime())
-	if err != nil {
-		return status.Error(codes.Unavailable, err.Error())
-	}
+	s.elect(conn.ctx, conn.cluster)
 
 	for {
 		select {
-		case <-stream.Context().Done():
-			log.Warnf("Connection from cluster '%s' closed", opts.Cluster)
+		case <-conn.ctx.Done():
+			logger.Warnf("Connection from cluster '%s' by deployd instance '%s' closed", conn.cluster, conn.instance)
 			return nil
-		case req := <-c:
+		case req := <-conn.requests:
 			err := stream.Send(req.request)
+			if err == nil {
+				s.sent(conn, req.request)
+			}
 			req.wait <- err
 		case <-time.After(30 * time.Minute):
-			log.Warnf("Connection from cluster '%s' timed out", opts.Cluster)
+			logger.Warnf("Connection from cluster '%s' by deployd instance '%s' timed out", conn.cluster, conn.instance)
 			return fmt.Errorf("timeout")
 		}
 	}
@@ -140,17 +159,8 @@ func (s *dispatchServer) ReportStatus(ctx context.Context, status *pb.Deployment
 	return &pb.ReportStatusOpts{}, s.HandleDeploymentStatus(ctx, status)
 }
 
-// Send all status updates belonging to a specific request
-func (s *dispatchServer) StreamStatus(ctx context.Context, channel chan<- *pb.DeploymentStatus) {
-	s.statusStreamsLock.Lock()
-	s.statusStreams[ctx] = channel
-	s.statusStreamsLock.Unlock()
-
-	<-ctx.Done()
-
-	s.statusStreamsLock.Lock()
-	delete(s.statusStreams, ctx)
-	s.statusStreamsLock.Unlock()
-
-	close(channel)
+// SubscribeStatus returns a channel with status updates for a single deployment, subscribed when SubscribeStatus returns.
+// The channel is closed when the context is done, or earlier if the subscriber does not keep up.
+func (s *dispatchServer) SubscribeStatus(ctx context.Context, deploymentID string) <-chan *pb.DeploymentStatus {
+	return s.statusStreams.subscribe(ctx, deploymentID)
 }
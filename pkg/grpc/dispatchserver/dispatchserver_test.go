@@ -40,8 +40,9 @@ func TestInterceptors(t *testing.T) {
 	mockDeployment := &database.Deployment{
 		ID: "mock",
 	}
-	deploymentStore.On("HistoricDeployments", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
-	deploymentStore.On("WriteDeploymentStatus", mock.Anything, mock.Anything).Return(nil)
+	deploymentStore.On("HistoricDeployments", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	deploymentStore.On("WriteDeploymentInstance", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	deploymentStore.On("WriteDeploymentStatus", mock.Anything, mock.Anything).Return(true, nil)
 	deploymentStore.On("Deployment", mock.Anything, mock.Anything).Return(mockDeployment, nil)
 
 	mockApiClients, mockApiServer := apiclient.NewMockClient(t)
@@ -0,0 +1,196 @@
+package dispatchserver
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/hookd/metrics"
+)
+
+var (
+	// How long a deployd instance stays active after its lease was last renewed.
+	leaseTTL = 15 * time.Second
+	// How often leases are renewed, and standby instances try to become active.
+	leaseRenewInterval = 5 * time.Second
+)
+
+// memoryLeases elects the active deployd instances when hookd runs as a single replica.
+// Leases do not expire, as they are released when the active instance disconnects.
+type memoryLeases struct {
+	lock   sync.Mutex
+	leases map[string]memoryLease
+}
+
+type memoryLease struct {
+	instance string
+	released bool
+}
+
+var _ database.LeaseStore = &memoryLeases{}
+
+func newMemoryLeases() *memoryLeases {
+	return &memoryLeases{
+		leases: make(map[string]memoryLease),
+	}
+}
+
+func (m *memoryLeases) AcquireDeploydLease(ctx context.Context, cluster, instance, replica string, ttl time.Duration) (bool, string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	lease := m.leases[cluster]
+	if !lease.released && len(lease.instance) > 0 && lease.instance != instance {
+		return false, lease.instance, nil
+	}
+	m.leases[cluster] = memoryLease{instance: instance}
+	return true, lease.instance, nil
+}
+
+func (m *memoryLeases) ReleaseDeploydLease(ctx context.Context, cluster, instance, replica string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if lease := m.leases[cluster]; lease.instance == instance {
+		lease.released = true
+		m.leases[cluster] = lease
+	}
+	return nil
+}
+
+// leases returns where leases are held, and the replica that holds them.
+// Replicas share leases through the database, so that a cluster has a single active instance across all replicas.
+func (s *dispatchServer) leases() (database.LeaseStore, string) {
+	if r := s.replica.Load(); r != nil {
+		return r.store, r.id
+	}
+	return s.localLeases, ""
+}
+
+// elect acquires or renews the lease for the active deployd instance of a cluster, if connected to this replica.
+// The first connection becomes active if no instance is, and the lease is free. An instance that loses its lease to
+// another stands by.
+func (s *dispatchServer) elect(ctx context.Context, cluster string) {
+	s.electionLock.Lock()
+	defer s.electionLock.Unlock()
+
+	s.onlineClustersLock.RLock()
+	conns := s.onlineClustersMap[cluster]
+	var candidate *connection
+	if i := slices.IndexFunc(conns, func(c *connection) bool { return c.active }); i >= 0 {
+		candidate = conns[i]
+	} else if len(conns) > 0 {
+		candidate = conns[0]
+	}
+	s.onlineClustersLock.RUnlock()
+
+	if candidate == nil {
+		return
+	}
+
+	leases, replicaID := s.leases()
+	acquired, previous, err := leases.AcquireDeploydLease(ctx, cluster, candidate.instance, replicaID, leaseTTL)
+	if err != nil {
+		candidate.logger().Errorf("Acquire lease for active deployd instance: %s", err)
+		return
+	}
+
+	// The instance may have disconnected, or reconnected, in the meantime.
+	s.onlineClustersLock.Lock()
+	conns = s.onlineClustersMap[cluster]
+	i := slices.IndexFunc(conns, func(c *connection) bool { return c.instance == candidate.instance })
+	var conn *connection
+	wasActive := false
+	if i >= 0 {
+		conn = conns[i]
+		wasActive = conn.active
+		conn.active = acquired
+		s.reportConnections(cluster)
+	}
+	s.onlineClustersLock.Unlock()
+
+	switch {
+	case conn == nil:
+		if acquired {
+			err = leases.ReleaseDeploydLease(ctx, cluster, candidate.instance, replicaID)
+			if err != nil {
+				candidate.logger().Errorf("Release lease for active deployd instance: %s", err)
+			}
+		}
+	case acquired && !wasActive:
+		go s.activate(conn, previous)
+	case !acquired && wasActive:
+		conn.logger().Warnf("Deployd instance '%s' stands by, as instance '%s' is active for cluster '%s'", conn.instance, previous, cluster)
+	}
+}
+
+// resign releases the lease of an active deployd instance that disconnected, and elects the next one.
+func (s *dispatchServer) resign(conn *connection) {
+	ctx, cancel := context.WithTimeout(context.Background(), leaseTTL)
+	defer cancel()
+
+	leases, replicaID := s.leases()
+	s.electionLock.Lock()
+	err := leases.ReleaseDeploydLease(ctx, conn.cluster, conn.instance, replicaID)
+	s.electionLock.Unlock()
+	if err != nil {
+		conn.logger().Errorf("Release lease for active deployd instance: %s", err)
+	}
+
+	s.elect(ctx, conn.cluster)
+
+	// Instances connected to other replicas need not wait for the lease to be renewed.
+	if r := s.replica.Load(); r != nil {
+		err = r.store.NotifyDispatch(ctx, database.DispatchNotification{
+			Kind:    messageLease,
+			Sender:  r.id,
+			Cluster: conn.cluster,
+		})
+		if err != nil {
+			conn.logger().Errorf("Notify other hookd replicas about released lease: %s", err)
+		}
+	}
+}
+
+// renewLeases keeps the leases of active instances connected to this replica, and lets standby instances take over
+// leases that have expired, until the context is done.
+func (s *dispatchServer) renewLeases(ctx context.Context) {
+	ticker := time.NewTicker(leaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, cluster := range s.onlineClusters() {
+			s.elect(ctx, cluster)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// activate hands a connection that just became active the deployments it should take care of: its own from before
+// deployd started, and those left unfinished by the instance that was active before.
+func (s *dispatchServer) activate(conn *connection, previous string) {
+	logger := conn.logger()
+	logger.Infof("Deployd instance '%s' is now active for cluster '%s'", conn.instance, conn.cluster)
+
+	resumable, err := s.handleHistoric(conn.ctx, conn.cluster, conn.instance, conn.startup, conn.resume)
+	if err != nil {
+		// deployd reconnects, and unfinished deployments are handled again.
+		logger.Errorf("Handle unfinished deployments: %s", err)
+		conn.cancel()
+		return
+	}
+
+	s.resume(conn, resumable)
+
+	if len(previous) > 0 && previous != conn.instance {
+		metrics.DeploydFailover(conn.cluster)
+		s.takeOver(conn, previous)
+	}
+}
@@ -5,6 +5,8 @@ package dispatchserver
 import (
 	context "context"
 
+	database "github.com/nais/deploy/pkg/hookd/database"
+
 	pb "github.com/nais/deploy/pkg/pb"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -15,6 +17,20 @@ type MockDispatchServer struct {
 	mock.Mock
 }
 
+// CancelDeployment provides a mock function with given fields: ctx, deployment
+func (_m *MockDispatchServer) CancelDeployment(ctx context.Context, deployment *pb.DeploymentRequest) error {
+	ret := _m.Called(ctx, deployment)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *pb.DeploymentRequest) error); ok {
+		r0 = rf(ctx, deployment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Deployments provides a mock function with given fields: _a0, _a1
 func (_m *MockDispatchServer) Deployments(_a0 *pb.GetDeploymentOpts, _a1 pb.Dispatch_DeploymentsServer) error {
 	ret := _m.Called(_a0, _a1)
@@ -43,6 +59,20 @@ func (_m *MockDispatchServer) HandleDeploymentStatus(ctx context.Context, status
 	return r0
 }
 
+// Replicate provides a mock function with given fields: ctx, store, replicaID
+func (_m *MockDispatchServer) Replicate(ctx context.Context, store database.DispatchStore, replicaID string) error {
+	ret := _m.Called(ctx, store, replicaID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, database.DispatchStore, string) error); ok {
+		r0 = rf(ctx, store, replicaID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ReportStatus provides a mock function with given fields: _a0, _a1
 func (_m *MockDispatchServer) ReportStatus(_a0 context.Context, _a1 *pb.DeploymentStatus) (*pb.ReportStatusOpts, error) {
 	ret := _m.Called(_a0, _a1)
@@ -83,9 +113,20 @@ func (_m *MockDispatchServer) SendDeploymentRequest(ctx context.Context, deploym
 	return r0
 }
 
-// StreamStatus provides a mock function with given fields: _a0, _a1
-func (_m *MockDispatchServer) StreamStatus(_a0 context.Context, _a1 chan<- *pb.DeploymentStatus) {
-	_m.Called(_a0, _a1)
+// SubscribeStatus provides a mock function with given fields: ctx, deploymentID
+func (_m *MockDispatchServer) SubscribeStatus(ctx context.Context, deploymentID string) <-chan *pb.DeploymentStatus {
+	ret := _m.Called(ctx, deploymentID)
+
+	var r0 <-chan *pb.DeploymentStatus
+	if rf, ok := ret.Get(0).(func(context.Context, string) <-chan *pb.DeploymentStatus); ok {
+		r0 = rf(ctx, deploymentID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan *pb.DeploymentStatus)
+		}
+	}
+
+	return r0
 }
 
 // mustEmbedUnimplementedDispatchServer provides a mock function with given fields:
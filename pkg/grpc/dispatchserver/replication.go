@@ -0,0 +1,268 @@
+package dispatchserver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Kinds of messages passed between hookd replicas.
+const (
+	messageStatus  = "status"
+	messageRequest = "request"
+	messageAck     = "ack"
+	messageLease   = "lease"
+)
+
+var (
+	// How long a forwarded request may wait before a replica with the cluster online picks it up.
+	forwardTimeout = 5 * time.Second
+	// How long to wait before listening again after losing the database connection.
+	listenRetryInterval = 5 * time.Second
+	// How often, and after how long, messages between replicas are deleted from the database.
+	messageCleanupInterval = 10 * time.Minute
+	messageRetention       = time.Hour
+)
+
+// replica holds the state needed to exchange requests and statuses with other hookd replicas.
+type replica struct {
+	id       string
+	store    database.DispatchStore
+	acksLock sync.Mutex
+	acks     map[string]chan database.DispatchNotification
+}
+
+// Replicate exchanges deployment requests and statuses with other hookd replicas through the database, until the
+// context is done. Requests for clusters connected to another replica are forwarded to it, and statuses reported to
+// any replica are streamed to clients of every replica.
+func (s *dispatchServer) Replicate(ctx context.Context, store database.DispatchStore, replicaID string) error {
+	r := &replica{
+		id:    replicaID,
+		store: store,
+		acks:  make(map[string]chan database.DispatchNotification),
+	}
+	s.replica.Store(r)
+	defer s.replica.Store(nil)
+
+	log.Infof("Replicating deployment requests and statuses as hookd replica '%s'", replicaID)
+
+	go s.cleanupMessages(ctx, r)
+	go s.renewLeases(ctx)
+
+	for {
+		err := r.store.ListenDispatch(ctx, func(notification database.DispatchNotification) {
+			s.handleNotification(ctx, r, notification)
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		log.Errorf("Listen for messages from other hookd replicas: %s", err)
+
+		// Statuses from other replicas may have been lost while not listening.
+		// Status streams are closed so that clients reconnect and resume from the database.
+		s.statusStreams.closeAll()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(listenRetryInterval):
+		}
+	}
+}
+
+func (s *dispatchServer) cleanupMessages(ctx context.Context, r *replica) {
+	ticker := time.NewTicker(messageCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.store.DeleteDispatchMessages(ctx, time.Now().Add(-messageRetention))
+			if err != nil {
+				log.Errorf("Delete old messages between hookd replicas: %s", err)
+			}
+		}
+	}
+}
+
+func (s *dispatchServer) handleNotification(ctx context.Context, r *replica, notification database.DispatchNotification) {
+	if notification.Sender == r.id {
+		return
+	}
+
+	switch notification.Kind {
+	case messageStatus:
+		s.receiveStatus(ctx, r, notification.ID)
+	case messageRequest:
+		if s.activeConnection(notification.Cluster) != nil {
+			go s.receiveRequest(ctx, r, notification.ID)
+		}
+	case messageLease:
+		go s.elect(ctx, notification.Cluster)
+	case messageAck:
+		r.acksLock.Lock()
+		ack, ok := r.acks[notification.ID]
+		r.acksLock.Unlock()
+		if ok {
+			select {
+			case ack <- notification:
+			default:
+			}
+		}
+	}
+}
+
+// publishStatus makes a status available to the status streams of other replicas.
+func (s *dispatchServer) publishStatus(ctx context.Context, r *replica, st *pb.DeploymentStatus) error {
+	payload, err := proto.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	return r.store.WriteDispatchMessage(ctx, database.DispatchMessage{
+		ID:      uuid.New().String(),
+		Kind:    messageStatus,
+		Sender:  r.id,
+		Cluster: st.GetRequest().GetCluster(),
+		Payload: payload,
+		Created: time.Now(),
+	})
+}
+
+func (s *dispatchServer) receiveStatus(ctx context.Context, r *replica, id string) {
+	message, err := r.store.DispatchMessage(ctx, id)
+	if err != nil {
+		log.Errorf("Read deployment status from hookd replica: %s", err)
+		return
+	}
+
+	st := &pb.DeploymentStatus{}
+	err = proto.Unmarshal(message.Payload, st)
+	if err != nil {
+		log.Errorf("Decode deployment status from hookd replica '%s': %s", message.Sender, err)
+		return
+	}
+
+	s.statusStreams.publish(st)
+	s.finished(st)
+}
+
+// forward asks the replica that has the request's cluster online to send the request to deployd,
+// and waits until it has done so.
+func (s *dispatchServer) forward(ctx context.Context, r *replica, request *pb.DeploymentRequest) error {
+	payload, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	id := uuid.New().String()
+	ack := make(chan database.DispatchNotification, 1)
+	r.acksLock.Lock()
+	r.acks[id] = ack
+	r.acksLock.Unlock()
+
+	defer func() {
+		r.acksLock.Lock()
+		delete(r.acks, id)
+		r.acksLock.Unlock()
+	}()
+
+	err = r.store.WriteDispatchMessage(ctx, database.DispatchMessage{
+		ID:      id,
+		Kind:    messageRequest,
+		Sender:  r.id,
+		Cluster: request.GetCluster(),
+		Payload: payload,
+		Created: time.Now(),
+	})
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "forward deployment request to other hookd replicas: %s", err)
+	}
+
+	select {
+	case notification := <-ack:
+		return ackError(notification)
+	case <-time.After(forwardTimeout):
+	case <-ctx.Done():
+	}
+
+	// Take the request back, so that it cannot be sent late. If another replica got to it first,
+	// it is already on its way to deployd.
+	claimCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), forwardTimeout)
+	defer cancel()
+	_, err = r.store.ClaimDispatchMessage(claimCtx, id, r.id)
+	if err == nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("send deployment request: %w", ctx.Err())
+		}
+		return status.Errorf(codes.Unavailable, "cluster '%s' is offline", request.GetCluster())
+	} else if !database.IsErrNotFound(err) {
+		return status.Errorf(codes.Unavailable, "forward deployment request to other hookd replicas: %s", err)
+	}
+
+	select {
+	case notification := <-ack:
+		return ackError(notification)
+	case <-ctx.Done():
+		return fmt.Errorf("send deployment request: %w", ctx.Err())
+	}
+}
+
+func ackError(notification database.DispatchNotification) error {
+	if len(notification.Error) > 0 {
+		return fmt.Errorf("send deployment request through hookd replica '%s': %s", notification.Sender, notification.Error)
+	}
+	return nil
+}
+
+// receiveRequest sends a request forwarded by another replica to deployd, unless another replica has already claimed it.
+func (s *dispatchServer) receiveRequest(ctx context.Context, r *replica, id string) {
+	message, err := r.store.ClaimDispatchMessage(ctx, id, r.id)
+	if database.IsErrNotFound(err) {
+		return
+	} else if err != nil {
+		log.Errorf("Claim deployment request from hookd replica: %s", err)
+		return
+	}
+
+	ack := database.DispatchNotification{
+		ID:     id,
+		Kind:   messageAck,
+		Sender: r.id,
+	}
+
+	request := &pb.DeploymentRequest{}
+	err = proto.Unmarshal(message.Payload, request)
+	if err == nil {
+		// The request is never forwarded again, even if the cluster has gone offline in the meantime.
+		conns := s.targets(request)
+		if len(conns) > 0 {
+			err = s.deliverAll(ctx, conns, request)
+		} else {
+			err = status.Errorf(codes.Unavailable, "cluster '%s' is offline", request.GetCluster())
+		}
+	}
+	if err != nil {
+		ack.Error = err.Error()
+		log.WithFields(request.LogFields()).Errorf("Deployment request from hookd replica '%s': %s", message.Sender, err)
+	} else {
+		log.WithFields(request.LogFields()).Debugf("Deployment request from hookd replica '%s' sent to deployd", message.Sender)
+	}
+
+	err = r.store.NotifyDispatch(ctx, ack)
+	if err != nil {
+		log.WithFields(request.LogFields()).Errorf("Acknowledge deployment request from hookd replica '%s': %s", message.Sender, err)
+	}
+}
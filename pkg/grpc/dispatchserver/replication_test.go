@@ -0,0 +1,245 @@
+package dispatchserver
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/nais/deploy/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeDispatchStore passes messages between replicas in memory, like the database does with LISTEN/NOTIFY.
+type fakeDispatchStore struct {
+	lock      sync.Mutex
+	messages  map[string]database.DispatchMessage
+	claimed   map[string]string
+	leases    map[string]fakeLease
+	listeners []chan database.DispatchNotification
+}
+
+type fakeLease struct {
+	instance string
+	replica  string
+	expires  time.Time
+}
+
+func newFakeDispatchStore() *fakeDispatchStore {
+	return &fakeDispatchStore{
+		messages: make(map[string]database.DispatchMessage),
+		claimed:  make(map[string]string),
+		leases:   make(map[string]fakeLease),
+	}
+}
+
+func (f *fakeDispatchStore) AcquireDeploydLease(ctx context.Context, cluster, instance, replica string, ttl time.Duration) (bool, string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	lease, ok := f.leases[cluster]
+	held := lease.instance == instance && lease.replica == replica
+	if ok && !held && lease.expires.After(time.Now()) {
+		return false, lease.instance, nil
+	}
+	f.leases[cluster] = fakeLease{instance: instance, replica: replica, expires: time.Now().Add(ttl)}
+	return true, lease.instance, nil
+}
+
+func (f *fakeDispatchStore) ReleaseDeploydLease(ctx context.Context, cluster, instance, replica string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if lease := f.leases[cluster]; lease.instance == instance && lease.replica == replica {
+		lease.expires = time.Now()
+		f.leases[cluster] = lease
+	}
+	return nil
+}
+
+func (f *fakeDispatchStore) WriteDispatchMessage(ctx context.Context, message database.DispatchMessage) error {
+	f.lock.Lock()
+	f.messages[message.ID] = message
+	f.lock.Unlock()
+
+	return f.NotifyDispatch(ctx, database.DispatchNotification{
+		ID:      message.ID,
+		Kind:    message.Kind,
+		Sender:  message.Sender,
+		Cluster: message.Cluster,
+	})
+}
+
+func (f *fakeDispatchStore) DispatchMessage(ctx context.Context, id string) (*database.DispatchMessage, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	message, ok := f.messages[id]
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+	return &message, nil
+}
+
+func (f *fakeDispatchStore) ClaimDispatchMessage(ctx context.Context, id, replica string) (*database.DispatchMessage, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	message, ok := f.messages[id]
+	if !ok || len(f.claimed[id]) > 0 {
+		return nil, database.ErrNotFound
+	}
+	f.claimed[id] = replica
+	return &message, nil
+}
+
+func (f *fakeDispatchStore) DeleteDispatchMessages(ctx context.Context, before time.Time) error {
+	return nil
+}
+
+func (f *fakeDispatchStore) NotifyDispatch(ctx context.Context, notification database.DispatchNotification) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, listener := range f.listeners {
+		listener <- notification
+	}
+	return nil
+}
+
+func (f *fakeDispatchStore) ListenDispatch(ctx context.Context, fn func(database.DispatchNotification)) error {
+	listener := make(chan database.DispatchNotification, 16)
+	f.lock.Lock()
+	f.listeners = append(f.listeners, listener)
+	f.lock.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case notification := <-listener:
+			fn(notification)
+		}
+	}
+}
+
+func (f *fakeDispatchStore) listening() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.listeners)
+}
+
+// replicas starts two dispatch servers replicating through the same store.
+func replicas(t *testing.T, ctx context.Context, db *database.MockDeploymentStore) (*dispatchServer, *dispatchServer) {
+	db.On("WriteDeploymentStatus", mock.Anything, mock.Anything).Return(true, nil).Maybe()
+	db.On("WriteDeploymentInstance", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	store := newFakeDispatchStore()
+	a := New(db, nil).(*dispatchServer)
+	b := New(db, nil).(*dispatchServer)
+	go a.Replicate(ctx, store, "a")
+	go b.Replicate(ctx, store, "b")
+
+	assert.Eventually(t, func() bool { return store.listening() == 2 }, time.Second, time.Millisecond)
+
+	return a, b
+}
+
+func TestReplication(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, _ = telemetry.New(ctx, "test", "")
+
+	db := database.NewMockDeploymentStore(t)
+	db.On("HistoricDeployments", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	a, b := replicas(t, ctx, db)
+
+	t.Run("statuses reported to one replica are streamed from another", func(t *testing.T) {
+		statuses := a.SubscribeStatus(ctx, "1")
+
+		err := b.HandleDeploymentStatus(ctx, &pb.DeploymentStatus{
+			Request: &pb.DeploymentRequest{ID: "1", Cluster: "dev", DryRun: true},
+			State:   pb.DeploymentState_in_progress,
+		})
+		assert.NoError(t, err)
+
+		select {
+		case st := <-statuses:
+			assert.Equal(t, pb.DeploymentState_in_progress, st.GetState())
+			assert.NotEmpty(t, st.GetID())
+		case <-time.After(time.Second):
+			t.Fatal("status was not streamed from the other replica")
+		}
+	})
+
+	t.Run("requests are forwarded to the replica with the cluster online", func(t *testing.T) {
+		requests := connect(t, b, "dev", "deployd-1")
+
+		err := a.SendDeploymentRequest(ctx, &pb.DeploymentRequest{ID: "2", Cluster: "dev"})
+		assert.NoError(t, err)
+
+		select {
+		case req := <-requests:
+			assert.Equal(t, "2", req.GetID())
+		default:
+			t.Fatal("request was not sent to deployd")
+		}
+	})
+
+	t.Run("cluster that is offline on all replicas", func(t *testing.T) {
+		defer func(timeout time.Duration) { forwardTimeout = timeout }(forwardTimeout)
+		forwardTimeout = 10 * time.Millisecond
+
+		err := a.SendDeploymentRequest(ctx, &pb.DeploymentRequest{ID: "3", Cluster: "prod"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+func TestReplicatedElection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, _ = telemetry.New(ctx, "test", "")
+	cluster := "dev"
+	inProgress := "in_progress"
+
+	db := database.NewMockDeploymentStore(t)
+	db.On("HistoricDeployments", mock.Anything, cluster, "deployd-1", mock.Anything).Return(nil, nil)
+	a, b := replicas(t, ctx, db)
+
+	activeCtx, disconnectActive := context.WithCancel(ctx)
+	active := &fakeDeploymentStream{ctx: activeCtx, requests: make(chan *pb.DeploymentRequest, 16)}
+	go a.Deployments(&pb.GetDeploymentOpts{Cluster: cluster, Instance: "deployd-1", Resume: true}, active)
+	assert.Eventually(t, func() bool { return a.activeConnection(cluster) != nil }, time.Second, time.Millisecond)
+
+	standby := connect(t, b, cluster, "deployd-2")
+
+	t.Run("instance connected to another replica stands by", func(t *testing.T) {
+		b.elect(ctx, cluster)
+		assert.Nil(t, b.activeConnection(cluster))
+
+		err := b.SendDeploymentRequest(ctx, &pb.DeploymentRequest{ID: "1", Cluster: cluster})
+		assert.NoError(t, err)
+		assert.Equal(t, "1", received(t, active.requests).GetID())
+		assert.Len(t, standby, 0)
+	})
+
+	t.Run("standby instance on another replica takes over only deployments sent to the active instance", func(t *testing.T) {
+		db.On("HistoricDeployments", mock.Anything, cluster, "deployd-2", mock.Anything).Return(nil, nil)
+		db.On("DispatchedDeployments", mock.Anything, cluster, "deployd-1").Return([]*database.Deployment{{ID: "1", Team: "aura", Cluster: &cluster, State: &inProgress}}, nil)
+		db.On("DeploymentDeadline", mock.Anything, "1").Return(time.Now().Add(time.Hour), nil)
+		db.On("DeploymentPayload", mock.Anything, "1").Return([]byte(`{"resources":[{"kind":"ConfigMap"}]}`), nil)
+
+		disconnectActive()
+
+		req := received(t, standby)
+		assert.Equal(t, "1", req.GetID())
+		assert.True(t, req.GetResume())
+		assert.Equal(t, "deployd-2", b.activeConnection(cluster).instance)
+		db.AssertNotCalled(t, "DispatchedDeployments", mock.Anything, cluster, "deployd-2")
+	})
+}
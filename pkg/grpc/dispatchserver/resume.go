@@ -0,0 +1,117 @@
+package dispatchserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	database_mapper "github.com/nais/deploy/pkg/hookd/database/mapper"
+	"github.com/nais/deploy/pkg/k8sutils"
+	"github.com/nais/deploy/pkg/pb"
+	log "github.com/sirupsen/logrus"
+)
+
+// resumableRequest restores the request of an unfinished deployment, so that deployd can resume watching it.
+// Returns nil if the deployment cannot be resumed, i.e. if its request was not stored or its deadline has passed.
+func (s *dispatchServer) resumableRequest(ctx context.Context, deploy *database.Deployment) (*pb.DeploymentRequest, error) {
+	req := database_mapper.PbRequest(*deploy)
+	logger := log.WithFields(req.LogFields())
+
+	deadline, err := s.db.DeploymentDeadline(ctx, deploy.ID)
+	if database.IsErrNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(deadline) {
+		logger.Infof("Deadline of unfinished deployment has passed; not resuming")
+		return nil, nil
+	}
+
+	payload, err := s.db.DeploymentPayload(ctx, deploy.ID)
+	if database.IsErrNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	stored, err := pb.RequestFromPayload(payload)
+	if err != nil {
+		logger.Errorf("Decode request of unfinished deployment: %s", err)
+		return nil, nil
+	}
+
+	// Earlier versions of hookd stored only the resources; the rest of the request is rebuilt from the deployment.
+	if len(stored.GetID()) > 0 {
+		req = stored
+	} else {
+		req.Kubernetes = stored.GetKubernetes()
+	}
+
+	req.Deadline = pb.TimeAsTimestamp(deadline)
+	req.Resume = true
+
+	// Resources to roll back to or prune are not stored with the request, and are looked up again.
+	err = s.restoreRollback(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	err = s.restorePruneResources(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// restoreRollback adds the resources of the deployment that a resumed request rolls back to on failure.
+func (s *dispatchServer) restoreRollback(ctx context.Context, req *pb.DeploymentRequest) error {
+	if !req.GetRollbackOnFailure() || len(req.GetRollbackDeploymentID()) == 0 {
+		return nil
+	}
+
+	logger := log.WithFields(req.LogFields())
+
+	payload, err := s.db.DeploymentPayload(ctx, req.GetRollbackDeploymentID())
+	if database.IsErrNotFound(err) {
+		logger.Infof("Resources of previous deployment %s are no longer stored; nothing to roll back to", req.GetRollbackDeploymentID())
+		req.RollbackDeploymentID = ""
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	previous, err := pb.RequestFromPayload(payload)
+	if err != nil {
+		logger.Errorf("Decode resources of previous deployment %s: %s", req.GetRollbackDeploymentID(), err)
+		req.RollbackDeploymentID = ""
+		return nil
+	}
+
+	req.Rollback = previous.GetKubernetes()
+
+	return nil
+}
+
+// restorePruneResources adds the resources that a resumed request prunes once it has been rolled out.
+func (s *dispatchServer) restorePruneResources(ctx context.Context, req *pb.DeploymentRequest) error {
+	if !req.GetPrune() || len(req.GetPruneDeploymentID()) == 0 {
+		return nil
+	}
+
+	resources, err := k8sutils.ResourcesFromDeploymentRequest(req)
+	if err != nil {
+		log.WithFields(req.LogFields()).Errorf("Decode resources of unfinished deployment: %s", err)
+		return nil
+	}
+
+	previous, err := s.db.DeploymentResources(ctx, req.GetPruneDeploymentID())
+	if err != nil {
+		return err
+	}
+
+	req.PruneResources = database_mapper.PruneCandidates(previous, k8sutils.Identifiers(resources))
+
+	return nil
+}
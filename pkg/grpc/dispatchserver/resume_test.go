@@ -0,0 +1,121 @@
+package dispatchserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nais/api/pkg/apiclient"
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestHandleHistoric(t *testing.T) {
+	ctx := context.Background()
+	startup := time.Now()
+	cluster := "test"
+
+	deployments := []*database.Deployment{
+		{ID: "resumable", Team: "aura", Cluster: &cluster, Created: startup.Add(-time.Minute)},
+		{ID: "expired", Team: "aura", Cluster: &cluster, Created: startup.Add(-time.Hour)},
+		{ID: "dry-run", Team: "aura", Cluster: &cluster, Created: startup.Add(-time.Minute), DryRun: true},
+	}
+
+	setup := func(t *testing.T) (*dispatchServer, *database.MockDeploymentStore) {
+		store := database.NewMockDeploymentStore(t)
+		store.On("HistoricDeployments", mock.Anything, cluster, "deployd-1", startup).Return(deployments, nil)
+		apiClients, apiMocks := apiclient.NewMockClient(t)
+		apiMocks.Deployments.EXPECT().CreateDeploymentStatus(mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+		return New(store, apiClients.Deployments()).(*dispatchServer), store
+	}
+
+	t.Run("unfinished deployments are marked as inactive", func(t *testing.T) {
+		ds, store := setup(t)
+		store.On("WriteDeploymentStatus", mock.Anything, mock.MatchedBy(func(status database.DeploymentStatus) bool {
+			return status.Status == "inactive"
+		})).Return(true, nil).Times(3)
+
+		resumable, err := ds.handleHistoric(ctx, cluster, "deployd-1", startup, false)
+		assert.NoError(t, err)
+		assert.Empty(t, resumable)
+	})
+
+	t.Run("unfinished deployments are resumed if possible", func(t *testing.T) {
+		ds, store := setup(t)
+		deadline := startup.Add(5 * time.Minute).Truncate(time.Second)
+		store.On("DeploymentDeadline", mock.Anything, "resumable").Return(deadline, nil)
+		store.On("DeploymentDeadline", mock.Anything, "expired").Return(startup.Add(-50*time.Minute), nil)
+		store.On("DeploymentDeadline", mock.Anything, "dry-run").Return(time.Time{}, database.ErrNotFound)
+		store.On("DeploymentPayload", mock.Anything, "resumable").Return([]byte(`{"resources":[{"kind":"ConfigMap"}]}`), nil)
+		store.On("WriteDeploymentStatus", mock.Anything, mock.MatchedBy(func(status database.DeploymentStatus) bool {
+			return status.Status == "inactive" && status.DeploymentID != "resumable"
+		})).Return(true, nil).Times(2)
+
+		resumable, err := ds.handleHistoric(ctx, cluster, "deployd-1", startup, true)
+		assert.NoError(t, err)
+		assert.Len(t, resumable, 1)
+
+		req := resumable[0]
+		assert.Equal(t, "resumable", req.GetID())
+		assert.Equal(t, "aura", req.GetTeam())
+		assert.True(t, req.GetResume())
+		assert.Equal(t, deadline, req.GetDeadline().AsTime().Local())
+		assert.Len(t, req.GetKubernetes().GetResources(), 1)
+	})
+	t.Run("stored requests are resumed with all their options", func(t *testing.T) {
+		ds, store := setup(t)
+		deadline := startup.Add(5 * time.Minute).Truncate(time.Second)
+		kube, err := pb.KubernetesFromJSONResources([]byte(`[{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"kept","namespace":"aura"}}]`))
+		assert.NoError(t, err)
+		payload, err := protojson.Marshal(&pb.DeploymentRequest{
+			ID:                   "resumable",
+			Team:                 "aura",
+			Cluster:              cluster,
+			ForceOwnership:       true,
+			Prune:                true,
+			PruneDeploymentID:    "previous",
+			RollbackOnFailure:    true,
+			RollbackDeploymentID: "previous",
+			Repository:           &pb.GithubRepository{Owner: "navikt", Name: "app"},
+			Kubernetes:           kube,
+		})
+		assert.NoError(t, err)
+		previousPayload, err := protojson.Marshal(&pb.DeploymentRequest{
+			ID:         "previous",
+			Kubernetes: &pb.Kubernetes{Resources: []*structpb.Struct{{}, {}}},
+		})
+		assert.NoError(t, err)
+
+		store.On("DeploymentDeadline", mock.Anything, "resumable").Return(deadline, nil)
+		store.On("DeploymentDeadline", mock.Anything, mock.Anything).Return(time.Time{}, database.ErrNotFound)
+		store.On("DeploymentPayload", mock.Anything, "resumable").Return(payload, nil)
+		store.On("DeploymentPayload", mock.Anything, "previous").Return(previousPayload, nil)
+		store.On("DeploymentResources", mock.Anything, "previous").Return([]database.DeploymentResource{
+			{Version: "v1", Kind: "ConfigMap", Namespace: "aura", Name: "kept"},
+			{Version: "v1", Kind: "ConfigMap", Namespace: "aura", Name: "removed"},
+		}, nil)
+		store.On("WriteDeploymentStatus", mock.Anything, mock.Anything).Return(true, nil).Times(2)
+
+		resumable, err := ds.handleHistoric(ctx, cluster, "deployd-1", startup, true)
+		assert.NoError(t, err)
+		assert.Len(t, resumable, 1)
+
+		req := resumable[0]
+		assert.True(t, req.GetResume())
+		assert.True(t, req.GetForceOwnership())
+		assert.True(t, req.GetPrune())
+		assert.Equal(t, "previous", req.GetPruneDeploymentID())
+		if assert.Len(t, req.GetPruneResources(), 1) {
+			assert.Equal(t, "removed", req.GetPruneResources()[0].GetName())
+		}
+		assert.True(t, req.GetRollbackOnFailure())
+		assert.Equal(t, "previous", req.GetRollbackDeploymentID())
+		assert.Len(t, req.GetRollback().GetResources(), 2)
+		assert.Equal(t, "navikt/app", req.GetRepository().FullName())
+		assert.Equal(t, deadline, req.GetDeadline().AsTime().Local())
+	})
+}
@@ -0,0 +1,102 @@
+package dispatchserver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nais/deploy/pkg/hookd/metrics"
+	"github.com/nais/deploy/pkg/pb"
+)
+
+// Number of statuses that may be waiting for a single status stream before it is considered too slow.
+const statusBufferSize = 32
+
+type subscriber struct {
+	ch chan *pb.DeploymentStatus
+}
+
+// subscriptions routes statuses to the status streams of their own deployment.
+// Publishing never blocks; a subscriber whose buffer is full is evicted, and its channel closed,
+// so that a slow client cannot hold up status handling for everyone else.
+type subscriptions struct {
+	lock         sync.Mutex
+	byDeployment map[string]map[*subscriber]bool
+	count        int
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{
+		byDeployment: make(map[string]map[*subscriber]bool),
+	}
+}
+
+// subscribe returns a channel with the statuses of a deployment.
+// The channel is closed when the context is done, or when the subscriber has been evicted.
+func (s *subscriptions) subscribe(ctx context.Context, deploymentID string) <-chan *pb.DeploymentStatus {
+	sub := &subscriber{
+		ch: make(chan *pb.DeploymentStatus, statusBufferSize),
+	}
+
+	s.lock.Lock()
+	if s.byDeployment[deploymentID] == nil {
+		s.byDeployment[deploymentID] = make(map[*subscriber]bool)
+	}
+	s.byDeployment[deploymentID][sub] = true
+	s.count++
+	metrics.SetStatusSubscribers(s.count)
+	s.lock.Unlock()
+
+	context.AfterFunc(ctx, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.remove(deploymentID, sub)
+	})
+
+	return sub.ch
+}
+
+func (s *subscriptions) publish(st *pb.DeploymentStatus) {
+	deploymentID := st.GetRequest().GetID()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for sub := range s.byDeployment[deploymentID] {
+		select {
+		case sub.ch <- st:
+		default:
+			metrics.StatusDropped()
+			s.remove(deploymentID, sub)
+		}
+	}
+}
+
+// remove closes the channel of a subscriber, unless it has already been removed.
+// The lock must be held by the caller.
+func (s *subscriptions) remove(deploymentID string, sub *subscriber) {
+	subs := s.byDeployment[deploymentID]
+	if !subs[sub] {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(s.byDeployment, deploymentID)
+	}
+	close(sub.ch)
+
+	s.count--
+	metrics.SetStatusSubscribers(s.count)
+}
+
+// closeAll closes the channels of all subscribers.
+func (s *subscriptions) closeAll() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for deploymentID, subs := range s.byDeployment {
+		for sub := range subs {
+			s.remove(deploymentID, sub)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package dispatchserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func deploymentStatus(id string) *pb.DeploymentStatus {
+	return &pb.DeploymentStatus{Request: &pb.DeploymentRequest{ID: id}}
+}
+
+func TestSubscriptions(t *testing.T) {
+	t.Run("statuses are routed to subscribers of the same deployment", func(t *testing.T) {
+		s := newSubscriptions()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		first := s.subscribe(ctx, "1")
+		second := s.subscribe(ctx, "2")
+
+		s.publish(deploymentStatus("1"))
+
+		assert.Len(t, first, 1)
+		assert.Len(t, second, 0)
+
+		cancel()
+		<-first
+		_, open := <-first
+		assert.False(t, open, "channel is closed when the subscriber goes away")
+		_, open = <-second
+		assert.False(t, open)
+		s.lock.Lock()
+		assert.Equal(t, 0, s.count)
+		assert.Empty(t, s.byDeployment)
+		s.lock.Unlock()
+	})
+
+	t.Run("slow subscribers are evicted without holding up others", func(t *testing.T) {
+		s := newSubscriptions()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		slow := s.subscribe(ctx, "1")
+		fast := s.subscribe(ctx, "1")
+
+		for i := 0; i < statusBufferSize+1; i++ {
+			s.publish(deploymentStatus("1"))
+			if i < statusBufferSize {
+				<-fast
+			}
+		}
+
+		received := 0
+		for range slow {
+			received++
+		}
+		assert.Equal(t, statusBufferSize, received, "channel is closed after the buffered statuses")
+
+		assert.Len(t, fast, 1)
+		s.lock.Lock()
+		assert.Equal(t, 1, s.count)
+		s.lock.Unlock()
+	})
+}
@@ -52,9 +52,10 @@ func NewServerInterceptor(apiKeyStore database.ApiKeyStore, tokenValidator Token
 }
 
 func (s *ServerInterceptor) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-	_, ok := req.(*pb.DeploymentRequest)
-	if !ok {
-		return nil, status.Errorf(codes.InvalidArgument, "requests to this endpoint must be DeploymentRequest")
+	switch req.(type) {
+	case *pb.DeploymentRequest, *pb.ListDeploymentsRequest:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "requests to this endpoint must be DeploymentRequest or ListDeploymentsRequest")
 	}
 
 	md, ok := metadata.FromIncomingContext(ctx)
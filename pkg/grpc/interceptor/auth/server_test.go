@@ -43,6 +43,21 @@ func TestServerInterceptorApiKey(t *testing.T) {
 		}
 	})
 
+	t.Run("list deployments", func(t *testing.T) {
+		timestamp := time.Now().Format(time.RFC3339Nano)
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{
+			"authorization": []string{sign([]byte(timestamp), []byte("apikey"))},
+			"timestamp":     []string{timestamp},
+			"team":          []string{"team"},
+		})
+
+		_, err := i.UnaryServerInterceptor(ctx, &pb.ListDeploymentsRequest{}, nil, handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
 	t.Run("invalid apikey", func(t *testing.T) {
 		timestamp := time.Now().Format(time.RFC3339Nano)
 
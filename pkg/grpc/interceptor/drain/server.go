@@ -0,0 +1,89 @@
+// Package drain_interceptor lets hookd shut down without cutting off clients mid-conversation.
+//
+// Once draining, new calls are turned away, and open streams are ended, with codes.Unavailable,
+// which tells clients to reconnect, presumably to another hookd instance.
+package drain_interceptor
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrDraining = status.Errorf(codes.Unavailable, "hookd is shutting down; reconnect and try again")
+
+type ServerInterceptor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	exempt map[string]bool
+}
+
+// New returns an interceptor that is not yet draining.
+// Unary calls to the exempt methods are still accepted while draining.
+func New(exempt ...string) *ServerInterceptor {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &ServerInterceptor{
+		ctx:    ctx,
+		cancel: cancel,
+		exempt: make(map[string]bool),
+	}
+	for _, method := range exempt {
+		t.exempt[method] = true
+	}
+	return t
+}
+
+// Drain rejects new calls, and ends all open streams.
+func (t *ServerInterceptor) Drain() {
+	t.cancel()
+}
+
+func (t *ServerInterceptor) draining() bool {
+	return t.ctx.Err() != nil
+}
+
+func (t *ServerInterceptor) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	if t.draining() && !t.exempt[info.FullMethod] {
+		return nil, ErrDraining
+	}
+	return handler(ctx, req)
+}
+
+func (t *ServerInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return t.UnaryServerInterceptor
+}
+
+func (t *ServerInterceptor) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if t.draining() {
+		return ErrDraining
+	}
+
+	ctx, cancel := context.WithCancel(ss.Context())
+	defer cancel()
+	stop := context.AfterFunc(t.ctx, cancel)
+	defer stop()
+
+	err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+
+	// Stream handlers return when their context is cancelled, regardless of why.
+	if t.draining() {
+		return ErrDraining
+	}
+
+	return err
+}
+
+func (t *ServerInterceptor) Stream() grpc.StreamServerInterceptor {
+	return t.StreamServerInterceptor
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
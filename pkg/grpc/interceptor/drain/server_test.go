@@ -0,0 +1,74 @@
+package drain_interceptor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeStream struct {
+	grpc.ServerStream
+}
+
+func (s *fakeStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	i := New(pb.Dispatch_ReportStatus_FullMethodName)
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+	deploy := &grpc.UnaryServerInfo{FullMethod: pb.Deploy_Deploy_FullMethodName}
+	reportStatus := &grpc.UnaryServerInfo{FullMethod: pb.Dispatch_ReportStatus_FullMethodName}
+
+	resp, err := i.UnaryServerInterceptor(context.Background(), nil, deploy, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	i.Drain()
+
+	_, err = i.UnaryServerInterceptor(context.Background(), nil, deploy, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	resp, err = i.UnaryServerInterceptor(context.Background(), nil, reportStatus, handler)
+	assert.NoError(t, err, "exempt methods are accepted while draining")
+	assert.Equal(t, "ok", resp)
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	i := New()
+	info := &grpc.StreamServerInfo{FullMethod: pb.Deploy_Status_FullMethodName}
+
+	started := make(chan struct{})
+	handler := func(srv any, stream grpc.ServerStream) error {
+		close(started)
+		<-stream.Context().Done()
+		return nil
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- i.StreamServerInterceptor(nil, &fakeStream{}, info, handler)
+	}()
+
+	<-started
+	i.Drain()
+
+	select {
+	case err := <-result:
+		assert.Equal(t, codes.Unavailable, status.Code(err), "open streams are told to reconnect")
+	case <-time.After(time.Second):
+		t.Fatal("stream was not ended by drain")
+	}
+
+	err := i.StreamServerInterceptor(nil, &fakeStream{}, info, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err), "new streams are rejected")
+}
@@ -21,6 +21,7 @@ type Config struct {
 	DatabaseEncryptionKey     string        `json:"database-encryption-key"`
 	DatabaseURL               string        `json:"database-url"`
 	DeploydKeys               []string      `json:"deployd-keys"`
+	DrainTimeout              time.Duration `json:"drain-timeout"`
 	FrontendKeys              []string      `json:"frontend-keys"`
 	GRPC                      GRPC          `json:"grpc"`
 	GoogleAllowedDomains      []string      `json:"google-allowed-domains"`
@@ -31,7 +32,9 @@ type Config struct {
 	LogLinkFormatter          string        `json:"log-link-formatter"`
 	MetricsPath               string        `json:"metrics-path"`
 	OpenTelemetryCollectorURL string        `json:"otel-exporter-otlp-endpoint"`
+	PayloadRetention          int           `json:"payload-retention"`
 	ProvisionKey              string        `json:"provision-key"`
+	Replication               bool          `json:"replication"`
 	NaisAPIAddress            string        `json:"nais-api-address"`
 	NaisAPIInsecureConnection bool          `json:"nais-api-insecure-connection"`
 	ClusterMigrationRedirect  []string      `json:"cluster-migration-redirect"`
@@ -43,6 +46,7 @@ const (
 	DatabaseEncryptionKey     = "database-encryption-key"
 	DatabaseUrl               = "database-url"
 	DeploydKeys               = "deployd-keys"
+	DrainTimeout              = "drain-timeout"
 	FrontendKeys              = "frontend-keys"
 	GoogleAllowedDomains      = "google-allowed-domains"
 	GoogleClusterProjects     = "google-cluster-projects"
@@ -56,7 +60,9 @@ const (
 	LogLinkFormatter          = "log-link-formatter"
 	MetricsPath               = "metrics-path"
 	OtelExporterOtlpEndpoint  = "otel-exporter-otlp-endpoint"
+	PayloadRetention          = "payload-retention"
 	ProvisionKey              = "provision-key"
+	Replication               = "replication"
 	NaisAPIAddress            = "nais-api-address"
 	NaisAPIInsecureConnection = "nais-api-insecure-connection"
 	ClusterMigrationRedirect  = "cluster-migration-redirect"
@@ -84,6 +90,9 @@ func Initialize() *Config {
 	flag.String(ProvisionKey, "", "Pre-shared key for /api/v1/provision endpoint.")
 	flag.String(MetricsPath, "/metrics", "HTTP endpoint for exposed metrics.")
 	flag.String(OtelExporterOtlpEndpoint, "", "OpenTelemetry collector endpoint URL.")
+	flag.Duration(DrainTimeout, time.Second*20, "How long to wait for open requests to finish when shutting down.")
+	flag.Int(PayloadRetention, 5, "Number of successful deployments per repository and cluster to keep the Kubernetes resources of, so that they can be rolled back to.")
+	flag.Bool(Replication, false, "Exchange deployment requests and statuses with other hookd replicas through the database. Required when running more than one replica.")
 
 	flag.String(GrpcAddress, "127.0.0.1:9090", "Listen address of gRPC server.")
 	flag.Bool(GrpcDeploydAuthentication, false, "Validate tokens on gRPC connections from deployd.")
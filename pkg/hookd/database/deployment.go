@@ -2,10 +2,13 @@ package database
 
 import (
 	"context"
+	"encoding/hex"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/lib/pq"
+	"github.com/nais/deploy/pkg/crypto"
 )
 
 type Deployment struct {
@@ -16,6 +19,7 @@ type Deployment struct {
 	GitHubRepository *string   `json:"githubRepository"`
 	Cluster          *string   `json:"cluster"`
 	State            *string   `json:"state"`
+	DryRun           bool      `json:"dryRun"`
 }
 
 type DeploymentStatus struct {
@@ -37,15 +41,43 @@ type DeploymentResource struct {
 	Namespace    string `json:"namespace"`
 }
 
+// DeploymentFilter selects a page of a team's deployments.
+// Apart from the team, fields with zero values are not used for filtering.
+type DeploymentFilter struct {
+	Team       string
+	Cluster    string
+	Repository string
+	States     []string
+	Since      time.Time
+	Until      time.Time
+	// Only return deployments that come after this one, when sorted newest first.
+	After *DeploymentCursor
+	Limit int
+}
+
+// DeploymentCursor is the position of a deployment in a list sorted newest first.
+type DeploymentCursor struct {
+	Created time.Time
+	ID      string
+}
+
 type DeploymentStore interface {
 	Deployments(ctx context.Context, teams, clusters, ignoreTeams []string, limit int) ([]*Deployment, error)
 	Deployment(ctx context.Context, id string) (*Deployment, error)
-	HistoricDeployments(ctx context.Context, cluster string, timestamp time.Time) ([]*Deployment, error)
+	DeploymentHistory(ctx context.Context, filter DeploymentFilter) ([]*Deployment, error)
+	HistoricDeployments(ctx context.Context, cluster, instance string, timestamp time.Time) ([]*Deployment, error)
+	DispatchedDeployments(ctx context.Context, cluster, instance string) ([]*Deployment, error)
+	LastSuccessfulDeployment(ctx context.Context, repository, cluster string) (*Deployment, error)
 	WriteDeployment(ctx context.Context, deployment Deployment) error
+	WriteDeploymentInstance(ctx context.Context, deploymentID, instance string) error
 	DeploymentStatus(ctx context.Context, deploymentID string) ([]DeploymentStatus, error)
-	WriteDeploymentStatus(ctx context.Context, status DeploymentStatus) error
+	WriteDeploymentStatus(ctx context.Context, status DeploymentStatus) (bool, error)
 	DeploymentResources(ctx context.Context, deploymentID string) ([]DeploymentResource, error)
 	WriteDeploymentResource(ctx context.Context, resource DeploymentResource) error
+	DeploymentPayload(ctx context.Context, deploymentID string) ([]byte, error)
+	DeploymentDeadline(ctx context.Context, deploymentID string) (time.Time, error)
+	WriteDeploymentPayload(ctx context.Context, deploymentID string, payload []byte, deadline time.Time) error
+	DeleteDeploymentPayloads(ctx context.Context, keep int) (int64, error)
 }
 
 var _ DeploymentStore = &Database{}
@@ -60,18 +92,95 @@ func scanDeployment(rows pgx.Rows) (*Deployment, error) {
 		&deployment.GitHubID,
 		&deployment.GitHubRepository,
 		&deployment.Cluster,
+		&deployment.DryRun,
+		&deployment.State,
 	)
 
 	return deployment, err
 }
 
-func (db *Database) HistoricDeployments(ctx context.Context, cluster string, timestamp time.Time) ([]*Deployment, error) {
+// DeploymentHistory returns deployments matching the filter, newest first.
+func (db *Database) DeploymentHistory(ctx context.Context, filter DeploymentFilter) ([]*Deployment, error) {
 	query := `
-SELECT id, team, created, github_id, github_repository, cluster
+SELECT id, team, created, github_id, github_repository, cluster, dry_run, state
 FROM deployment
-WHERE (cluster = $1 AND created < $2 AND (state = 'in_progress' OR state = 'queued'));
+WHERE team = $1
+AND ($2::VARCHAR = '' OR cluster = $2)
+AND ($3::VARCHAR = '' OR github_repository = $3)
+AND (ARRAY_LENGTH($4::VARCHAR[], 1) IS NULL OR state = ANY($4))
+AND ($5::TIMESTAMPTZ IS NULL OR created >= $5)
+AND ($6::TIMESTAMPTZ IS NULL OR created < $6)
+AND ($7::TIMESTAMPTZ IS NULL OR (created, id) < ($7, $8))
+ORDER BY created DESC, id DESC
+LIMIT $9;
 `
-	rows, err := db.timedQuery(ctx, query, cluster, timestamp)
+	var afterCreated *time.Time
+	var afterID string
+	if filter.After != nil {
+		afterCreated = &filter.After.Created
+		afterID = filter.After.ID
+	}
+
+	rows, err := db.timedQuery(ctx, query,
+		filter.Team,
+		filter.Cluster,
+		filter.Repository,
+		pq.Array(filter.States),
+		nullTime(filter.Since),
+		nullTime(filter.Until),
+		afterCreated,
+		afterID,
+		filter.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := make([]*Deployment, 0)
+	defer rows.Close()
+	for rows.Next() {
+		deployment, err := scanDeployment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deployments = append(deployments, deployment)
+	}
+
+	return deployments, nil
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// HistoricDeployments returns unfinished deployments in a cluster created before the timestamp, that were sent to
+// the deployd instance or that have not been sent to any instance that is known.
+func (db *Database) HistoricDeployments(ctx context.Context, cluster, instance string, timestamp time.Time) ([]*Deployment, error) {
+	query := `
+SELECT id, team, created, github_id, github_repository, cluster, dry_run, state
+FROM deployment
+WHERE (cluster = $1 AND created < $3 AND (state = 'in_progress' OR state = 'queued'))
+AND (deployd_instance = $2 OR deployd_instance IS NULL);
+`
+	return db.unfinishedDeployments(ctx, query, cluster, instance, timestamp)
+}
+
+// DispatchedDeployments returns unfinished deployments in a cluster that were last sent to the deployd instance.
+func (db *Database) DispatchedDeployments(ctx context.Context, cluster, instance string) ([]*Deployment, error) {
+	query := `
+SELECT id, team, created, github_id, github_repository, cluster, dry_run, state
+FROM deployment
+WHERE (cluster = $1 AND deployd_instance = $2 AND (state = 'in_progress' OR state = 'queued'));
+`
+	return db.unfinishedDeployments(ctx, query, cluster, instance)
+}
+
+func (db *Database) unfinishedDeployments(ctx context.Context, query string, args ...interface{}) ([]*Deployment, error) {
+	rows, err := db.timedQuery(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +201,7 @@ WHERE (cluster = $1 AND created < $2 AND (state = 'in_progress' OR state = 'queu
 
 func (db *Database) Deployments(ctx context.Context, teams, clusters, ignoreTeams []string, limit int) ([]*Deployment, error) {
 	query := `
-SELECT id, team, created, github_id, github_repository, cluster
+SELECT id, team, created, github_id, github_repository, cluster, dry_run, state
 FROM deployment
 WHERE (ARRAY_LENGTH($1::VARCHAR[], 1) IS NULL OR team = ANY($1))
 AND (ARRAY_LENGTH($2::VARCHAR[], 1) IS NULL OR cluster = ANY($2))
@@ -120,7 +229,7 @@ LIMIT $4;
 }
 
 func (db *Database) Deployment(ctx context.Context, id string) (*Deployment, error) {
-	query := `SELECT id, team, created, github_id, github_repository, cluster FROM deployment WHERE id = $1;`
+	query := `SELECT id, team, created, github_id, github_repository, cluster, dry_run, state FROM deployment WHERE id = $1;`
 	rows, err := db.timedQuery(ctx, query, id)
 	if err != nil {
 		return nil, err
@@ -139,10 +248,38 @@ func (db *Database) Deployment(ctx context.Context, id string) (*Deployment, err
 	return nil, ErrNotFound
 }
 
+// LastSuccessfulDeployment returns the most recent deployment from a repository into a cluster that completed successfully.
+// Dry runs are not considered.
+func (db *Database) LastSuccessfulDeployment(ctx context.Context, repository, cluster string) (*Deployment, error) {
+	query := `
+SELECT id, team, created, github_id, github_repository, cluster, dry_run, state
+FROM deployment
+WHERE (github_repository = $1 AND cluster = $2 AND state = 'success' AND dry_run = false)
+ORDER BY created DESC
+LIMIT 1;
+`
+	rows, err := db.timedQuery(ctx, query, repository, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	if rows.Next() {
+		deployment, err := scanDeployment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		return deployment, nil
+	}
+
+	return nil, ErrNotFound
+}
+
 func (db *Database) WriteDeployment(ctx context.Context, deployment Deployment) error {
 	query := `
-INSERT INTO deployment (id, team, created, github_id, github_repository, cluster)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO deployment (id, team, created, github_id, github_repository, cluster, dry_run)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 ON CONFLICT (id) DO UPDATE
 SET github_id = EXCLUDED.github_id, github_repository = EXCLUDED.github_repository;
 `
@@ -153,11 +290,19 @@ SET github_id = EXCLUDED.github_id, github_repository = EXCLUDED.github_reposito
 		deployment.GitHubID,
 		deployment.GitHubRepository,
 		deployment.Cluster,
+		deployment.DryRun,
 	)
 
 	return err
 }
 
+// WriteDeploymentInstance records the deployd instance a deployment was sent to.
+func (db *Database) WriteDeploymentInstance(ctx context.Context, deploymentID, instance string) error {
+	query := `UPDATE deployment SET deployd_instance = $2 WHERE id = $1;`
+	_, err := db.conn.Exec(ctx, query, deploymentID, instance)
+	return err
+}
+
 func (db *Database) DeploymentStatus(ctx context.Context, deploymentID string) ([]DeploymentStatus, error) {
 	query := `SELECT id, deployment_id, status, message, created FROM deployment_status WHERE deployment_id = $1 ORDER BY created DESC;`
 	rows, err := db.timedQuery(ctx, query, deploymentID)
@@ -193,14 +338,23 @@ func (db *Database) DeploymentStatus(ctx context.Context, deploymentID string) (
 	return statuses, nil
 }
 
-func (db *Database) WriteDeploymentStatus(ctx context.Context, status DeploymentStatus) error {
-	var query string
-
-	query = `
-INSERT INTO deployment_status (id, deployment_id, status, message, created)
-VALUES ($1, $2, $3, $4, $5);
+// WriteDeploymentStatus stores a status and updates the state of its deployment.
+// Statuses are identified by their ID, so that a status reported more than once is only stored once.
+// Returns false if a status with the same ID has already been stored.
+func (db *Database) WriteDeploymentStatus(ctx context.Context, status DeploymentStatus) (bool, error) {
+	query := `
+WITH inserted AS (
+    INSERT INTO deployment_status (id, deployment_id, status, message, created)
+    VALUES ($1, $2, $3, $4, $5)
+    ON CONFLICT (id) DO NOTHING
+    RETURNING deployment_id
+)
+UPDATE deployment
+SET state = $3
+FROM inserted
+WHERE deployment.id = inserted.deployment_id;
 `
-	_, err := db.conn.Exec(ctx, query,
+	tag, err := db.conn.Exec(ctx, query,
 		status.ID,
 		status.DeploymentID,
 		status.Status,
@@ -208,16 +362,10 @@ VALUES ($1, $2, $3, $4, $5);
 		status.Created,
 	)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	query = `UPDATE deployment SET state = $1 WHERE id = $2;`
-	_, err = db.conn.Exec(ctx, query,
-		status.Status,
-		status.DeploymentID,
-	)
-
-	return err
+	return tag.RowsAffected() > 0, nil
 }
 
 func (db *Database) DeploymentResources(ctx context.Context, deploymentID string) ([]DeploymentResource, error) {
@@ -272,3 +420,88 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
 
 	return err
 }
+
+// DeploymentPayload returns the decrypted request of a deployment, as stored by WriteDeploymentPayload.
+func (db *Database) DeploymentPayload(ctx context.Context, deploymentID string) ([]byte, error) {
+	query := `SELECT payload FROM deployment_payload WHERE deployment_id = $1;`
+	rows, err := db.timedQuery(ctx, query, deploymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	if rows.Next() {
+		var encrypted string
+		err := rows.Scan(&encrypted)
+		if err != nil {
+			return nil, err
+		}
+
+		return db.decrypt(encrypted)
+	}
+
+	return nil, ErrNotFound
+}
+
+// DeploymentDeadline returns the deadline of a deployment with a stored payload.
+func (db *Database) DeploymentDeadline(ctx context.Context, deploymentID string) (time.Time, error) {
+	query := `SELECT deadline FROM deployment_payload WHERE deployment_id = $1 AND deadline IS NOT NULL;`
+	rows, err := db.timedQuery(ctx, query, deploymentID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	defer rows.Close()
+	if rows.Next() {
+		var deadline time.Time
+		err := rows.Scan(&deadline)
+		return deadline, err
+	}
+
+	return time.Time{}, ErrNotFound
+}
+
+// WriteDeploymentPayload stores the request of a deployment, encrypted, so that it can be rolled back to
+// or resumed later.
+func (db *Database) WriteDeploymentPayload(ctx context.Context, deploymentID string, payload []byte, deadline time.Time) error {
+	encrypted, err := crypto.Encrypt(payload, db.encryptionKey)
+	if err != nil {
+		return fmt.Errorf("encrypt deployment payload: %s", err)
+	}
+
+	query := `
+INSERT INTO deployment_payload (deployment_id, payload, deadline)
+VALUES ($1, $2, $3)
+ON CONFLICT (deployment_id) DO NOTHING;
+`
+	_, err = db.conn.Exec(ctx, query, deploymentID, hex.EncodeToString(encrypted), deadline)
+
+	return err
+}
+
+// DeleteDeploymentPayloads deletes the payloads that are no longer needed, and returns how many were deleted.
+// Payloads of unfinished deployments are kept so that they can be resumed, along with the payloads of the
+// last `keep` successful deployments from each repository into each cluster, so that they can be rolled back to.
+func (db *Database) DeleteDeploymentPayloads(ctx context.Context, keep int) (int64, error) {
+	query := `
+DELETE FROM deployment_payload
+USING deployment
+WHERE deployment_payload.deployment_id = deployment.id
+  AND deployment.state IN ('success', 'failure', 'error', 'inactive', 'cancelled')
+  AND deployment.id NOT IN (
+    SELECT id
+    FROM (
+        SELECT id, row_number() OVER (PARTITION BY github_repository, cluster ORDER BY created DESC) AS n
+        FROM deployment
+        WHERE state = 'success' AND dry_run = false AND github_repository IS NOT NULL
+    ) AS successful
+    WHERE n <= $1
+  );
+`
+	tag, err := db.conn.Exec(ctx, query, keep)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
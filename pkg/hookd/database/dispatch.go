@@ -0,0 +1,230 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nais/deploy/pkg/crypto"
+	log "github.com/sirupsen/logrus"
+)
+
+// Name of the Postgres notification channel used to pass messages between hookd replicas.
+const dispatchChannel = "hookd_dispatch"
+
+// DispatchMessage is a message from one hookd replica to the others.
+// The payload is encrypted at rest.
+type DispatchMessage struct {
+	ID      string    `json:"id"`
+	Kind    string    `json:"kind"`
+	Sender  string    `json:"sender"`
+	Cluster string    `json:"cluster"`
+	Payload []byte    `json:"payload"`
+	Created time.Time `json:"created"`
+}
+
+// DispatchNotification tells listening replicas about a new message, or acknowledges a message.
+// Only references are passed, because Postgres limits notification payloads to 8000 bytes.
+type DispatchNotification struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Sender  string `json:"sender"`
+	Cluster string `json:"cluster,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// LeaseStore elects the deployd instance that receives deployment requests for a cluster.
+type LeaseStore interface {
+	AcquireDeploydLease(ctx context.Context, cluster, instance, replica string, ttl time.Duration) (bool, string, error)
+	ReleaseDeploydLease(ctx context.Context, cluster, instance, replica string) error
+}
+
+type DispatchStore interface {
+	LeaseStore
+	WriteDispatchMessage(ctx context.Context, message DispatchMessage) error
+	DispatchMessage(ctx context.Context, id string) (*DispatchMessage, error)
+	ClaimDispatchMessage(ctx context.Context, id, replica string) (*DispatchMessage, error)
+	DeleteDispatchMessages(ctx context.Context, before time.Time) error
+	NotifyDispatch(ctx context.Context, notification DispatchNotification) error
+	ListenDispatch(ctx context.Context, fn func(DispatchNotification)) error
+}
+
+var _ DispatchStore = &Database{}
+
+// AcquireDeploydLease makes a deployd instance connected to a replica the active one for a cluster, unless another
+// instance or replica holds a lease that has not yet expired. A lease that is already held is renewed.
+// The instance that held the lease before is returned, whether or not the lease was acquired.
+func (db *Database) AcquireDeploydLease(ctx context.Context, cluster, instance, replica string, ttl time.Duration) (bool, string, error) {
+	query := `
+WITH previous AS (
+    SELECT instance FROM deployd_lease WHERE cluster = $1
+), acquired AS (
+    INSERT INTO deployd_lease (cluster, instance, replica, expires)
+    VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+    ON CONFLICT (cluster) DO UPDATE
+    SET instance = EXCLUDED.instance, replica = EXCLUDED.replica, expires = EXCLUDED.expires
+    WHERE (deployd_lease.instance = EXCLUDED.instance AND deployd_lease.replica = EXCLUDED.replica)
+    OR deployd_lease.expires <= now()
+    RETURNING cluster
+)
+SELECT EXISTS (SELECT 1 FROM acquired), COALESCE((SELECT instance FROM previous), '');
+`
+	var acquired bool
+	var previous string
+	err := db.conn.QueryRow(ctx, query, cluster, instance, replica, ttl.Seconds()).Scan(&acquired, &previous)
+	if err != nil {
+		return false, "", err
+	}
+
+	return acquired, previous, nil
+}
+
+// ReleaseDeploydLease lets the lease of a deployd instance expire, so that another instance can acquire it.
+// The lease is kept, so that the next instance knows which deployments to take over.
+func (db *Database) ReleaseDeploydLease(ctx context.Context, cluster, instance, replica string) error {
+	query := `UPDATE deployd_lease SET expires = now() WHERE cluster = $1 AND instance = $2 AND replica = $3;`
+	_, err := db.conn.Exec(ctx, query, cluster, instance, replica)
+	return err
+}
+
+// WriteDispatchMessage stores a message and notifies listening replicas about it.
+// The notification is delivered when the message has been committed.
+func (db *Database) WriteDispatchMessage(ctx context.Context, message DispatchMessage) error {
+	encrypted, err := crypto.Encrypt(message.Payload, db.encryptionKey)
+	if err != nil {
+		return fmt.Errorf("encrypt dispatch message: %s", err)
+	}
+
+	notification, err := json.Marshal(DispatchNotification{
+		ID:      message.ID,
+		Kind:    message.Kind,
+		Sender:  message.Sender,
+		Cluster: message.Cluster,
+	})
+	if err != nil {
+		return err
+	}
+
+	query := `
+WITH message AS (
+    INSERT INTO dispatch_message (id, kind, sender, cluster, payload, created)
+    VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+    RETURNING id
+)
+SELECT pg_notify($7, $8) FROM message;
+`
+	_, err = db.conn.Exec(ctx, query,
+		message.ID,
+		message.Kind,
+		message.Sender,
+		message.Cluster,
+		encrypted,
+		message.Created,
+		dispatchChannel,
+		string(notification),
+	)
+
+	return err
+}
+
+func (db *Database) DispatchMessage(ctx context.Context, id string) (*DispatchMessage, error) {
+	query := `SELECT id, kind, sender, COALESCE(cluster, ''), payload, created FROM dispatch_message WHERE id = $1;`
+	return db.scanDispatchMessage(ctx, query, id)
+}
+
+// ClaimDispatchMessage marks a message as handled by a replica, so that it is handled only once.
+// ErrNotFound is returned if the message has already been claimed.
+func (db *Database) ClaimDispatchMessage(ctx context.Context, id, replica string) (*DispatchMessage, error) {
+	query := `
+UPDATE dispatch_message
+SET claimed_by = $2
+WHERE id = $1 AND claimed_by IS NULL
+RETURNING id, kind, sender, COALESCE(cluster, ''), payload, created;
+`
+	return db.scanDispatchMessage(ctx, query, id, replica)
+}
+
+func (db *Database) scanDispatchMessage(ctx context.Context, query string, args ...interface{}) (*DispatchMessage, error) {
+	rows, err := db.timedQuery(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, ErrNotFound
+	}
+
+	message := &DispatchMessage{}
+	var encrypted []byte
+	err = rows.Scan(
+		&message.ID,
+		&message.Kind,
+		&message.Sender,
+		&message.Cluster,
+		&encrypted,
+		&message.Created,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	message.Payload, err = crypto.Decrypt(encrypted, db.encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt dispatch message: %s", err)
+	}
+
+	return message, nil
+}
+
+func (db *Database) DeleteDispatchMessages(ctx context.Context, before time.Time) error {
+	query := `DELETE FROM dispatch_message WHERE created < $1;`
+	_, err := db.conn.Exec(ctx, query, before)
+	return err
+}
+
+// NotifyDispatch sends a notification to listening replicas without storing a message.
+func (db *Database) NotifyDispatch(ctx context.Context, notification DispatchNotification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.conn.Exec(ctx, `SELECT pg_notify($1, $2);`, dispatchChannel, string(payload))
+	return err
+}
+
+// ListenDispatch calls fn for every notification sent by any replica, including this one.
+// A dedicated connection is taken out of the pool for as long as ListenDispatch runs.
+// Notifications sent while nobody is listening are lost; ListenDispatch returns if the connection fails.
+func (db *Database) ListenDispatch(ctx context.Context, fn func(DispatchNotification)) error {
+	pooled, err := db.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+dispatchChannel)
+	if err != nil {
+		return err
+	}
+
+	for {
+		pgNotification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		notification := DispatchNotification{}
+		err = json.Unmarshal([]byte(pgNotification.Payload), &notification)
+		if err != nil {
+			log.Errorf("Invalid dispatch notification %q: %s", pgNotification.Payload, err)
+			continue
+		}
+
+		fn(notification)
+	}
+}
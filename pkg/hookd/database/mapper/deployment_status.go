@@ -1,14 +1,20 @@
 package database_mapper
 
 import (
+	"strings"
+
 	"github.com/google/uuid"
 	"github.com/nais/deploy/pkg/hookd/database"
 	"github.com/nais/deploy/pkg/pb"
 )
 
 func DeploymentStatus(status *pb.DeploymentStatus) database.DeploymentStatus {
+	id := status.GetID()
+	if len(id) == 0 {
+		id = uuid.New().String()
+	}
 	return database.DeploymentStatus{
-		ID:           uuid.New().String(),
+		ID:           id,
 		DeploymentID: status.GetRequest().GetID(),
 		Status:       status.GetState().String(),
 		Message:      status.GetMessage(),
@@ -24,6 +30,7 @@ func PbStatus(status database.DeploymentStatus) *pb.DeploymentStatus {
 		Time:    pb.TimeAsTimestamp(status.Created),
 		State:   pb.DeploymentState(pb.DeploymentState_value[status.Status]),
 		Message: status.Message,
+		ID:      status.ID,
 	}
 }
 
@@ -37,5 +44,40 @@ func PbRequest(deploy database.Deployment) *pb.DeploymentRequest {
 		Time:    pb.TimeAsTimestamp(deploy.Created),
 		Cluster: cluster,
 		Team:    deploy.Team,
+		DryRun:  deploy.DryRun,
+	}
+}
+
+// PbDeployment maps a stored deployment without its statuses and resources.
+func PbDeployment(deploy database.Deployment) *pb.Deployment {
+	req := PbRequest(deploy)
+	if deploy.GitHubRepository != nil {
+		owner, name, ok := strings.Cut(*deploy.GitHubRepository, "/")
+		if ok {
+			req.Repository = &pb.GithubRepository{
+				Owner: owner,
+				Name:  name,
+			}
+		}
+	}
+
+	state := pb.DeploymentState_queued
+	if deploy.State != nil {
+		state = pb.DeploymentState(pb.DeploymentState_value[*deploy.State])
+	}
+
+	return &pb.Deployment{
+		Request: req,
+		State:   state,
+	}
+}
+
+func PbResource(resource database.DeploymentResource) *pb.ResourceIdentifier {
+	return &pb.ResourceIdentifier{
+		Group:     resource.Group,
+		Version:   resource.Version,
+		Kind:      resource.Kind,
+		Namespace: resource.Namespace,
+		Name:      resource.Name,
 	}
 }
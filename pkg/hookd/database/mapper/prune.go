@@ -0,0 +1,33 @@
+package database_mapper
+
+import (
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/k8sutils"
+	"github.com/nais/deploy/pkg/pb"
+)
+
+// PruneCandidates returns the previously deployed resources that are not present in the current set of resources.
+// API versions are not compared, as the same resource may be deployed using a different version.
+func PruneCandidates(previous []database.DeploymentResource, current []k8sutils.Identifier) []*pb.ResourceIdentifier {
+	type key struct {
+		group, kind, namespace, name string
+	}
+
+	present := make(map[key]bool, len(current))
+	for _, id := range current {
+		present[key{id.Group, id.Kind, id.Namespace, id.Name}] = true
+	}
+
+	candidates := make([]*pb.ResourceIdentifier, 0)
+	for _, resource := range previous {
+		k := key{resource.Group, resource.Kind, resource.Namespace, resource.Name}
+		if present[k] {
+			continue
+		}
+		// Guard against duplicates in the previous deployment.
+		present[k] = true
+		candidates = append(candidates, PbResource(resource))
+	}
+
+	return candidates
+}
@@ -0,0 +1,30 @@
+package database_mapper
+
+import (
+	"testing"
+
+	"github.com/nais/deploy/pkg/hookd/database"
+	"github.com/nais/deploy/pkg/k8sutils"
+	"github.com/nais/deploy/pkg/pb"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestPruneCandidates(t *testing.T) {
+	previous := []database.DeploymentResource{
+		{Group: "nais.io", Version: "v1alpha1", Kind: "Application", Namespace: "aura", Name: "app"},
+		{Group: "", Version: "v1", Kind: "ConfigMap", Namespace: "aura", Name: "removed"},
+		{Group: "", Version: "v1", Kind: "ConfigMap", Namespace: "aura", Name: "removed"},
+		{Group: "kafka.nais.io", Version: "v1", Kind: "Topic", Namespace: "aura", Name: "topic"},
+	}
+
+	current := []k8sutils.Identifier{
+		{GroupVersionKind: schema.GroupVersionKind{Group: "nais.io", Version: "v1", Kind: "Application"}, Namespace: "aura", Name: "app"},
+		{GroupVersionKind: schema.GroupVersionKind{Group: "kafka.nais.io", Version: "v1", Kind: "Topic"}, Namespace: "other", Name: "topic"},
+	}
+
+	assert.Equal(t, []*pb.ResourceIdentifier{
+		{Group: "", Version: "v1", Kind: "ConfigMap", Namespace: "aura", Name: "removed"},
+		{Group: "kafka.nais.io", Version: "v1", Kind: "Topic", Namespace: "aura", Name: "topic"},
+	}, PruneCandidates(previous, current))
+}
@@ -14,6 +14,30 @@ type MockDeploymentStore struct {
 	mock.Mock
 }
 
+// DeleteDeploymentPayloads provides a mock function with given fields: ctx, keep
+func (_m *MockDeploymentStore) DeleteDeploymentPayloads(ctx context.Context, keep int) (int64, error) {
+	ret := _m.Called(ctx, keep)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (int64, error)); ok {
+		return rf(ctx, keep)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) int64); ok {
+		r0 = rf(ctx, keep)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, keep)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Deployment provides a mock function with given fields: ctx, id
 func (_m *MockDeploymentStore) Deployment(ctx context.Context, id string) (*Deployment, error) {
 	ret := _m.Called(ctx, id)
@@ -40,6 +64,82 @@ func (_m *MockDeploymentStore) Deployment(ctx context.Context, id string) (*Depl
 	return r0, r1
 }
 
+// DeploymentDeadline provides a mock function with given fields: ctx, deploymentID
+func (_m *MockDeploymentStore) DeploymentDeadline(ctx context.Context, deploymentID string) (time.Time, error) {
+	ret := _m.Called(ctx, deploymentID)
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (time.Time, error)); ok {
+		return rf(ctx, deploymentID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) time.Time); ok {
+		r0 = rf(ctx, deploymentID)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, deploymentID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeploymentHistory provides a mock function with given fields: ctx, filter
+func (_m *MockDeploymentStore) DeploymentHistory(ctx context.Context, filter DeploymentFilter) ([]*Deployment, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []*Deployment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, DeploymentFilter) ([]*Deployment, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, DeploymentFilter) []*Deployment); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*Deployment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, DeploymentFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeploymentPayload provides a mock function with given fields: ctx, deploymentID
+func (_m *MockDeploymentStore) DeploymentPayload(ctx context.Context, deploymentID string) ([]byte, error) {
+	ret := _m.Called(ctx, deploymentID)
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]byte, error)); ok {
+		return rf(ctx, deploymentID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = rf(ctx, deploymentID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, deploymentID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeploymentResources provides a mock function with given fields: ctx, deploymentID
 func (_m *MockDeploymentStore) DeploymentResources(ctx context.Context, deploymentID string) ([]DeploymentResource, error) {
 	ret := _m.Called(ctx, deploymentID)
@@ -118,25 +218,77 @@ func (_m *MockDeploymentStore) Deployments(ctx context.Context, teams []string,
 	return r0, r1
 }
 
-// HistoricDeployments provides a mock function with given fields: ctx, cluster, timestamp
-func (_m *MockDeploymentStore) HistoricDeployments(ctx context.Context, cluster string, timestamp time.Time) ([]*Deployment, error) {
-	ret := _m.Called(ctx, cluster, timestamp)
+// DispatchedDeployments provides a mock function with given fields: ctx, cluster, instance
+func (_m *MockDeploymentStore) DispatchedDeployments(ctx context.Context, cluster string, instance string) ([]*Deployment, error) {
+	ret := _m.Called(ctx, cluster, instance)
 
 	var r0 []*Deployment
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) ([]*Deployment, error)); ok {
-		return rf(ctx, cluster, timestamp)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*Deployment, error)); ok {
+		return rf(ctx, cluster, instance)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) []*Deployment); ok {
-		r0 = rf(ctx, cluster, timestamp)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*Deployment); ok {
+		r0 = rf(ctx, cluster, instance)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*Deployment)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
-		r1 = rf(ctx, cluster, timestamp)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, cluster, instance)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HistoricDeployments provides a mock function with given fields: ctx, cluster, instance, timestamp
+func (_m *MockDeploymentStore) HistoricDeployments(ctx context.Context, cluster string, instance string, timestamp time.Time) ([]*Deployment, error) {
+	ret := _m.Called(ctx, cluster, instance, timestamp)
+
+	var r0 []*Deployment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) ([]*Deployment, error)); ok {
+		return rf(ctx, cluster, instance, timestamp)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) []*Deployment); ok {
+		r0 = rf(ctx, cluster, instance, timestamp)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*Deployment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, cluster, instance, timestamp)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LastSuccessfulDeployment provides a mock function with given fields: ctx, repository, cluster
+func (_m *MockDeploymentStore) LastSuccessfulDeployment(ctx context.Context, repository string, cluster string) (*Deployment, error) {
+	ret := _m.Called(ctx, repository, cluster)
+
+	var r0 *Deployment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*Deployment, error)); ok {
+		return rf(ctx, repository, cluster)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *Deployment); ok {
+		r0 = rf(ctx, repository, cluster)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Deployment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, repository, cluster)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -158,6 +310,34 @@ func (_m *MockDeploymentStore) WriteDeployment(ctx context.Context, deployment D
 	return r0
 }
 
+// WriteDeploymentInstance provides a mock function with given fields: ctx, deploymentID, instance
+func (_m *MockDeploymentStore) WriteDeploymentInstance(ctx context.Context, deploymentID string, instance string) error {
+	ret := _m.Called(ctx, deploymentID, instance)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, deploymentID, instance)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WriteDeploymentPayload provides a mock function with given fields: ctx, deploymentID, payload, deadline
+func (_m *MockDeploymentStore) WriteDeploymentPayload(ctx context.Context, deploymentID string, payload []byte, deadline time.Time) error {
+	ret := _m.Called(ctx, deploymentID, payload, deadline)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, time.Time) error); ok {
+		r0 = rf(ctx, deploymentID, payload, deadline)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // WriteDeploymentResource provides a mock function with given fields: ctx, resource
 func (_m *MockDeploymentStore) WriteDeploymentResource(ctx context.Context, resource DeploymentResource) error {
 	ret := _m.Called(ctx, resource)
@@ -173,17 +353,27 @@ func (_m *MockDeploymentStore) WriteDeploymentResource(ctx context.Context, reso
 }
 
 // WriteDeploymentStatus provides a mock function with given fields: ctx, status
-func (_m *MockDeploymentStore) WriteDeploymentStatus(ctx context.Context, status DeploymentStatus) error {
+func (_m *MockDeploymentStore) WriteDeploymentStatus(ctx context.Context, status DeploymentStatus) (bool, error) {
 	ret := _m.Called(ctx, status)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, DeploymentStatus) error); ok {
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, DeploymentStatus) (bool, error)); ok {
+		return rf(ctx, status)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, DeploymentStatus) bool); ok {
 		r0 = rf(ctx, status)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(bool)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, DeploymentStatus) error); ok {
+		r1 = rf(ctx, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewMockDeploymentStore creates a new instance of MockDeploymentStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -0,0 +1,11 @@
+-- Run the entire migration as an atomic operation.
+START TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;
+
+-- Dry run deployments are validated by the cluster, but never persisted.
+ALTER TABLE deployment
+ADD COLUMN "dry_run" BOOLEAN NOT NULL DEFAULT false;
+
+-- Mark this database migration as completed.
+INSERT INTO migrations (version, created)
+VALUES (10, now());
+COMMIT;
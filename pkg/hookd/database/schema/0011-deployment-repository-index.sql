@@ -0,0 +1,10 @@
+-- Run the entire migration as an atomic operation.
+START TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;
+
+-- Enable fast lookups of the last deployment for a repository in a cluster
+CREATE INDEX deployment_repository_cluster ON deployment (github_repository, cluster, created);
+
+-- Mark this database migration as completed.
+INSERT INTO migrations (version, created)
+VALUES (11, now());
+COMMIT;
@@ -0,0 +1,14 @@
+-- Run the entire migration as an atomic operation.
+START TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;
+
+-- Table deployment_payload holds the encrypted Kubernetes resources of a deployment, so that it can be rolled back to.
+CREATE TABLE deployment_payload
+(
+    "deployment_id" varchar primary key references deployment (id) not null,
+    "payload"       text                                           not null
+);
+
+-- Mark this database migration as completed.
+INSERT INTO migrations (version, created)
+VALUES (12, now());
+COMMIT;
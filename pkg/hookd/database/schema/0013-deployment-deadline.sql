@@ -0,0 +1,11 @@
+-- Run the entire migration as an atomic operation.
+START TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;
+
+-- The deadline is needed to resume unfinished deployments after deployd has restarted.
+ALTER TABLE deployment_payload
+    ADD COLUMN "deadline" timestamp with time zone null;
+
+-- Mark this database migration as completed.
+INSERT INTO migrations (version, created)
+VALUES (13, now());
+COMMIT;
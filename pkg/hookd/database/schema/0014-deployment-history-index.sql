@@ -0,0 +1,10 @@
+-- Run the entire migration as an atomic operation.
+START TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;
+
+-- Enable fast paging through a team's deployments, newest first
+CREATE INDEX deployment_team_created ON deployment (team, created DESC, id DESC);
+
+-- Mark this database migration as completed.
+INSERT INTO migrations (version, created)
+VALUES (14, now());
+COMMIT;
@@ -0,0 +1,22 @@
+-- Run the entire migration as an atomic operation.
+START TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;
+
+-- Messages passed between hookd replicas.
+-- Notifications only carry a reference, as their payload is limited to 8000 bytes.
+CREATE TABLE dispatch_message
+(
+    id         VARCHAR PRIMARY KEY      NOT NULL,
+    kind       VARCHAR                  NOT NULL,
+    sender     VARCHAR                  NOT NULL,
+    cluster    VARCHAR                  NULL,
+    payload    BYTEA                    NOT NULL,
+    claimed_by VARCHAR                  NULL,
+    created    TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX dispatch_message_created ON dispatch_message (created);
+
+-- Mark this database migration as completed.
+INSERT INTO migrations (version, created)
+VALUES (15, now());
+COMMIT;
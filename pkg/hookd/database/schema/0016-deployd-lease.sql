@@ -0,0 +1,21 @@
+-- Run the entire migration as an atomic operation.
+START TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;
+
+-- The deployd instance a deployment was last sent to.
+ALTER TABLE deployment
+    ADD COLUMN deployd_instance VARCHAR NULL;
+
+-- The deployd instance that receives deployment requests for a cluster, elected by the hookd replica it is connected to.
+-- A lease that has expired may be taken over by another instance.
+CREATE TABLE deployd_lease
+(
+    cluster  VARCHAR PRIMARY KEY      NOT NULL,
+    instance VARCHAR                  NOT NULL,
+    replica  VARCHAR                  NOT NULL,
+    expires  TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Mark this database migration as completed.
+INSERT INTO migrations (version, created)
+VALUES (16, now());
+COMMIT;
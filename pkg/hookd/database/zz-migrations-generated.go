@@ -12,4 +12,11 @@ var migrations = []string{
 	"-- Run the entire migration as an atomic operation.\nSTART TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;\n\n-- Add cluster field to deployment table.\nALTER TABLE deployment\nADD COLUMN \"state\" VARCHAR NULL;\n\n-- Enable fast lookups on cluster and state\nCREATE INDEX deployment_state ON deployment (state);\nCREATE INDEX deployment_cluster ON deployment (cluster);\n\n-- Mark this database migration as completed.\nINSERT INTO migrations (version, created)\nVALUES (7, now());\nCOMMIT;\n",
 	"-- Run the entire migration as an atomic operation.\nSTART TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;\n\n-- Enable fast lookups on team\nCREATE INDEX deployment_team ON deployment (team);\n\n-- Mark this database migration as completed.\nINSERT INTO migrations (version, created)\nVALUES (8, now());\nCOMMIT;\n",
 	"-- Run the entire migration as an atomic operation.\nSTART TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;\n\n-- Remove no longer used Azure column / index\nDROP INDEX apikey_team_azure_id_index;\nALTER TABLE apikey DROP COLUMN \"team_azure_id\";\n\n-- Mark this database migration as completed.\nINSERT INTO migrations (version, created)\nVALUES (9, now());\nCOMMIT;\n",
+	"-- Run the entire migration as an atomic operation.\nSTART TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;\n\n-- Dry run deployments are validated by the cluster, but never persisted.\nALTER TABLE deployment\nADD COLUMN \"dry_run\" BOOLEAN NOT NULL DEFAULT false;\n\n-- Mark this database migration as completed.\nINSERT INTO migrations (version, created)\nVALUES (10, now());\nCOMMIT;\n",
+	"-- Run the entire migration as an atomic operation.\nSTART TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;\n\n-- Enable fast lookups of the last deployment for a repository in a cluster\nCREATE INDEX deployment_repository_cluster ON deployment (github_repository, cluster, created);\n\n-- Mark this database migration as completed.\nINSERT INTO migrations (version, created)\nVALUES (11, now());\nCOMMIT;\n",
+	"-- Run the entire migration as an atomic operation.\nSTART TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;\n\n-- Table deployment_payload holds the encrypted Kubernetes resources of a deployment, so that it can be rolled back to.\nCREATE TABLE deployment_payload\n(\n    \"deployment_id\" varchar primary key references deployment (id) not null,\n    \"payload\"       text                                           not null\n);\n\n-- Mark this database migration as completed.\nINSERT INTO migrations (version, created)\nVALUES (12, now());\nCOMMIT;\n",
+	"-- Run the entire migration as an atomic operation.\nSTART TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;\n\n-- The deadline is needed to resume unfinished deployments after deployd has restarted.\nALTER TABLE deployment_payload\n    ADD COLUMN \"deadline\" timestamp with time zone null;\n\n-- Mark this database migration as completed.\nINSERT INTO migrations (version, created)\nVALUES (13, now());\nCOMMIT;\n",
+	"-- Run the entire migration as an atomic operation.\nSTART TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;\n\n-- Enable fast paging through a team's deployments, newest first\nCREATE INDEX deployment_team_created ON deployment (team, created DESC, id DESC);\n\n-- Mark this database migration as completed.\nINSERT INTO migrations (version, created)\nVALUES (14, now());\nCOMMIT;\n",
+	"-- Run the entire migration as an atomic operation.\nSTART TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;\n\n-- Messages passed between hookd replicas.\n-- Notifications only carry a reference, as their payload is limited to 8000 bytes.\nCREATE TABLE dispatch_message\n(\n    id         VARCHAR PRIMARY KEY      NOT NULL,\n    kind       VARCHAR                  NOT NULL,\n    sender     VARCHAR                  NOT NULL,\n    cluster    VARCHAR                  NULL,\n    payload    BYTEA                    NOT NULL,\n    claimed_by VARCHAR                  NULL,\n    created    TIMESTAMP WITH TIME ZONE NOT NULL\n);\n\nCREATE INDEX dispatch_message_created ON dispatch_message (created);\n\n-- Mark this database migration as completed.\nINSERT INTO migrations (version, created)\nVALUES (15, now());\nCOMMIT;\n",
+	"-- Run the entire migration as an atomic operation.\nSTART TRANSACTION ISOLATION LEVEL SERIALIZABLE READ WRITE;\n\n-- The deployd instance a deployment was last sent to.\nALTER TABLE deployment\n    ADD COLUMN deployd_instance VARCHAR NULL;\n\n-- The deployd instance that receives deployment requests for a cluster, elected by the hookd replica it is connected to.\n-- A lease that has expired may be taken over by another instance.\nCREATE TABLE deployd_lease\n(\n    cluster  VARCHAR PRIMARY KEY      NOT NULL,\n    instance VARCHAR                  NOT NULL,\n    replica  VARCHAR                  NOT NULL,\n    expires  TIMESTAMP WITH TIME ZONE NOT NULL\n);\n\n-- Mark this database migration as completed.\nINSERT INTO migrations (version, created)\nVALUES (16, now());\nCOMMIT;\n",
 }
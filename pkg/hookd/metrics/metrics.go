@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"slices"
 	"sync"
 	"time"
 
@@ -21,6 +22,7 @@ const (
 	Repository           = "repository"
 	Team                 = "team"
 	Cluster              = "cluster"
+	Deployd              = "deployd"
 
 	LabelType  = "type"
 	LabelError = "error"
@@ -29,6 +31,7 @@ const (
 var (
 	deployQueue        = make(map[string]interface{})
 	clusterConnections = make(map[string]bool)
+	deploydInstances   = make(map[string][]string)
 	qlock              = &sync.Mutex{}
 )
 
@@ -91,6 +94,55 @@ var (
 		},
 	)
 
+	statusSubscribers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:      "status_subscribers",
+		Help:      "number of open deployment status streams",
+		Namespace: namespace,
+		Subsystem: subsystem,
+	})
+
+	statusDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:      "status_dropped_total",
+		Help:      "deployment statuses that could not be delivered to a status stream that fell behind",
+		Namespace: namespace,
+		Subsystem: subsystem,
+	})
+
+	deploydConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "deployd_connections",
+		Help:      "deployd instances connected per cluster; 1 for the active instance, 0 for instances standing by",
+		Namespace: namespace,
+		Subsystem: subsystem,
+	},
+		[]string{
+			Cluster,
+			Deployd,
+		},
+	)
+
+	deploydRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "deployd_requests",
+		Help:      "deployment requests sent to each deployd instance",
+		Namespace: namespace,
+		Subsystem: subsystem,
+	},
+		[]string{
+			Cluster,
+			Deployd,
+		},
+	)
+
+	deploydFailovers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "deployd_failovers",
+		Help:      "number of times a standby deployd instance took over from the active one",
+		Namespace: namespace,
+		Subsystem: subsystem,
+	},
+		[]string{
+			Cluster,
+		},
+	)
+
 	interceptorRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name:      "auth_interceptor_requests",
 		Help:      "Number of requests by type in auth interceptor",
@@ -108,6 +160,11 @@ func init() {
 	prometheus.MustRegister(leadTime)
 	prometheus.MustRegister(clusterStatus)
 	prometheus.MustRegister(interceptorRequests)
+	prometheus.MustRegister(statusSubscribers)
+	prometheus.MustRegister(statusDropped)
+	prometheus.MustRegister(deploydConnections)
+	prometheus.MustRegister(deploydRequests)
+	prometheus.MustRegister(deploydFailovers)
 }
 
 func SetConnectedClusters(clusters []string) {
@@ -167,6 +224,8 @@ func UpdateQueue(status *pb.DeploymentStatus) {
 		fallthrough
 	case pb.DeploymentState_inactive:
 		fallthrough
+	case pb.DeploymentState_cancelled:
+		fallthrough
 	case pb.DeploymentState_error:
 		fallthrough
 	case pb.DeploymentState_failure:
@@ -186,3 +245,41 @@ func InterceptorRequest(requestType string, errType string) {
 		LabelError: errType,
 	}).Inc()
 }
+
+func SetStatusSubscribers(n int) {
+	statusSubscribers.Set(float64(n))
+}
+
+func StatusDropped() {
+	statusDropped.Inc()
+}
+
+// SetDeploydConnections reports the deployd instances connected to a cluster, the active one first.
+// Metrics of instances that are no longer connected are removed, as instances come and go with every rollout.
+func SetDeploydConnections(cluster string, instances []string, activeInstance string) {
+	qlock.Lock()
+	defer qlock.Unlock()
+
+	for _, instance := range deploydInstances[cluster] {
+		deploydConnections.DeleteLabelValues(cluster, instance)
+		if !slices.Contains(instances, instance) {
+			deploydRequests.DeleteLabelValues(cluster, instance)
+		}
+	}
+	for _, instance := range instances {
+		active := 0.0
+		if instance == activeInstance {
+			active = 1.0
+		}
+		deploydConnections.WithLabelValues(cluster, instance).Set(active)
+	}
+	deploydInstances[cluster] = instances
+}
+
+func DeploydRequestSent(cluster, instance string) {
+	deploydRequests.WithLabelValues(cluster, instance).Inc()
+}
+
+func DeploydFailover(cluster string) {
+	deploydFailovers.WithLabelValues(cluster).Inc()
+}
@@ -32,6 +32,7 @@ const (
 	DeploymentState_in_progress DeploymentState = 4
 	DeploymentState_queued      DeploymentState = 5
 	DeploymentState_pending     DeploymentState = 6
+	DeploymentState_cancelled   DeploymentState = 7
 )
 
 // Enum value maps for DeploymentState.
@@ -44,6 +45,7 @@ var (
 		4: "in_progress",
 		5: "queued",
 		6: "pending",
+		7: "cancelled",
 	}
 	DeploymentState_value = map[string]int32{
 		"success":     0,
@@ -53,6 +55,7 @@ var (
 		"in_progress": 4,
 		"queued":      5,
 		"pending":     6,
+		"cancelled":   7,
 	}
 )
 
@@ -190,18 +193,42 @@ type DeploymentRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ID                string                 `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	Time              *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
-	Deadline          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=deadline,proto3" json:"deadline,omitempty"`
-	Cluster           string                 `protobuf:"bytes,4,opt,name=cluster,proto3" json:"cluster,omitempty"`
-	Team              string                 `protobuf:"bytes,5,opt,name=team,proto3" json:"team,omitempty"`
-	GitRefSha         string                 `protobuf:"bytes,6,opt,name=gitRefSha,proto3" json:"gitRefSha,omitempty"`
-	Kubernetes        *Kubernetes            `protobuf:"bytes,7,opt,name=kubernetes,proto3" json:"kubernetes,omitempty"`
-	Repository        *GithubRepository      `protobuf:"bytes,8,opt,name=repository,proto3" json:"repository,omitempty"`
-	GithubEnvironment string                 `protobuf:"bytes,9,opt,name=GithubEnvironment,proto3" json:"GithubEnvironment,omitempty"`
-	TraceParent       string                 `protobuf:"bytes,10,opt,name=traceParent,proto3" json:"traceParent,omitempty"`
-	DeployerUsername  string                 `protobuf:"bytes,11,opt,name=deployerUsername,proto3" json:"deployerUsername,omitempty"`
-	TriggerUrl        string                 `protobuf:"bytes,12,opt,name=triggerUrl,proto3" json:"triggerUrl,omitempty"`
+	ID                   string                 `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Time                 *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	Deadline             *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	Cluster              string                 `protobuf:"bytes,4,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	Team                 string                 `protobuf:"bytes,5,opt,name=team,proto3" json:"team,omitempty"`
+	GitRefSha            string                 `protobuf:"bytes,6,opt,name=gitRefSha,proto3" json:"gitRefSha,omitempty"`
+	Kubernetes           *Kubernetes            `protobuf:"bytes,7,opt,name=kubernetes,proto3" json:"kubernetes,omitempty"`
+	Repository           *GithubRepository      `protobuf:"bytes,8,opt,name=repository,proto3" json:"repository,omitempty"`
+	GithubEnvironment    string                 `protobuf:"bytes,9,opt,name=GithubEnvironment,proto3" json:"GithubEnvironment,omitempty"`
+	TraceParent          string                 `protobuf:"bytes,10,opt,name=traceParent,proto3" json:"traceParent,omitempty"`
+	DeployerUsername     string                 `protobuf:"bytes,11,opt,name=deployerUsername,proto3" json:"deployerUsername,omitempty"`
+	TriggerUrl           string                 `protobuf:"bytes,12,opt,name=triggerUrl,proto3" json:"triggerUrl,omitempty"`
+	ForceOwnership       bool                   `protobuf:"varint,13,opt,name=forceOwnership,proto3" json:"forceOwnership,omitempty"`
+	DryRun               bool                   `protobuf:"varint,14,opt,name=dryRun,proto3" json:"dryRun,omitempty"`
+	Diff                 bool                   `protobuf:"varint,15,opt,name=diff,proto3" json:"diff,omitempty"`
+	Prune                bool                   `protobuf:"varint,16,opt,name=prune,proto3" json:"prune,omitempty"`
+	PruneResources       []*ResourceIdentifier  `protobuf:"bytes,17,rep,name=pruneResources,proto3" json:"pruneResources,omitempty"`
+	RollbackOnFailure    bool                   `protobuf:"varint,18,opt,name=rollbackOnFailure,proto3" json:"rollbackOnFailure,omitempty"`
+	Rollback             *Kubernetes            `protobuf:"bytes,19,opt,name=rollback,proto3" json:"rollback,omitempty"`
+	RollbackDeploymentID string                 `protobuf:"bytes,20,opt,name=rollbackDeploymentID,proto3" json:"rollbackDeploymentID,omitempty"`
+	// Set when an unfinished deployment is sent again after deployd has restarted.
+	// Resources that have already been applied are not applied again, only watched.
+	Resume bool `protobuf:"varint,21,opt,name=resume,proto3" json:"resume,omitempty"`
+	// Set when hookd forwards a cancellation of a deployment that has already been sent to deployd.
+	// Only the ID, team and cluster are set.
+	Cancel bool `protobuf:"varint,22,opt,name=cancel,proto3" json:"cancel,omitempty"`
+	// Used with the Status RPC: replay every stored status of the deployment, oldest first, before following live updates.
+	// Otherwise, only the latest stored status is sent.
+	ReplayStatus bool `protobuf:"varint,23,opt,name=replayStatus,proto3" json:"replayStatus,omitempty"`
+	// Used with the Status RPC: replay the stored statuses that were reported after this one, oldest first,
+	// before following live updates. Takes precedence over replayStatus.
+	StatusSinceID string `protobuf:"bytes,24,opt,name=statusSinceID,proto3" json:"statusSinceID,omitempty"`
+	// Used with the Status RPC when statusSinceID is not set or not known: replay the stored statuses that were reported after this time.
+	StatusSinceTime *timestamppb.Timestamp `protobuf:"bytes,25,opt,name=statusSinceTime,proto3" json:"statusSinceTime,omitempty"`
+	// ID of the deployment that pruneResources were part of. Only resources last applied by that deployment are pruned.
+	PruneDeploymentID string `protobuf:"bytes,26,opt,name=pruneDeploymentID,proto3" json:"pruneDeploymentID,omitempty"`
 }
 
 func (x *DeploymentRequest) Reset() {
@@ -320,34 +347,506 @@ func (x *DeploymentRequest) GetTriggerUrl() string {
 	return ""
 }
 
+func (x *DeploymentRequest) GetForceOwnership() bool {
+	if x != nil {
+		return x.ForceOwnership
+	}
+	return false
+}
+
+func (x *DeploymentRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *DeploymentRequest) GetDiff() bool {
+	if x != nil {
+		return x.Diff
+	}
+	return false
+}
+
+func (x *DeploymentRequest) GetPrune() bool {
+	if x != nil {
+		return x.Prune
+	}
+	return false
+}
+
+func (x *DeploymentRequest) GetPruneResources() []*ResourceIdentifier {
+	if x != nil {
+		return x.PruneResources
+	}
+	return nil
+}
+
+func (x *DeploymentRequest) GetRollbackOnFailure() bool {
+	if x != nil {
+		return x.RollbackOnFailure
+	}
+	return false
+}
+
+func (x *DeploymentRequest) GetRollback() *Kubernetes {
+	if x != nil {
+		return x.Rollback
+	}
+	return nil
+}
+
+func (x *DeploymentRequest) GetRollbackDeploymentID() string {
+	if x != nil {
+		return x.RollbackDeploymentID
+	}
+	return ""
+}
+
+func (x *DeploymentRequest) GetResume() bool {
+	if x != nil {
+		return x.Resume
+	}
+	return false
+}
+
+func (x *DeploymentRequest) GetCancel() bool {
+	if x != nil {
+		return x.Cancel
+	}
+	return false
+}
+
+func (x *DeploymentRequest) GetReplayStatus() bool {
+	if x != nil {
+		return x.ReplayStatus
+	}
+	return false
+}
+
+func (x *DeploymentRequest) GetStatusSinceID() string {
+	if x != nil {
+		return x.StatusSinceID
+	}
+	return ""
+}
+
+func (x *DeploymentRequest) GetStatusSinceTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StatusSinceTime
+	}
+	return nil
+}
+
+func (x *DeploymentRequest) GetPruneDeploymentID() string {
+	if x != nil {
+		return x.PruneDeploymentID
+	}
+	return ""
+}
+
+// Identifies a single Kubernetes resource.
+type ResourceIdentifier struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Group     string `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Version   string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Kind      string `protobuf:"bytes,3,opt,name=kind,proto3" json:"kind,omitempty"`
+	Namespace string `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Name      string `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *ResourceIdentifier) Reset() {
+	*x = ResourceIdentifier{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_pb_deployment_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceIdentifier) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceIdentifier) ProtoMessage() {}
+
+func (x *ResourceIdentifier) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_pb_deployment_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceIdentifier.ProtoReflect.Descriptor instead.
+func (*ResourceIdentifier) Descriptor() ([]byte, []int) {
+	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ResourceIdentifier) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *ResourceIdentifier) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ResourceIdentifier) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ResourceIdentifier) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ResourceIdentifier) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// A single field that differs between the live object in the cluster and the desired object.
+// Values are JSON encoded; an empty value means that the field is absent.
+type FieldDiff struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path    string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Live    string `protobuf:"bytes,2,opt,name=live,proto3" json:"live,omitempty"`
+	Desired string `protobuf:"bytes,3,opt,name=desired,proto3" json:"desired,omitempty"`
+}
+
+func (x *FieldDiff) Reset() {
+	*x = FieldDiff{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_pb_deployment_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FieldDiff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldDiff) ProtoMessage() {}
+
+func (x *FieldDiff) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_pb_deployment_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldDiff.ProtoReflect.Descriptor instead.
+func (*FieldDiff) Descriptor() ([]byte, []int) {
+	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FieldDiff) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FieldDiff) GetLive() string {
+	if x != nil {
+		return x.Live
+	}
+	return ""
+}
+
+func (x *FieldDiff) GetDesired() string {
+	if x != nil {
+		return x.Desired
+	}
+	return ""
+}
+
+// Difference between the live and desired state of a single resource.
+type ResourceDiff struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Resource string       `protobuf:"bytes,1,opt,name=resource,proto3" json:"resource,omitempty"`
+	Created  bool         `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	Fields   []*FieldDiff `protobuf:"bytes,3,rep,name=fields,proto3" json:"fields,omitempty"`
+}
+
+func (x *ResourceDiff) Reset() {
+	*x = ResourceDiff{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_pb_deployment_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceDiff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceDiff) ProtoMessage() {}
+
+func (x *ResourceDiff) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_pb_deployment_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceDiff.ProtoReflect.Descriptor instead.
+func (*ResourceDiff) Descriptor() ([]byte, []int) {
+	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ResourceDiff) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *ResourceDiff) GetCreated() bool {
+	if x != nil {
+		return x.Created
+	}
+	return false
+}
+
+func (x *ResourceDiff) GetFields() []*FieldDiff {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+// The last lines logged by a container that failed during rollout.
+type ContainerLog struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pod       string   `protobuf:"bytes,1,opt,name=pod,proto3" json:"pod,omitempty"`
+	Container string   `protobuf:"bytes,2,opt,name=container,proto3" json:"container,omitempty"`
+	Lines     []string `protobuf:"bytes,3,rep,name=lines,proto3" json:"lines,omitempty"`
+}
+
+func (x *ContainerLog) Reset() {
+	*x = ContainerLog{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_pb_deployment_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ContainerLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerLog) ProtoMessage() {}
+
+func (x *ContainerLog) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_pb_deployment_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerLog.ProtoReflect.Descriptor instead.
+func (*ContainerLog) Descriptor() ([]byte, []int) {
+	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ContainerLog) GetPod() string {
+	if x != nil {
+		return x.Pod
+	}
+	return ""
+}
+
+func (x *ContainerLog) GetContainer() string {
+	if x != nil {
+		return x.Container
+	}
+	return ""
+}
+
+func (x *ContainerLog) GetLines() []string {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
 type DeploymentStatus struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Request *DeploymentRequest     `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
-	Time    *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
-	State   DeploymentState        `protobuf:"varint,3,opt,name=state,proto3,enum=pb.DeploymentState" json:"state,omitempty"`
-	Message string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Request *DeploymentRequest     `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	Time    *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	State   DeploymentState        `protobuf:"varint,3,opt,name=state,proto3,enum=pb.DeploymentState" json:"state,omitempty"`
+	Message string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Diff    *ResourceDiff          `protobuf:"bytes,5,opt,name=diff,proto3" json:"diff,omitempty"`
+	Logs    []*ContainerLog        `protobuf:"bytes,6,rep,name=logs,proto3" json:"logs,omitempty"`
+	// Identifies the status once it has been received by hookd.
+	ID string `protobuf:"bytes,7,opt,name=ID,proto3" json:"ID,omitempty"`
+}
+
+func (x *DeploymentStatus) Reset() {
+	*x = DeploymentStatus{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_pb_deployment_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeploymentStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeploymentStatus) ProtoMessage() {}
+
+func (x *DeploymentStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_pb_deployment_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeploymentStatus.ProtoReflect.Descriptor instead.
+func (*DeploymentStatus) Descriptor() ([]byte, []int) {
+	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeploymentStatus) GetRequest() *DeploymentRequest {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *DeploymentStatus) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *DeploymentStatus) GetState() DeploymentState {
+	if x != nil {
+		return x.State
+	}
+	return DeploymentState_success
+}
+
+func (x *DeploymentStatus) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *DeploymentStatus) GetDiff() *ResourceDiff {
+	if x != nil {
+		return x.Diff
+	}
+	return nil
+}
+
+func (x *DeploymentStatus) GetLogs() []*ContainerLog {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+func (x *DeploymentStatus) GetID() string {
+	if x != nil {
+		return x.ID
+	}
+	return ""
+}
+
+// A deployment as it is stored by hookd.
+type Deployment struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Only the ID, time of creation, cluster, team, repository and dry run flag are set.
+	Request *DeploymentRequest `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	// State of the most recent status, or queued if no status has been reported yet.
+	State DeploymentState `protobuf:"varint,2,opt,name=state,proto3,enum=pb.DeploymentState" json:"state,omitempty"`
+	// Every status reported for the deployment, newest first. Only set by GetDeployment.
+	Statuses []*DeploymentStatus `protobuf:"bytes,3,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	// Resources that were part of the deployment. Only set by GetDeployment.
+	Resources []*ResourceIdentifier `protobuf:"bytes,4,rep,name=resources,proto3" json:"resources,omitempty"`
 }
 
-func (x *DeploymentStatus) Reset() {
-	*x = DeploymentStatus{}
+func (x *Deployment) Reset() {
+	*x = Deployment{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_pb_deployment_proto_msgTypes[3]
+		mi := &file_pkg_pb_deployment_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *DeploymentStatus) String() string {
+func (x *Deployment) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeploymentStatus) ProtoMessage() {}
+func (*Deployment) ProtoMessage() {}
 
-func (x *DeploymentStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_pb_deployment_proto_msgTypes[3]
+func (x *Deployment) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_pb_deployment_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -358,35 +857,201 @@ func (x *DeploymentStatus) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeploymentStatus.ProtoReflect.Descriptor instead.
-func (*DeploymentStatus) Descriptor() ([]byte, []int) {
-	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use Deployment.ProtoReflect.Descriptor instead.
+func (*Deployment) Descriptor() ([]byte, []int) {
+	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *DeploymentStatus) GetRequest() *DeploymentRequest {
+func (x *Deployment) GetRequest() *DeploymentRequest {
 	if x != nil {
 		return x.Request
 	}
 	return nil
 }
 
-func (x *DeploymentStatus) GetTime() *timestamppb.Timestamp {
+func (x *Deployment) GetState() DeploymentState {
 	if x != nil {
-		return x.Time
+		return x.State
+	}
+	return DeploymentState_success
+}
+
+func (x *Deployment) GetStatuses() []*DeploymentStatus {
+	if x != nil {
+		return x.Statuses
 	}
 	return nil
 }
 
-func (x *DeploymentStatus) GetState() DeploymentState {
+func (x *Deployment) GetResources() []*ResourceIdentifier {
 	if x != nil {
-		return x.State
+		return x.Resources
 	}
-	return DeploymentState_success
+	return nil
 }
 
-func (x *DeploymentStatus) GetMessage() string {
+// Filters and pagination for ListDeployments. Filters that are not set match every deployment of the team.
+type ListDeploymentsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Team    string `protobuf:"bytes,1,opt,name=team,proto3" json:"team,omitempty"`
+	Cluster string `protobuf:"bytes,2,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	// Full name of the GitHub repository, such as navikt/example.
+	Repository string            `protobuf:"bytes,3,opt,name=repository,proto3" json:"repository,omitempty"`
+	States     []DeploymentState `protobuf:"varint,4,rep,packed,name=states,proto3,enum=pb.DeploymentState" json:"states,omitempty"`
+	// Only deployments created at or after this time.
+	Since *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=since,proto3" json:"since,omitempty"`
+	// Only deployments created before this time.
+	Until *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=until,proto3" json:"until,omitempty"`
+	// Maximum number of deployments in the response. Defaults to 20, and is capped at 100.
+	Limit int32 `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+	// The nextCursor of a previous response, to fetch the page after it.
+	Cursor string `protobuf:"bytes,8,opt,name=cursor,proto3" json:"cursor,omitempty"`
+}
+
+func (x *ListDeploymentsRequest) Reset() {
+	*x = ListDeploymentsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_pb_deployment_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListDeploymentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDeploymentsRequest) ProtoMessage() {}
+
+func (x *ListDeploymentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_pb_deployment_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDeploymentsRequest.ProtoReflect.Descriptor instead.
+func (*ListDeploymentsRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListDeploymentsRequest) GetTeam() string {
 	if x != nil {
-		return x.Message
+		return x.Team
+	}
+	return ""
+}
+
+func (x *ListDeploymentsRequest) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
+
+func (x *ListDeploymentsRequest) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
+func (x *ListDeploymentsRequest) GetStates() []DeploymentState {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+func (x *ListDeploymentsRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *ListDeploymentsRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *ListDeploymentsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListDeploymentsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type ListDeploymentsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Newest first.
+	Deployments []*Deployment `protobuf:"bytes,1,rep,name=deployments,proto3" json:"deployments,omitempty"`
+	// Set when there are more deployments than were returned.
+	NextCursor string `protobuf:"bytes,2,opt,name=nextCursor,proto3" json:"nextCursor,omitempty"`
+}
+
+func (x *ListDeploymentsResponse) Reset() {
+	*x = ListDeploymentsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_pb_deployment_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListDeploymentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDeploymentsResponse) ProtoMessage() {}
+
+func (x *ListDeploymentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_pb_deployment_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDeploymentsResponse.ProtoReflect.Descriptor instead.
+func (*ListDeploymentsResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListDeploymentsResponse) GetDeployments() []*Deployment {
+	if x != nil {
+		return x.Deployments
+	}
+	return nil
+}
+
+func (x *ListDeploymentsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
 	}
 	return ""
 }
@@ -398,12 +1063,16 @@ type GetDeploymentOpts struct {
 
 	Cluster     string                 `protobuf:"bytes,1,opt,name=cluster,proto3" json:"cluster,omitempty"`
 	StartupTime *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=startupTime,proto3" json:"startupTime,omitempty"`
+	// Request unfinished deployments from before startupTime to be resumed instead of marked as inactive.
+	Resume bool `protobuf:"varint,3,opt,name=resume,proto3" json:"resume,omitempty"`
+	// Identifies this deployd instance, e.g. by its pod name, when several instances serve the same cluster.
+	Instance string `protobuf:"bytes,4,opt,name=instance,proto3" json:"instance,omitempty"`
 }
 
 func (x *GetDeploymentOpts) Reset() {
 	*x = GetDeploymentOpts{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_pb_deployment_proto_msgTypes[4]
+		mi := &file_pkg_pb_deployment_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -416,7 +1085,7 @@ func (x *GetDeploymentOpts) String() string {
 func (*GetDeploymentOpts) ProtoMessage() {}
 
 func (x *GetDeploymentOpts) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_pb_deployment_proto_msgTypes[4]
+	mi := &file_pkg_pb_deployment_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -429,7 +1098,7 @@ func (x *GetDeploymentOpts) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetDeploymentOpts.ProtoReflect.Descriptor instead.
 func (*GetDeploymentOpts) Descriptor() ([]byte, []int) {
-	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{4}
+	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *GetDeploymentOpts) GetCluster() string {
@@ -446,6 +1115,20 @@ func (x *GetDeploymentOpts) GetStartupTime() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *GetDeploymentOpts) GetResume() bool {
+	if x != nil {
+		return x.Resume
+	}
+	return false
+}
+
+func (x *GetDeploymentOpts) GetInstance() string {
+	if x != nil {
+		return x.Instance
+	}
+	return ""
+}
+
 type ReportStatusOpts struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -455,7 +1138,7 @@ type ReportStatusOpts struct {
 func (x *ReportStatusOpts) Reset() {
 	*x = ReportStatusOpts{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_pb_deployment_proto_msgTypes[5]
+		mi := &file_pkg_pb_deployment_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -468,7 +1151,7 @@ func (x *ReportStatusOpts) String() string {
 func (*ReportStatusOpts) ProtoMessage() {}
 
 func (x *ReportStatusOpts) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_pb_deployment_proto_msgTypes[5]
+	mi := &file_pkg_pb_deployment_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -481,7 +1164,7 @@ func (x *ReportStatusOpts) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportStatusOpts.ProtoReflect.Descriptor instead.
 func (*ReportStatusOpts) Descriptor() ([]byte, []int) {
-	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{5}
+	return file_pkg_pb_deployment_proto_rawDescGZIP(), []int{12}
 }
 
 var File_pkg_pb_deployment_proto protoreflect.FileDescriptor
@@ -501,7 +1184,7 @@ var file_pkg_pb_deployment_proto_rawDesc = []byte{
 	0x75, 0x72, 0x63, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x67, 0x6f,
 	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x74,
 	0x72, 0x75, 0x63, 0x74, 0x52, 0x09, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x22,
-	0xd9, 0x03, 0x0a, 0x11, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65,
+	0xff, 0x07, 0x0a, 0x11, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65,
 	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28,
 	0x09, 0x52, 0x02, 0x49, 0x44, 0x12, 0x2e, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20,
 	0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
@@ -530,55 +1213,176 @@ var file_pkg_pb_deployment_proto_rawDesc = []byte{
 	0x61, 0x6d, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x64, 0x65, 0x70, 0x6c, 0x6f,
 	0x79, 0x65, 0x72, 0x55, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x74,
 	0x72, 0x69, 0x67, 0x67, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x0a, 0x74, 0x72, 0x69, 0x67, 0x67, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x22, 0xb8, 0x01, 0x0a, 0x10,
-	0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
-	0x12, 0x2f, 0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x0b, 0x32, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e,
-	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x12, 0x2e, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0a, 0x74, 0x72, 0x69, 0x67, 0x67, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x12, 0x26, 0x0a, 0x0e, 0x66,
+	0x6f, 0x72, 0x63, 0x65, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x73, 0x68, 0x69, 0x70, 0x18, 0x0d, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0e, 0x66, 0x6f, 0x72, 0x63, 0x65, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x73,
+	0x68, 0x69, 0x70, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x72, 0x79, 0x52, 0x75, 0x6e, 0x18, 0x0e, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x06, 0x64, 0x72, 0x79, 0x52, 0x75, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x64,
+	0x69, 0x66, 0x66, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x64, 0x69, 0x66, 0x66, 0x12,
+	0x14, 0x0a, 0x05, 0x70, 0x72, 0x75, 0x6e, 0x65, 0x18, 0x10, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05,
+	0x70, 0x72, 0x75, 0x6e, 0x65, 0x12, 0x3e, 0x0a, 0x0e, 0x70, 0x72, 0x75, 0x6e, 0x65, 0x52, 0x65,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18, 0x11, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e,
+	0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x64, 0x65, 0x6e, 0x74,
+	0x69, 0x66, 0x69, 0x65, 0x72, 0x52, 0x0e, 0x70, 0x72, 0x75, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x73, 0x12, 0x2c, 0x0a, 0x11, 0x72, 0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63,
+	0x6b, 0x4f, 0x6e, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x18, 0x12, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x11, 0x72, 0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x4f, 0x6e, 0x46, 0x61, 0x69, 0x6c,
+	0x75, 0x72, 0x65, 0x12, 0x2a, 0x0a, 0x08, 0x72, 0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x18,
+	0x13, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x62, 0x2e, 0x4b, 0x75, 0x62, 0x65, 0x72,
+	0x6e, 0x65, 0x74, 0x65, 0x73, 0x52, 0x08, 0x72, 0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x12,
+	0x32, 0x0a, 0x14, 0x72, 0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x44, 0x65, 0x70, 0x6c, 0x6f,
+	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x18, 0x14, 0x20, 0x01, 0x28, 0x09, 0x52, 0x14, 0x72,
+	0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e,
+	0x74, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x18, 0x15, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x63,
+	0x61, 0x6e, 0x63, 0x65, 0x6c, 0x18, 0x16, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x63, 0x61, 0x6e,
+	0x63, 0x65, 0x6c, 0x12, 0x22, 0x0a, 0x0c, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x79, 0x53, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x18, 0x17, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x72, 0x65, 0x70, 0x6c, 0x61,
+	0x79, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x24, 0x0a, 0x0d, 0x73, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x53, 0x69, 0x6e, 0x63, 0x65, 0x49, 0x44, 0x18, 0x18, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
+	0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x53, 0x69, 0x6e, 0x63, 0x65, 0x49, 0x44, 0x12, 0x44, 0x0a,
+	0x0f, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x53, 0x69, 0x6e, 0x63, 0x65, 0x54, 0x69, 0x6d, 0x65,
+	0x18, 0x19, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x52, 0x0f, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x53, 0x69, 0x6e, 0x63, 0x65, 0x54,
+	0x69, 0x6d, 0x65, 0x12, 0x2c, 0x0a, 0x11, 0x70, 0x72, 0x75, 0x6e, 0x65, 0x44, 0x65, 0x70, 0x6c,
+	0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x18, 0x1a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x11,
+	0x70, 0x72, 0x75, 0x6e, 0x65, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x49,
+	0x44, 0x22, 0x8a, 0x01, 0x0a, 0x12, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x64,
+	0x65, 0x6e, 0x74, 0x69, 0x66, 0x69, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x6f, 0x75,
+	0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x18,
+	0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x1c, 0x0a, 0x09,
+	0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x4d,
+	0x0a, 0x09, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x44, 0x69, 0x66, 0x66, 0x12, 0x12, 0x0a, 0x04, 0x70,
+	0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12,
+	0x12, 0x0a, 0x04, 0x6c, 0x69, 0x76, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6c,
+	0x69, 0x76, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x65, 0x73, 0x69, 0x72, 0x65, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x64, 0x65, 0x73, 0x69, 0x72, 0x65, 0x64, 0x22, 0x6b, 0x0a,
+	0x0c, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x44, 0x69, 0x66, 0x66, 0x12, 0x1a, 0x0a,
+	0x08, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x63, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x64, 0x12, 0x25, 0x0a, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18, 0x03, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x44, 0x69,
+	0x66, 0x66, 0x52, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x22, 0x54, 0x0a, 0x0c, 0x43, 0x6f,
+	0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x4c, 0x6f, 0x67, 0x12, 0x10, 0x0a, 0x03, 0x70, 0x6f,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x70, 0x6f, 0x64, 0x12, 0x1c, 0x0a, 0x09,
+	0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69,
+	0x6e, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x6c, 0x69, 0x6e, 0x65, 0x73,
+	0x22, 0x94, 0x02, 0x0a, 0x10, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x2f, 0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c,
+	0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x07, 0x72,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2e, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x29, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f,
+	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x24, 0x0a, 0x04, 0x64,
+	0x69, 0x66, 0x66, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70, 0x62, 0x2e, 0x52,
+	0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x44, 0x69, 0x66, 0x66, 0x52, 0x04, 0x64, 0x69, 0x66,
+	0x66, 0x12, 0x24, 0x0a, 0x04, 0x6c, 0x6f, 0x67, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x10, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x4c, 0x6f,
+	0x67, 0x52, 0x04, 0x6c, 0x6f, 0x67, 0x73, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x02, 0x49, 0x44, 0x22, 0xd0, 0x01, 0x0a, 0x0a, 0x44, 0x65, 0x70, 0x6c,
+	0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x2f, 0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70,
+	0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x07,
+	0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x29, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c,
+	0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x12, 0x30, 0x0a, 0x08, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x65, 0x73, 0x18, 0x03,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79,
+	0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x08, 0x73, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x65, 0x73, 0x12, 0x34, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x69, 0x65, 0x72, 0x52,
+	0x09, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x22, 0xa5, 0x02, 0x0a, 0x16, 0x4c,
+	0x69, 0x73, 0x74, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6c, 0x75,
+	0x73, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6c, 0x75, 0x73,
+	0x74, 0x65, 0x72, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72,
+	0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74,
+	0x6f, 0x72, 0x79, 0x12, 0x2b, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x18, 0x04, 0x20,
+	0x03, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d,
+	0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73,
+	0x12, 0x30, 0x0a, 0x05, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
 	0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
-	0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x04, 0x74, 0x69, 0x6d,
-	0x65, 0x12, 0x29, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e,
-	0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74,
-	0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07,
-	0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d,
-	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x6b, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x44, 0x65, 0x70,
-	0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x4f, 0x70, 0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63,
-	0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6c,
-	0x75, 0x73, 0x74, 0x65, 0x72, 0x12, 0x3c, 0x0a, 0x0b, 0x73, 0x74, 0x61, 0x72, 0x74, 0x75, 0x70,
-	0x54, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d,
-	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0b, 0x73, 0x74, 0x61, 0x72, 0x74, 0x75, 0x70, 0x54,
-	0x69, 0x6d, 0x65, 0x22, 0x12, 0x0a, 0x10, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x4f, 0x70, 0x74, 0x73, 0x2a, 0x6e, 0x0a, 0x0f, 0x44, 0x65, 0x70, 0x6c, 0x6f,
-	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x73, 0x75,
-	0x63, 0x63, 0x65, 0x73, 0x73, 0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
-	0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x10, 0x02, 0x12,
-	0x0c, 0x0a, 0x08, 0x69, 0x6e, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x10, 0x03, 0x12, 0x0f, 0x0a,
-	0x0b, 0x69, 0x6e, 0x5f, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x10, 0x04, 0x12, 0x0a,
-	0x0a, 0x06, 0x71, 0x75, 0x65, 0x75, 0x65, 0x64, 0x10, 0x05, 0x12, 0x0b, 0x0a, 0x07, 0x70, 0x65,
-	0x6e, 0x64, 0x69, 0x6e, 0x67, 0x10, 0x06, 0x32, 0x89, 0x01, 0x0a, 0x08, 0x44, 0x69, 0x73, 0x70,
-	0x61, 0x74, 0x63, 0x68, 0x12, 0x3f, 0x0a, 0x0b, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65,
-	0x6e, 0x74, 0x73, 0x12, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x47, 0x65, 0x74, 0x44, 0x65, 0x70, 0x6c,
-	0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x4f, 0x70, 0x74, 0x73, 0x1a, 0x15, 0x2e, 0x70, 0x62, 0x2e,
-	0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x22, 0x00, 0x30, 0x01, 0x12, 0x3c, 0x0a, 0x0c, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x53,
-	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f,
-	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x1a, 0x14, 0x2e, 0x70, 0x62,
-	0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x4f, 0x70, 0x74,
-	0x73, 0x22, 0x00, 0x32, 0x7c, 0x0a, 0x06, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x12, 0x37, 0x0a,
-	0x06, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x12, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70,
-	0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14,
-	0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74,
-	0x61, 0x74, 0x75, 0x73, 0x22, 0x00, 0x12, 0x39, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
-	0x12, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70,
-	0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x22, 0x00, 0x30,
-	0x01, 0x42, 0x39, 0x0a, 0x18, 0x6e, 0x6f, 0x2e, 0x6e, 0x61, 0x76, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x73, 0x2e, 0x64, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5a, 0x1d, 0x67,
-	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6e, 0x61, 0x69, 0x73, 0x2f, 0x64,
-	0x65, 0x70, 0x6c, 0x6f, 0x79, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x05, 0x73, 0x69, 0x6e,
+	0x63, 0x65, 0x12, 0x30, 0x0a, 0x05, 0x75, 0x6e, 0x74, 0x69, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x05, 0x75,
+	0x6e, 0x74, 0x69, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75,
+	0x72, 0x73, 0x6f, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73,
+	0x6f, 0x72, 0x22, 0x6b, 0x0a, 0x17, 0x4c, 0x69, 0x73, 0x74, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79,
+	0x6d, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x30, 0x0a,
+	0x0b, 0x64, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65,
+	0x6e, 0x74, 0x52, 0x0b, 0x64, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x12,
+	0x1e, 0x0a, 0x0a, 0x6e, 0x65, 0x78, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0a, 0x6e, 0x65, 0x78, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22,
+	0x9f, 0x01, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e,
+	0x74, 0x4f, 0x70, 0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x12,
+	0x3c, 0x0a, 0x0b, 0x73, 0x74, 0x61, 0x72, 0x74, 0x75, 0x70, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x52, 0x0b, 0x73, 0x74, 0x61, 0x72, 0x74, 0x75, 0x70, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x72,
+	0x65, 0x73, 0x75, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63,
+	0x65, 0x22, 0x12, 0x0a, 0x10, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x4f, 0x70, 0x74, 0x73, 0x2a, 0x7d, 0x0a, 0x0f, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d,
+	0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63,
+	0x65, 0x73, 0x73, 0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x10, 0x01,
+	0x12, 0x0b, 0x0a, 0x07, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x10, 0x02, 0x12, 0x0c, 0x0a,
+	0x08, 0x69, 0x6e, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x10, 0x03, 0x12, 0x0f, 0x0a, 0x0b, 0x69,
+	0x6e, 0x5f, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x10, 0x04, 0x12, 0x0a, 0x0a, 0x06,
+	0x71, 0x75, 0x65, 0x75, 0x65, 0x64, 0x10, 0x05, 0x12, 0x0b, 0x0a, 0x07, 0x70, 0x65, 0x6e, 0x64,
+	0x69, 0x6e, 0x67, 0x10, 0x06, 0x12, 0x0d, 0x0a, 0x09, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c,
+	0x65, 0x64, 0x10, 0x07, 0x32, 0x89, 0x01, 0x0a, 0x08, 0x44, 0x69, 0x73, 0x70, 0x61, 0x74, 0x63,
+	0x68, 0x12, 0x3f, 0x0a, 0x0b, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x12, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x47, 0x65, 0x74, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d,
+	0x65, 0x6e, 0x74, 0x4f, 0x70, 0x74, 0x73, 0x1a, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70,
+	0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x00,
+	0x30, 0x01, 0x12, 0x3c, 0x0a, 0x0c, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x12, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65,
+	0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x1a, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x4f, 0x70, 0x74, 0x73, 0x22, 0x00,
+	0x32, 0xbd, 0x02, 0x0a, 0x06, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x12, 0x37, 0x0a, 0x06, 0x44,
+	0x65, 0x70, 0x6c, 0x6f, 0x79, 0x12, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f,
+	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70,
+	0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x22, 0x00, 0x12, 0x39, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x15,
+	0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f,
+	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x22, 0x00, 0x30, 0x01, 0x12,
+	0x37, 0x0a, 0x06, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x12, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x44,
+	0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x22, 0x00, 0x12, 0x4c, 0x0a, 0x0f, 0x4c, 0x69, 0x73, 0x74,
+	0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1a, 0x2e, 0x70, 0x62,
+	0x2e, 0x4c, 0x69, 0x73, 0x74, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x4c, 0x69, 0x73,
+	0x74, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x38, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x44, 0x65, 0x70,
+	0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70,
+	0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e,
+	0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x22, 0x00,
+	0x42, 0x39, 0x0a, 0x18, 0x6e, 0x6f, 0x2e, 0x6e, 0x61, 0x76, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x73, 0x2e, 0x64, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5a, 0x1d, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6e, 0x61, 0x69, 0x73, 0x2f, 0x64, 0x65,
+	0x70, 0x6c, 0x6f, 0x79, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -594,41 +1398,68 @@ func file_pkg_pb_deployment_proto_rawDescGZIP() []byte {
 }
 
 var file_pkg_pb_deployment_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_pkg_pb_deployment_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_pkg_pb_deployment_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_pkg_pb_deployment_proto_goTypes = []any{
-	(DeploymentState)(0),          // 0: pb.DeploymentState
-	(*GithubRepository)(nil),      // 1: pb.GithubRepository
-	(*Kubernetes)(nil),            // 2: pb.Kubernetes
-	(*DeploymentRequest)(nil),     // 3: pb.DeploymentRequest
-	(*DeploymentStatus)(nil),      // 4: pb.DeploymentStatus
-	(*GetDeploymentOpts)(nil),     // 5: pb.GetDeploymentOpts
-	(*ReportStatusOpts)(nil),      // 6: pb.ReportStatusOpts
-	(*structpb.Struct)(nil),       // 7: google.protobuf.Struct
-	(*timestamppb.Timestamp)(nil), // 8: google.protobuf.Timestamp
+	(DeploymentState)(0),            // 0: pb.DeploymentState
+	(*GithubRepository)(nil),        // 1: pb.GithubRepository
+	(*Kubernetes)(nil),              // 2: pb.Kubernetes
+	(*DeploymentRequest)(nil),       // 3: pb.DeploymentRequest
+	(*ResourceIdentifier)(nil),      // 4: pb.ResourceIdentifier
+	(*FieldDiff)(nil),               // 5: pb.FieldDiff
+	(*ResourceDiff)(nil),            // 6: pb.ResourceDiff
+	(*ContainerLog)(nil),            // 7: pb.ContainerLog
+	(*DeploymentStatus)(nil),        // 8: pb.DeploymentStatus
+	(*Deployment)(nil),              // 9: pb.Deployment
+	(*ListDeploymentsRequest)(nil),  // 10: pb.ListDeploymentsRequest
+	(*ListDeploymentsResponse)(nil), // 11: pb.ListDeploymentsResponse
+	(*GetDeploymentOpts)(nil),       // 12: pb.GetDeploymentOpts
+	(*ReportStatusOpts)(nil),        // 13: pb.ReportStatusOpts
+	(*structpb.Struct)(nil),         // 14: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil),   // 15: google.protobuf.Timestamp
 }
 var file_pkg_pb_deployment_proto_depIdxs = []int32{
-	7,  // 0: pb.Kubernetes.resources:type_name -> google.protobuf.Struct
-	8,  // 1: pb.DeploymentRequest.time:type_name -> google.protobuf.Timestamp
-	8,  // 2: pb.DeploymentRequest.deadline:type_name -> google.protobuf.Timestamp
+	14, // 0: pb.Kubernetes.resources:type_name -> google.protobuf.Struct
+	15, // 1: pb.DeploymentRequest.time:type_name -> google.protobuf.Timestamp
+	15, // 2: pb.DeploymentRequest.deadline:type_name -> google.protobuf.Timestamp
 	2,  // 3: pb.DeploymentRequest.kubernetes:type_name -> pb.Kubernetes
 	1,  // 4: pb.DeploymentRequest.repository:type_name -> pb.GithubRepository
-	3,  // 5: pb.DeploymentStatus.request:type_name -> pb.DeploymentRequest
-	8,  // 6: pb.DeploymentStatus.time:type_name -> google.protobuf.Timestamp
-	0,  // 7: pb.DeploymentStatus.state:type_name -> pb.DeploymentState
-	8,  // 8: pb.GetDeploymentOpts.startupTime:type_name -> google.protobuf.Timestamp
-	5,  // 9: pb.Dispatch.Deployments:input_type -> pb.GetDeploymentOpts
-	4,  // 10: pb.Dispatch.ReportStatus:input_type -> pb.DeploymentStatus
-	3,  // 11: pb.Deploy.Deploy:input_type -> pb.DeploymentRequest
-	3,  // 12: pb.Deploy.Status:input_type -> pb.DeploymentRequest
-	3,  // 13: pb.Dispatch.Deployments:output_type -> pb.DeploymentRequest
-	6,  // 14: pb.Dispatch.ReportStatus:output_type -> pb.ReportStatusOpts
-	4,  // 15: pb.Deploy.Deploy:output_type -> pb.DeploymentStatus
-	4,  // 16: pb.Deploy.Status:output_type -> pb.DeploymentStatus
-	13, // [13:17] is the sub-list for method output_type
-	9,  // [9:13] is the sub-list for method input_type
-	9,  // [9:9] is the sub-list for extension type_name
-	9,  // [9:9] is the sub-list for extension extendee
-	0,  // [0:9] is the sub-list for field type_name
+	4,  // 5: pb.DeploymentRequest.pruneResources:type_name -> pb.ResourceIdentifier
+	2,  // 6: pb.DeploymentRequest.rollback:type_name -> pb.Kubernetes
+	15, // 7: pb.DeploymentRequest.statusSinceTime:type_name -> google.protobuf.Timestamp
+	5,  // 8: pb.ResourceDiff.fields:type_name -> pb.FieldDiff
+	3,  // 9: pb.DeploymentStatus.request:type_name -> pb.DeploymentRequest
+	15, // 10: pb.DeploymentStatus.time:type_name -> google.protobuf.Timestamp
+	0,  // 11: pb.DeploymentStatus.state:type_name -> pb.DeploymentState
+	6,  // 12: pb.DeploymentStatus.diff:type_name -> pb.ResourceDiff
+	7,  // 13: pb.DeploymentStatus.logs:type_name -> pb.ContainerLog
+	3,  // 14: pb.Deployment.request:type_name -> pb.DeploymentRequest
+	0,  // 15: pb.Deployment.state:type_name -> pb.DeploymentState
+	8,  // 16: pb.Deployment.statuses:type_name -> pb.DeploymentStatus
+	4,  // 17: pb.Deployment.resources:type_name -> pb.ResourceIdentifier
+	0,  // 18: pb.ListDeploymentsRequest.states:type_name -> pb.DeploymentState
+	15, // 19: pb.ListDeploymentsRequest.since:type_name -> google.protobuf.Timestamp
+	15, // 20: pb.ListDeploymentsRequest.until:type_name -> google.protobuf.Timestamp
+	9,  // 21: pb.ListDeploymentsResponse.deployments:type_name -> pb.Deployment
+	15, // 22: pb.GetDeploymentOpts.startupTime:type_name -> google.protobuf.Timestamp
+	12, // 23: pb.Dispatch.Deployments:input_type -> pb.GetDeploymentOpts
+	8,  // 24: pb.Dispatch.ReportStatus:input_type -> pb.DeploymentStatus
+	3,  // 25: pb.Deploy.Deploy:input_type -> pb.DeploymentRequest
+	3,  // 26: pb.Deploy.Status:input_type -> pb.DeploymentRequest
+	3,  // 27: pb.Deploy.Cancel:input_type -> pb.DeploymentRequest
+	10, // 28: pb.Deploy.ListDeployments:input_type -> pb.ListDeploymentsRequest
+	3,  // 29: pb.Deploy.GetDeployment:input_type -> pb.DeploymentRequest
+	3,  // 30: pb.Dispatch.Deployments:output_type -> pb.DeploymentRequest
+	13, // 31: pb.Dispatch.ReportStatus:output_type -> pb.ReportStatusOpts
+	8,  // 32: pb.Deploy.Deploy:output_type -> pb.DeploymentStatus
+	8,  // 33: pb.Deploy.Status:output_type -> pb.DeploymentStatus
+	8,  // 34: pb.Deploy.Cancel:output_type -> pb.DeploymentStatus
+	11, // 35: pb.Deploy.ListDeployments:output_type -> pb.ListDeploymentsResponse
+	9,  // 36: pb.Deploy.GetDeployment:output_type -> pb.Deployment
+	30, // [30:37] is the sub-list for method output_type
+	23, // [23:30] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
 }
 
 func init() { file_pkg_pb_deployment_proto_init() }
@@ -674,7 +1505,7 @@ func file_pkg_pb_deployment_proto_init() {
 			}
 		}
 		file_pkg_pb_deployment_proto_msgTypes[3].Exporter = func(v any, i int) any {
-			switch v := v.(*DeploymentStatus); i {
+			switch v := v.(*ResourceIdentifier); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -686,7 +1517,7 @@ func file_pkg_pb_deployment_proto_init() {
 			}
 		}
 		file_pkg_pb_deployment_proto_msgTypes[4].Exporter = func(v any, i int) any {
-			switch v := v.(*GetDeploymentOpts); i {
+			switch v := v.(*FieldDiff); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -698,6 +1529,90 @@ func file_pkg_pb_deployment_proto_init() {
 			}
 		}
 		file_pkg_pb_deployment_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*ResourceDiff); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_pb_deployment_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*ContainerLog); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_pb_deployment_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*DeploymentStatus); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_pb_deployment_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*Deployment); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_pb_deployment_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*ListDeploymentsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_pb_deployment_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*ListDeploymentsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_pb_deployment_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*GetDeploymentOpts); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_pb_deployment_proto_msgTypes[12].Exporter = func(v any, i int) any {
 			switch v := v.(*ReportStatusOpts); i {
 			case 0:
 				return &v.state
@@ -716,7 +1631,7 @@ func file_pkg_pb_deployment_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_pkg_pb_deployment_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   6,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
@@ -171,8 +171,11 @@ var Dispatch_ServiceDesc = grpc.ServiceDesc{
 }
 
 const (
-	Deploy_Deploy_FullMethodName = "/pb.Deploy/Deploy"
-	Deploy_Status_FullMethodName = "/pb.Deploy/Status"
+	Deploy_Deploy_FullMethodName          = "/pb.Deploy/Deploy"
+	Deploy_Status_FullMethodName          = "/pb.Deploy/Status"
+	Deploy_Cancel_FullMethodName          = "/pb.Deploy/Cancel"
+	Deploy_ListDeployments_FullMethodName = "/pb.Deploy/ListDeployments"
+	Deploy_GetDeployment_FullMethodName   = "/pb.Deploy/GetDeployment"
 )
 
 // DeployClient is the client API for Deploy service.
@@ -183,6 +186,13 @@ const (
 type DeployClient interface {
 	Deploy(ctx context.Context, in *DeploymentRequest, opts ...grpc.CallOption) (*DeploymentStatus, error)
 	Status(ctx context.Context, in *DeploymentRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DeploymentStatus], error)
+	// Abort a deployment that has not yet finished. Only the ID and team of the request are used.
+	// The deployment is reported as cancelled once deployd has stopped it.
+	Cancel(ctx context.Context, in *DeploymentRequest, opts ...grpc.CallOption) (*DeploymentStatus, error)
+	// List the deployments of a team, newest first.
+	ListDeployments(ctx context.Context, in *ListDeploymentsRequest, opts ...grpc.CallOption) (*ListDeploymentsResponse, error)
+	// Look up a single deployment with its statuses and resources. Only the ID and team of the request are used.
+	GetDeployment(ctx context.Context, in *DeploymentRequest, opts ...grpc.CallOption) (*Deployment, error)
 }
 
 type deployClient struct {
@@ -222,6 +232,36 @@ func (c *deployClient) Status(ctx context.Context, in *DeploymentRequest, opts .
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Deploy_StatusClient = grpc.ServerStreamingClient[DeploymentStatus]
 
+func (c *deployClient) Cancel(ctx context.Context, in *DeploymentRequest, opts ...grpc.CallOption) (*DeploymentStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeploymentStatus)
+	err := c.cc.Invoke(ctx, Deploy_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deployClient) ListDeployments(ctx context.Context, in *ListDeploymentsRequest, opts ...grpc.CallOption) (*ListDeploymentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDeploymentsResponse)
+	err := c.cc.Invoke(ctx, Deploy_ListDeployments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deployClient) GetDeployment(ctx context.Context, in *DeploymentRequest, opts ...grpc.CallOption) (*Deployment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Deployment)
+	err := c.cc.Invoke(ctx, Deploy_GetDeployment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DeployServer is the server API for Deploy service.
 // All implementations must embed UnimplementedDeployServer
 // for forward compatibility.
@@ -230,6 +270,13 @@ type Deploy_StatusClient = grpc.ServerStreamingClient[DeploymentStatus]
 type DeployServer interface {
 	Deploy(context.Context, *DeploymentRequest) (*DeploymentStatus, error)
 	Status(*DeploymentRequest, grpc.ServerStreamingServer[DeploymentStatus]) error
+	// Abort a deployment that has not yet finished. Only the ID and team of the request are used.
+	// The deployment is reported as cancelled once deployd has stopped it.
+	Cancel(context.Context, *DeploymentRequest) (*DeploymentStatus, error)
+	// List the deployments of a team, newest first.
+	ListDeployments(context.Context, *ListDeploymentsRequest) (*ListDeploymentsResponse, error)
+	// Look up a single deployment with its statuses and resources. Only the ID and team of the request are used.
+	GetDeployment(context.Context, *DeploymentRequest) (*Deployment, error)
 	mustEmbedUnimplementedDeployServer()
 }
 
@@ -246,6 +293,15 @@ func (UnimplementedDeployServer) Deploy(context.Context, *DeploymentRequest) (*D
 func (UnimplementedDeployServer) Status(*DeploymentRequest, grpc.ServerStreamingServer[DeploymentStatus]) error {
 	return status.Errorf(codes.Unimplemented, "method Status not implemented")
 }
+func (UnimplementedDeployServer) Cancel(context.Context, *DeploymentRequest) (*DeploymentStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
+func (UnimplementedDeployServer) ListDeployments(context.Context, *ListDeploymentsRequest) (*ListDeploymentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeployments not implemented")
+}
+func (UnimplementedDeployServer) GetDeployment(context.Context, *DeploymentRequest) (*Deployment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeployment not implemented")
+}
 func (UnimplementedDeployServer) mustEmbedUnimplementedDeployServer() {}
 func (UnimplementedDeployServer) testEmbeddedByValue()                {}
 
@@ -296,6 +352,60 @@ func _Deploy_Status_Handler(srv interface{}, stream grpc.ServerStream) error {
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Deploy_StatusServer = grpc.ServerStreamingServer[DeploymentStatus]
 
+func _Deploy_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeploymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeployServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Deploy_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeployServer).Cancel(ctx, req.(*DeploymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Deploy_ListDeployments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeploymentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeployServer).ListDeployments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Deploy_ListDeployments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeployServer).ListDeployments(ctx, req.(*ListDeploymentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Deploy_GetDeployment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeploymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeployServer).GetDeployment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Deploy_GetDeployment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeployServer).GetDeployment(ctx, req.(*DeploymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Deploy_ServiceDesc is the grpc.ServiceDesc for Deploy service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -307,6 +417,18 @@ var Deploy_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Deploy",
 			Handler:    _Deploy_Deploy_Handler,
 		},
+		{
+			MethodName: "Cancel",
+			Handler:    _Deploy_Cancel_Handler,
+		},
+		{
+			MethodName: "ListDeployments",
+			Handler:    _Deploy_ListDeployments_Handler,
+		},
+		{
+			MethodName: "GetDeployment",
+			Handler:    _Deploy_GetDeployment_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
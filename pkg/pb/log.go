@@ -12,6 +12,7 @@ const (
 	LogFieldCluster              = "deployment_cluster"
 	LogFieldTeam                 = "team"
 	LogFieldDeploymentStatusType = "deployment_status"
+	LogFieldDeploydInstance      = "deployd_instance"
 )
 
 func (x *DeploymentStatus) LogFields() log.Fields {
@@ -15,6 +15,39 @@ type MockDeployClient struct {
 	mock.Mock
 }
 
+// Cancel provides a mock function with given fields: ctx, in, opts
+func (_m *MockDeployClient) Cancel(ctx context.Context, in *DeploymentRequest, opts ...grpc.CallOption) (*DeploymentStatus, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *DeploymentStatus
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *DeploymentRequest, ...grpc.CallOption) (*DeploymentStatus, error)); ok {
+		return rf(ctx, in, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *DeploymentRequest, ...grpc.CallOption) *DeploymentStatus); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*DeploymentStatus)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *DeploymentRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Deploy provides a mock function with given fields: ctx, in, opts
 func (_m *MockDeployClient) Deploy(ctx context.Context, in *DeploymentRequest, opts ...grpc.CallOption) (*DeploymentStatus, error) {
 	_va := make([]interface{}, len(opts))
@@ -48,6 +81,72 @@ func (_m *MockDeployClient) Deploy(ctx context.Context, in *DeploymentRequest, o
 	return r0, r1
 }
 
+// GetDeployment provides a mock function with given fields: ctx, in, opts
+func (_m *MockDeployClient) GetDeployment(ctx context.Context, in *DeploymentRequest, opts ...grpc.CallOption) (*Deployment, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *Deployment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *DeploymentRequest, ...grpc.CallOption) (*Deployment, error)); ok {
+		return rf(ctx, in, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *DeploymentRequest, ...grpc.CallOption) *Deployment); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Deployment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *DeploymentRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDeployments provides a mock function with given fields: ctx, in, opts
+func (_m *MockDeployClient) ListDeployments(ctx context.Context, in *ListDeploymentsRequest, opts ...grpc.CallOption) (*ListDeploymentsResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *ListDeploymentsResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *ListDeploymentsRequest, ...grpc.CallOption) (*ListDeploymentsResponse, error)); ok {
+		return rf(ctx, in, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *ListDeploymentsRequest, ...grpc.CallOption) *ListDeploymentsResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ListDeploymentsResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *ListDeploymentsRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Status provides a mock function with given fields: ctx, in, opts
 func (_m *MockDeployClient) Status(ctx context.Context, in *DeploymentRequest, opts ...grpc.CallOption) (Deploy_StatusClient, error) {
 	_va := make([]interface{}, len(opts))
@@ -13,6 +13,32 @@ type MockDeployServer struct {
 	mock.Mock
 }
 
+// Cancel provides a mock function with given fields: _a0, _a1
+func (_m *MockDeployServer) Cancel(_a0 context.Context, _a1 *DeploymentRequest) (*DeploymentStatus, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *DeploymentStatus
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *DeploymentRequest) (*DeploymentStatus, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *DeploymentRequest) *DeploymentStatus); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*DeploymentStatus)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *DeploymentRequest) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Deploy provides a mock function with given fields: _a0, _a1
 func (_m *MockDeployServer) Deploy(_a0 context.Context, _a1 *DeploymentRequest) (*DeploymentStatus, error) {
 	ret := _m.Called(_a0, _a1)
@@ -39,6 +65,58 @@ func (_m *MockDeployServer) Deploy(_a0 context.Context, _a1 *DeploymentRequest)
 	return r0, r1
 }
 
+// GetDeployment provides a mock function with given fields: _a0, _a1
+func (_m *MockDeployServer) GetDeployment(_a0 context.Context, _a1 *DeploymentRequest) (*Deployment, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *Deployment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *DeploymentRequest) (*Deployment, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *DeploymentRequest) *Deployment); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Deployment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *DeploymentRequest) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDeployments provides a mock function with given fields: _a0, _a1
+func (_m *MockDeployServer) ListDeployments(_a0 context.Context, _a1 *ListDeploymentsRequest) (*ListDeploymentsResponse, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *ListDeploymentsResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *ListDeploymentsRequest) (*ListDeploymentsResponse, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *ListDeploymentsRequest) *ListDeploymentsResponse); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ListDeploymentsResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *ListDeploymentsRequest) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Status provides a mock function with given fields: _a0, _a1
 func (_m *MockDeployServer) Status(_a0 *DeploymentRequest, _a1 Deploy_StatusServer) error {
 	ret := _m.Called(_a0, _a1)
@@ -41,6 +41,23 @@ func KubernetesFromJSON(data []byte) (*Kubernetes, error) {
 	return k, nil
 }
 
+// RequestFromPayload decodes a deployment request stored by hookd.
+// Earlier versions of hookd stored only the Kubernetes resources, which are returned in an otherwise empty request.
+func RequestFromPayload(data []byte) (*DeploymentRequest, error) {
+	req := &DeploymentRequest{}
+	err := protojson.Unmarshal(data, req)
+	if err == nil {
+		return req, nil
+	}
+
+	kube, kubeErr := KubernetesFromJSON(data)
+	if kubeErr != nil {
+		return nil, err
+	}
+
+	return &DeploymentRequest{Kubernetes: kube}, nil
+}
+
 func (m *Kubernetes) JSONResources() ([]json.RawMessage, error) {
 	resources := m.GetResources()
 	msgs := make([]json.RawMessage, len(resources))
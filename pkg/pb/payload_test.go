@@ -29,3 +29,26 @@ func TestJSONPayload(t *testing.T) {
 	assert.Equal(t, "bar", fs.Foo)
 	assert.Equal(t, []int{564}, fs.Baz)
 }
+
+func TestRequestFromPayload(t *testing.T) {
+	t.Run("full request", func(t *testing.T) {
+		req, err := pb.RequestFromPayload([]byte(`{"ID":"1","forceOwnership":true,"prune":true,"kubernetes":{"resources":[{"kind":"ConfigMap"}]}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, "1", req.GetID())
+		assert.True(t, req.GetForceOwnership())
+		assert.True(t, req.GetPrune())
+		assert.Len(t, req.GetKubernetes().GetResources(), 1)
+	})
+
+	t.Run("resources only, as stored by earlier versions", func(t *testing.T) {
+		req, err := pb.RequestFromPayload([]byte(`{"resources":[{"kind":"ConfigMap"}]}`))
+		assert.NoError(t, err)
+		assert.Empty(t, req.GetID())
+		assert.Len(t, req.GetKubernetes().GetResources(), 1)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := pb.RequestFromPayload([]byte(`{"unknown":true}`))
+		assert.Error(t, err)
+	})
+}
@@ -11,6 +11,7 @@ func (x DeploymentState) Finished() bool {
 	case DeploymentState_error:
 	case DeploymentState_failure:
 	case DeploymentState_inactive:
+	case DeploymentState_cancelled:
 	default:
 		return false
 	}
@@ -29,6 +30,9 @@ func (x DeploymentState) IsError() bool {
 }
 
 func (x DeploymentState) StatusEmoji() rune {
+	if x == DeploymentState_cancelled {
+		return '🛑'
+	}
 	if x.IsError() {
 		return '❌'
 	}
@@ -38,6 +42,15 @@ func (x DeploymentState) StatusEmoji() rune {
 	return '❓'
 }
 
+func NewCancelledStatus(req *DeploymentRequest) *DeploymentStatus {
+	return &DeploymentStatus{
+		Request: req,
+		Message: "Deployment was cancelled; resources that were already applied have been left as they are.",
+		State:   DeploymentState_cancelled,
+		Time:    TimeAsTimestamp(time.Now()),
+	}
+}
+
 func NewErrorStatus(req *DeploymentRequest, err error) *DeploymentStatus {
 	return &DeploymentStatus{
 		Request: req,
@@ -84,9 +97,13 @@ func NewQueuedStatus(req *DeploymentRequest) *DeploymentStatus {
 }
 
 func NewSuccessStatus(req *DeploymentRequest) *DeploymentStatus {
+	message := "Deployment completed successfully."
+	if req.GetDryRun() {
+		message = "Dry run completed successfully; no changes were persisted."
+	}
 	return &DeploymentStatus{
 		Request: req,
-		Message: "Deployment completed successfully.",
+		Message: message,
 		State:   DeploymentState_success,
 		Time:    TimeAsTimestamp(time.Now()),
 	}